@@ -1,10 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,6 +13,7 @@ import (
 
 	"github.com/m0khm/devhub/backend/internal/admin"
 	"github.com/m0khm/devhub/backend/internal/auth"
+	"github.com/m0khm/devhub/backend/internal/bot"
 	"github.com/m0khm/devhub/backend/internal/code"
 	"github.com/m0khm/devhub/backend/internal/community"
 	"github.com/m0khm/devhub/backend/internal/config"
@@ -26,10 +27,13 @@ import (
 	"github.com/m0khm/devhub/backend/internal/middleware"
 	"github.com/m0khm/devhub/backend/internal/notification"
 	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/ratelimit"
+	"github.com/m0khm/devhub/backend/internal/search"
 	"github.com/m0khm/devhub/backend/internal/storage"
 	"github.com/m0khm/devhub/backend/internal/topic"
 	"github.com/m0khm/devhub/backend/internal/user"
 	"github.com/m0khm/devhub/backend/internal/video" // NEW
+	"github.com/m0khm/devhub/backend/internal/webhook"
 )
 
 func main() {
@@ -52,8 +56,9 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(cfg.JWT.Secret, cfg.JWT.ExpireHours)
+	// Initialize JWT manager and session token denylist
+	jwtManager := auth.NewJWTManager(cfg.JWT.Secret, time.Duration(cfg.JWT.AccessTTLMinutes)*time.Minute)
+	tokenDenylist := auth.NewDenylist(redisClient)
 
 	// Initialize mailer
 	var mailerClient mailer.Sender
@@ -75,7 +80,16 @@ func main() {
 	}
 
 	// Initialize WebSocket hub
-	wsHub := message.NewHub()
+	var wsBroker message.Broker
+	switch cfg.WS.Broker {
+	case "memory":
+		wsBroker = message.NewMemoryBroker()
+	default:
+		wsBroker = message.NewRedisBroker(redisClient, cfg.WS.Channel)
+	}
+	defer wsBroker.Close()
+
+	wsHub := message.NewHub(wsBroker)
 	go wsHub.Run()
 
 	// Initialize S3 client
@@ -101,7 +115,18 @@ func main() {
 	userRepo := user.NewRepository(db)
 
 	// Initialize services
-	authService := auth.NewService(db, jwtManager, mailerClient)
+	sessionService := auth.NewSessionService(
+		db,
+		jwtManager,
+		tokenDenylist,
+		time.Duration(cfg.JWT.RefreshTTLDays)*24*time.Hour,
+	)
+	authService := auth.NewService(db, sessionService, mailerClient)
+	if cfg.RateLimit.Enabled {
+		authService.SetLoginLockout(auth.NewLoginLockout(redisClient, cfg.RateLimit.LoginLockout))
+	}
+	tokenService := auth.NewTokenService(db)
+	authenticator := auth.NewAuthenticator(jwtManager, tokenService, tokenDenylist)
 	adminService := admin.NewService(
 		cfg.Admin.User,
 		cfg.Admin.Password,
@@ -114,18 +139,60 @@ func main() {
 	userService := user.NewService(db, authService, mailerClient)
 	groupService := group.NewService(db)
 	communityService := community.NewService(db)
+	searchService := search.NewService(db, cfg.Search)
 	dmService := dm.NewService(dmRepo, projectRepo)
 	notificationService := notification.NewService(notificationRepo, projectRepo, topicRepo)
 	invitationService := project.NewInvitationService(projectRepo, userRepo)
+	botService := bot.NewService(db, projectRepo, userRepo, tokenService)
 	deployRepo := deploy.NewRepository(db)
-	deployEncryptor, err := deploy.NewEncryptor(cfg.Deploy.SecretsKey)
+	legacySecretsKey := cfg.Deploy.SecretsKey
+	if cfg.Server.Environment == "production" && cfg.Deploy.LegacyKeyIsDefault() {
+		// The default key may only stay to let rotate-secrets re-encrypt old values
+		remaining, err := deploy.CountLegacySecrets(db)
+		if err != nil {
+			log.Fatalf("Failed to count legacy deploy secrets: %v", err)
+		}
+		if remaining > 0 && !cfg.Deploy.DropLegacySecrets {
+			log.Fatalf("%d rows still hold secrets encrypted with the default DEPLOY_SECRETS_KEY: run rotate-secrets, or set DEPLOY_DROP_LEGACY_SECRETS=true to start with them unreadable", remaining)
+		}
+		if remaining > 0 {
+			log.Printf("⚠️  DEPLOY_DROP_LEGACY_SECRETS is set, %d rows with secrets encrypted with the default DEPLOY_SECRETS_KEY are unreadable", remaining)
+		}
+		legacySecretsKey = ""
+	}
+	deployEncryptor, err := deploy.NewEncryptor(cfg.Deploy.SecretsKeys, cfg.Deploy.SecretsActiveKey, legacySecretsKey)
 	if err != nil {
 		log.Fatalf("Failed to init deploy encryptor: %v", err)
 	}
 	deployService := deploy.NewService(deployRepo, projectRepo, deployEncryptor)
+	deployService.SetRecordingStorage(s3Client, cfg.Deploy.RecordTerminalInput)
+	deployService.SetScriptLimits(deploy.ScriptLimits{
+		DefaultTimeout: cfg.Deploy.ScriptDefaultTimeout,
+		MaxTimeout:     cfg.Deploy.ScriptMaxTimeout,
+		MaxConcurrency: cfg.Deploy.ScriptMaxConcurrency,
+	})
+	mfaService := auth.NewMFAService(db, jwtManager, sessionService, deployEncryptor)
+	authService.SetMFAService(mfaService)
+	githubService := auth.NewGitHubService(db, redisClient, authService, cfg.GitHub)
+	deployService.SetTwoFactorCheck(mfaService.IsEnabled)
+	webhookRepo := webhook.NewRepository(db)
+	webhookWorker := webhook.NewWorker(webhookRepo, deployEncryptor, cfg.Webhook)
+	webhookService := webhook.NewService(webhookRepo, projectRepo, deployEncryptor, webhookWorker, cfg.Webhook)
+	go webhookWorker.Run(context.Background())
+	incomingWebhookService := webhook.NewIncomingService(webhookRepo, projectRepo, topicRepo, messageService)
+
+	// Project events fan out to outgoing webhooks
+	projectService.SetProjectEventHook(webhookService.Dispatch)
+	invitationService.SetProjectEventHook(webhookService.Dispatch)
+	messageService.SetProjectEventHook(webhookService.Dispatch)
+	deployService.SetProjectEventHook(webhookService.Dispatch)
 
 	// Initialize handlers
 	authHandler := auth.NewHandler(authService)
+	tokenHandler := auth.NewTokenHandler(tokenService)
+	mfaHandler := auth.NewMFAHandler(mfaService)
+	githubHandler := auth.NewGitHubHandler(githubService)
+	botHandler := bot.NewHandler(botService)
 	adminHandler := admin.NewHandler(adminService)
 	projectHandler := project.NewHandler(projectService)
 	codeHandler := code.NewHandler(codeService)
@@ -135,25 +202,66 @@ func main() {
 	dmHandler := dm.NewHandler(dmService)
 	wsHandler := message.NewWSHandler(wsHub, messageService)
 	messageHandler.SetWSHandler(wsHandler)
+	projectService.SetAccessChangeHook(wsHandler.RevalidateProjectMember)
+	botService.SetAccessChangeHook(wsHandler.RevalidateProjectMember)
+	topicService.SetAccessChangeHook(wsHandler.RevalidateTopic)
+	topicService.SetReadHook(wsHandler.BroadcastReadReceipt)
+	invitationService.SetEventHook(wsHandler.SendToUser)
+	dmService.SetEventHook(wsHandler.SendToUser)
 	messageHandler.SetNotificationService(notificationService)
+	messageService.SetInvitationService(invitationService)
+	messageService.SetTopicService(topicService)
+	messageService.SetDeployService(deployService)
+	deployService.SetSystemMessageHook(wsHandler.PostSystemMessage)
+	deployService.SetAttachmentLookup(messageService.ProjectAttachment)
+	go deployService.RunHeartbeats(context.Background())
+
+	reminderWorker := message.NewReminderWorker(messageRepo, notificationRepo, 30*time.Second)
+	reminderWorker.SetNotifier(wsHandler.BroadcastNotificationCreated)
+	go reminderWorker.Run(context.Background())
 	fileHandler := message.NewFileHandler(messageService, s3Client)
 	fileHandler.SetWSHandler(wsHandler)
 	userHandler := user.NewHandler(userService, s3Client)
 	groupHandler := group.NewHandler(groupService)
 	communityHandler := community.NewHandler(communityService)
+	searchHandler := search.NewHandler(searchService)
 	notificationHandler := notification.NewHandler(notificationService)
 
 	videoHandler := video.NewHandler() // NEW
 	deployHandler := deploy.NewHandler(deployService)
 	deployWSHandler := deploy.NewWSHandler(deployService)
+	webhookHandler := webhook.NewHandler(webhookService)
+	incomingWebhookHandler := webhook.NewIncomingHandler(incomingWebhookService)
+	incomingWebhookHandler.SetWSHandler(wsHandler)
+	incomingWebhookHandler.SetNotificationService(notificationService)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "DevHub API",
 		ServerHeader: "DevHub",
 		ErrorHandler: customErrorHandler,
+		// Routes only answer their lower case paths, e.g. /api/projects and not
+		// /API/Projects, the scope rules are written for those
+		CaseSensitive: true,
+
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: len(cfg.Server.TrustedProxies) > 0,
+		TrustedProxies:          cfg.Server.TrustedProxies,
 	})
 
+	// Rate limits are counted in Redis and shared by all API replicas
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		rateLimiter = ratelimit.NewLimiter(redisClient)
+	}
+	limits := cfg.RateLimit
+	authLimit := middleware.RateLimit(rateLimiter, "auth", limits.Auth, middleware.ByIP)
+	emailCodesLimit := middleware.RateLimit(rateLimiter, "email_codes", limits.EmailCodes, middleware.ByUser)
+	// Public endpoints sending codes are also limited per recipient
+	emailCodesRecipientLimit := middleware.RateLimit(rateLimiter, "email_codes_recipient", limits.EmailCodes, middleware.ByEmail)
+	messageCreateLimit := middleware.RateLimit(rateLimiter, "message_create", limits.MessageCreate, middleware.ByUser)
+	uploadLimit := middleware.RateLimit(rateLimiter, "upload", limits.Upload, middleware.ByUser)
+
 	// Global middleware
 	app.Use(recover.New())
 	app.Use(middleware.Logger())
@@ -172,83 +280,68 @@ func main() {
 	})
 
 	// API routes
-	api := app.Group("/api")
+	api := app.Group("/api", middleware.RateLimit(rateLimiter, "ip", limits.PerIP, middleware.ByIP))
 
 	// Auth routes (public)
 	authRoutes := api.Group("/auth")
-	authRoutes.Post("/register", authHandler.Register)
-	authRoutes.Post("/register/confirm", authHandler.ConfirmRegister)
-	authRoutes.Post("/register/resend", authHandler.ResendRegister)
-	authRoutes.Post("/login", authHandler.Login)
-	authRoutes.Get("/me", middleware.Auth(jwtManager), authHandler.GetMe)
+	authRoutes.Post("/register", emailCodesLimit, emailCodesRecipientLimit, authHandler.Register)
+	authRoutes.Post("/register/confirm", authLimit, authHandler.ConfirmRegister)
+	authRoutes.Post("/register/resend", emailCodesLimit, emailCodesRecipientLimit, authHandler.ResendRegister)
+	authRoutes.Post("/login", authLimit, authHandler.Login)
+	authRoutes.Post("/login/mfa", authLimit, mfaHandler.Login)
+	authRoutes.Get("/github", githubHandler.Start)
+	authRoutes.Get("/github/callback", githubHandler.Callback)
+	authRoutes.Post("/github/link", middleware.Auth(authenticator), githubHandler.Link)
+	authRoutes.Delete("/github/link", middleware.Auth(authenticator), githubHandler.Unlink)
+	authRoutes.Post("/password/forgot", emailCodesLimit, emailCodesRecipientLimit, authHandler.ForgotPassword)
+	authRoutes.Post("/password/reset", authLimit, authHandler.ResetPassword)
+	authRoutes.Get("/me", middleware.Auth(authenticator), authHandler.GetMe)
+	authRoutes.Get("/scopes", tokenHandler.ListScopes)
+	authRoutes.Post("/refresh", authHandler.Refresh)
+	authRoutes.Post("/logout", middleware.Auth(authenticator), authHandler.Logout)
+	authRoutes.Get("/sessions", middleware.Auth(authenticator), authHandler.ListSessions)
+	authRoutes.Delete("/sessions", middleware.Auth(authenticator), authHandler.RevokeOtherSessions)
+	authRoutes.Delete("/sessions/:id", middleware.Auth(authenticator), authHandler.RevokeSession)
+	authRoutes.Get("/mfa", middleware.Auth(authenticator), mfaHandler.Status)
+	authRoutes.Post("/mfa/enroll", middleware.Auth(authenticator), mfaHandler.Enroll)
+	authRoutes.Post("/mfa/verify", middleware.Auth(authenticator), mfaHandler.Verify)
+	authRoutes.Post("/mfa/disable", middleware.Auth(authenticator), mfaHandler.Disable)
+	authRoutes.Post("/mfa/recovery-codes", middleware.Auth(authenticator), mfaHandler.RegenerateRecoveryCodes)
 
 	// Admin routes (public login + protected dashboard)
 	adminRoutes := api.Group("/admin")
 	adminRoutes.Post("/login", adminHandler.Login)
 	adminRoutes.Get("/", middleware.Admin(adminService), adminHandler.Dashboard)
 
-	// ---- WebSocket routes (НЕ через protected) ----
-	wsRoutes := api.Group("/topics")
-
-	wsRoutes.Use("/:topicId/ws", func(c *fiber.Ctx) error {
-		if !websocket.IsWebSocketUpgrade(c) {
-			return fiber.ErrUpgradeRequired
-		}
-
-		// token from query or header
-		token := c.Query("token")
-		if token == "" {
-			authHeader := c.Get("Authorization")
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				token = strings.TrimPrefix(authHeader, "Bearer ")
-			}
-		}
-
-		if token == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing token"})
-		}
+	// Incoming webhooks (public, authenticated by the token in the URL)
+	api.Post("/hooks/incoming/:token", incomingWebhookHandler.Post)
 
-		claims, err := jwtManager.Verify(token)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-		}
+	// ---- WebSocket routes (НЕ через protected) ----
+	wsAuth := middleware.WSAuth(authenticator)
 
-		c.Locals("userID", claims.UserID.String())
-		return c.Next()
-	})
+	// Multiplexed user connection
+	api.Use("/ws", wsAuth)
+	api.Get("/ws", websocket.New(wsHandler.HandleUserWebSocket))
 
+	// Legacy per-topic connection
+	wsRoutes := api.Group("/topics")
+	wsRoutes.Use("/:topicId/ws", wsAuth, wsHandler.AuthorizeTopic)
 	wsRoutes.Get("/:topicId/ws", websocket.New(wsHandler.HandleWebSocket))
 
 	deployWsRoutes := api.Group("/projects")
-	deployWsRoutes.Use("/:projectId/deploy/servers/:serverId/terminal/ws", func(c *fiber.Ctx) error {
-		if !websocket.IsWebSocketUpgrade(c) {
-			return fiber.ErrUpgradeRequired
-		}
-
-		token := c.Query("token")
-		if token == "" {
-			authHeader := c.Get("Authorization")
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				token = strings.TrimPrefix(authHeader, "Bearer ")
-			}
-		}
-
-		if token == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing token"})
-		}
-
-		claims, err := jwtManager.Verify(token)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-		}
-
-		c.Locals("userID", claims.UserID.String())
-		return c.Next()
-	})
+	deployWsRoutes.Use("/:projectId/deploy/servers/:serverId/terminal/ws", wsAuth)
 	deployWsRoutes.Get("/:projectId/deploy/servers/:serverId/terminal/ws", websocket.New(deployWSHandler.HandleTerminal))
+	deployWsRoutes.Use("/:projectId/deploy/sessions/:sessionId/ws", wsAuth)
+	deployWsRoutes.Get("/:projectId/deploy/sessions/:sessionId/ws", websocket.New(deployWSHandler.HandleAttach))
+	deployWsRoutes.Use("/:projectId/deploy/runs/:runId/ws", wsAuth)
+	deployWsRoutes.Get("/:projectId/deploy/runs/:runId/ws", websocket.New(deployWSHandler.HandleRun))
 
 	// ---- Protected routes (JWT middleware) ----
-	protected := api.Group("/", middleware.Auth(jwtManager))
+	protected := api.Group(
+		"/",
+		middleware.Auth(authenticator),
+		middleware.RateLimit(rateLimiter, "user", limits.PerUser, middleware.ByUser),
+	)
 
 	// Project routes
 	projectRoutes := protected.Group("/projects")
@@ -272,6 +365,51 @@ func main() {
 	projectRoutes.Post("/:projectId/deploy/servers", deployHandler.CreateServer)
 	projectRoutes.Get("/:projectId/deploy/servers", deployHandler.ListServers)
 	projectRoutes.Get("/:projectId/deploy/servers/:serverId", deployHandler.GetServer)
+	projectRoutes.Post("/:projectId/deploy/servers/:serverId/host-key/approve", deployHandler.ApproveHostKey)
+	projectRoutes.Get("/:projectId/deploy/servers/:serverId/sessions", deployHandler.ListTerminalSessions)
+	projectRoutes.Get("/:projectId/deploy/servers/:serverId/sessions/:sessionId/recording", deployHandler.GetRecording)
+	projectRoutes.Get("/:projectId/deploy/servers/:serverId/files", deployHandler.ListRemoteFiles)
+	projectRoutes.Get("/:projectId/deploy/servers/:serverId/files/download", deployHandler.DownloadRemoteFile)
+	projectRoutes.Post("/:projectId/deploy/servers/:serverId/files/upload", deployHandler.UploadRemoteFile)
+	projectRoutes.Post("/:projectId/deploy/servers/:serverId/files/transfer", deployHandler.TransferAttachment)
+	projectRoutes.Get("/:projectId/deploy/servers/:serverId/files/content", deployHandler.GetRemoteFileContent)
+	projectRoutes.Put("/:projectId/deploy/servers/:serverId/files/content", deployHandler.UpdateRemoteFileContent)
+	projectRoutes.Get("/:projectId/deploy/sessions/live", deployHandler.ListLiveTerminals)
+	projectRoutes.Post("/:projectId/deploy/scripts", deployHandler.CreateScript)
+	projectRoutes.Get("/:projectId/deploy/scripts", deployHandler.ListScripts)
+	projectRoutes.Get("/:projectId/deploy/scripts/:scriptId", deployHandler.GetScript)
+	projectRoutes.Put("/:projectId/deploy/scripts/:scriptId", deployHandler.UpdateScript)
+	projectRoutes.Delete("/:projectId/deploy/scripts/:scriptId", deployHandler.DeleteScript)
+	projectRoutes.Get("/:projectId/deploy/scripts/:scriptId/versions", deployHandler.ListScriptVersions)
+	projectRoutes.Post("/:projectId/deploy/scripts/:scriptId/runs", deployHandler.RunScript)
+	projectRoutes.Get("/:projectId/deploy/runs", deployHandler.ListScriptRuns)
+	projectRoutes.Get("/:projectId/deploy/runs/:runId", deployHandler.GetScriptRun)
+	projectRoutes.Get("/:projectId/deploy/runs/:runId/targets/:targetId/log", deployHandler.GetRunLog)
+	projectRoutes.Post("/:projectId/deploy/pipelines", deployHandler.CreatePipeline)
+	projectRoutes.Get("/:projectId/deploy/pipelines", deployHandler.ListPipelines)
+	projectRoutes.Get("/:projectId/deploy/pipelines/:pipelineId", deployHandler.GetPipeline)
+	projectRoutes.Put("/:projectId/deploy/pipelines/:pipelineId", deployHandler.UpdatePipeline)
+	projectRoutes.Delete("/:projectId/deploy/pipelines/:pipelineId", deployHandler.DeletePipeline)
+	projectRoutes.Post("/:projectId/deploy/pipelines/:pipelineId/runs", deployHandler.StartPipeline)
+	projectRoutes.Get("/:projectId/deploy/pipelines/:pipelineId/runs", deployHandler.ListPipelineRuns)
+	projectRoutes.Get("/:projectId/deploy/pipeline-runs/:runId", deployHandler.GetPipelineRun)
+	projectRoutes.Post("/:projectId/deploy/pipeline-runs/:runId/approve", deployHandler.ApprovePipelineStage)
+	projectRoutes.Post("/:projectId/deploy/pipeline-runs/:runId/reject", deployHandler.RejectPipelineStage)
+	projectRoutes.Post("/:projectId/bots", botHandler.Create)
+	projectRoutes.Get("/:projectId/bots", botHandler.List)
+	projectRoutes.Delete("/:projectId/bots/:botId", botHandler.Delete)
+	projectRoutes.Post("/:projectId/bots/:botId/tokens", botHandler.CreateToken)
+	projectRoutes.Get("/:projectId/bots/:botId/tokens", botHandler.ListTokens)
+	projectRoutes.Delete("/:projectId/bots/:botId/tokens/:tokenId", botHandler.RevokeToken)
+	projectRoutes.Post("/:projectId/webhooks", webhookHandler.Create)
+	projectRoutes.Get("/:projectId/webhooks", webhookHandler.List)
+	projectRoutes.Get("/:projectId/webhooks/:webhookId", webhookHandler.Get)
+	projectRoutes.Patch("/:projectId/webhooks/:webhookId", webhookHandler.Update)
+	projectRoutes.Delete("/:projectId/webhooks/:webhookId", webhookHandler.Delete)
+	projectRoutes.Post("/:projectId/webhooks/:webhookId/rotate-secret", webhookHandler.RotateSecret)
+	projectRoutes.Post("/:projectId/webhooks/:webhookId/ping", webhookHandler.Ping)
+	projectRoutes.Get("/:projectId/webhooks/:webhookId/deliveries", webhookHandler.ListDeliveries)
+	projectRoutes.Post("/:projectId/webhooks/:webhookId/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
 	projectRoutes.Post("/:projectId/topics", topicHandler.Create)
 	projectRoutes.Get("/:projectId/topics", topicHandler.GetByProjectID)
 
@@ -280,13 +418,20 @@ func main() {
 	topicRoutes.Get("/:id", topicHandler.GetByID)
 	topicRoutes.Put("/:id", topicHandler.Update)
 	topicRoutes.Delete("/:id", topicHandler.Delete)
+	topicRoutes.Post("/:id/read", topicHandler.MarkRead)
+	topicRoutes.Get("/:id/read", topicHandler.GetReadCursors)
 
 	// Message routes (внутри топика)
-	topicRoutes.Post("/:topicId/messages", messageHandler.Create)
+	topicRoutes.Post("/:topicId/messages", messageCreateLimit, messageHandler.Create)
 	topicRoutes.Get("/:topicId/messages", messageHandler.GetByTopicID)
 	topicRoutes.Get("/:topicId/pins", messageHandler.GetPinnedByTopicID)
 	topicRoutes.Get("/:topicId/search", messageHandler.SearchMessages)
-	topicRoutes.Post("/:topicId/upload", fileHandler.UploadFile)
+	topicRoutes.Get("/:topicId/commands", messageHandler.GetCommands)
+	topicRoutes.Post("/:topicId/incoming-webhooks", incomingWebhookHandler.Create)
+	topicRoutes.Get("/:topicId/incoming-webhooks", incomingWebhookHandler.List)
+	topicRoutes.Post("/:topicId/incoming-webhooks/:hookId/rotate", incomingWebhookHandler.Rotate)
+	topicRoutes.Delete("/:topicId/incoming-webhooks/:hookId", incomingWebhookHandler.Revoke)
+	topicRoutes.Post("/:topicId/upload", uploadLimit, fileHandler.UploadFile)
 
 	// Video routes (внутри топика) // NEW
 	topicRoutes.Post("/:topicId/video/room", videoHandler.CreateRoom)
@@ -298,6 +443,10 @@ func main() {
 	messageRoutes.Post("/:id/reactions", messageHandler.ToggleReaction)
 	messageRoutes.Post("/:id/pin", messageHandler.PinMessage)
 	messageRoutes.Delete("/:id/pin", messageHandler.UnpinMessage)
+	messageRoutes.Get("/:id/thread", messageHandler.GetThread)
+	messageRoutes.Post("/:id/thread/follow", messageHandler.FollowThread)
+	messageRoutes.Delete("/:id/thread/follow", messageHandler.UnfollowThread)
+	messageRoutes.Post("/:id/poll/vote", messageHandler.VotePoll)
 
 	// File routes
 	fileRoutes := protected.Group("/files")
@@ -312,10 +461,14 @@ func main() {
 	userRoutes := protected.Group("/users")
 	userRoutes.Get("/", userHandler.Search)
 	userRoutes.Patch("/me", userHandler.UpdateMe)
-	userRoutes.Post("/me/avatar", userHandler.UploadAvatar)
-	userRoutes.Post("/me/email", userHandler.StartEmailChange)
+	userRoutes.Post("/me/avatar", uploadLimit, userHandler.UploadAvatar)
+	userRoutes.Post("/me/email", emailCodesLimit, userHandler.StartEmailChange)
 	userRoutes.Post("/me/email/confirm", userHandler.ConfirmEmailChange)
 	userRoutes.Delete("/me", userHandler.DeleteMe)
+	userRoutes.Post("/me/password", authLimit, userHandler.ChangePassword)
+	userRoutes.Post("/me/tokens", tokenHandler.Create)
+	userRoutes.Get("/me/tokens", tokenHandler.List)
+	userRoutes.Delete("/me/tokens/:id", tokenHandler.Revoke)
 
 	// Group search routes
 	groupRoutes := protected.Group("/groups")
@@ -325,6 +478,9 @@ func main() {
 	communityRoutes := protected.Group("/communities")
 	communityRoutes.Get("/", communityHandler.Search)
 
+	// Global search
+	protected.Get("/search", searchHandler.Search)
+
 	// Notification routes
 	notificationRoutes := protected.Group("/notifications")
 	notificationRoutes.Get("/", notificationHandler.List)
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/m0khm/devhub/backend/internal/config"
+	"github.com/m0khm/devhub/backend/internal/database"
+	"github.com/m0khm/devhub/backend/internal/deploy"
+)
+
+// rotate-secrets re-encrypts stored secrets with DEPLOY_SECRETS_ACTIVE_KEY.
+// To retire a key: add the new key to DEPLOY_SECRETS_KEYS, make it active and
+// restart the API, run this command, then remove the old key (and
+// DEPLOY_SECRETS_KEY once no value without a key ID is left).
+func main() {
+	var batchSize int
+	var dryRun bool
+	var tables string
+	flag.IntVar(&batchSize, "batch", 100, "Rows re-encrypted per transaction")
+	flag.BoolVar(&dryRun, "dry-run", false, "Count the values to rotate without changing them")
+	flag.StringVar(&tables, "tables", "", "Comma separated tables to rotate, all by default")
+	flag.Parse()
+
+	if batchSize < 1 {
+		log.Fatal("-batch must be at least 1")
+	}
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	encryptor, err := deploy.NewEncryptor(cfg.Deploy.SecretsKeys, cfg.Deploy.SecretsActiveKey, cfg.Deploy.SecretsKey)
+	if err != nil {
+		log.Fatalf("Failed to init deploy encryptor: %v", err)
+	}
+
+	known := map[string]bool{}
+	for _, table := range deploy.SecretTables {
+		known[table.Name] = true
+	}
+	selected := map[string]bool{}
+	for _, name := range strings.Split(tables, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !known[name] {
+			log.Fatalf("Unknown table %s", name)
+		}
+		selected[name] = true
+	}
+
+	// Connect to database
+	db, err := database.ConnectPostgres(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	log.Printf("Rotating secrets to key %q", encryptor.ActiveKeyID())
+	for _, table := range deploy.SecretTables {
+		if len(selected) > 0 && !selected[table.Name] {
+			continue
+		}
+
+		stats, err := deploy.RotateSecrets(db, encryptor, table, batchSize, dryRun)
+		if err != nil {
+			log.Fatalf("Rotation failed after %d rotated rows: %v", stats.Rotated, err)
+		}
+		log.Printf("%s: %d rows scanned, %d rotated, %d changed during rotation", table.Name, stats.Scanned, stats.Rotated, stats.Changed)
+	}
+	if dryRun {
+		log.Println("Dry run, nothing was changed")
+		return
+	}
+	log.Println("✅ Rotation completed successfully")
+}
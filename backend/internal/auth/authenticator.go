@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	UserID uuid.UUID
+	Email  string
+	// Scopes is nil for session tokens, which may do everything the user can
+	Scopes  []string
+	TokenID *uuid.UUID
+	// SessionID is set for session tokens
+	SessionID *uuid.UUID
+}
+
+// IsScoped reports whether the identity comes from a personal access token
+func (i *Identity) IsScoped() bool {
+	return i.TokenID != nil
+}
+
+func (i *Identity) HasScope(scope string) bool {
+	if !i.IsScoped() {
+		return true
+	}
+	for _, granted := range i.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Authenticator accepts session JWTs and personal access tokens
+type Authenticator struct {
+	jwtManager *JWTManager
+	tokens     *TokenService
+	denylist   *Denylist
+}
+
+func NewAuthenticator(jwtManager *JWTManager, tokens *TokenService, denylist *Denylist) *Authenticator {
+	return &Authenticator{
+		jwtManager: jwtManager,
+		tokens:     tokens,
+		denylist:   denylist,
+	}
+}
+
+func (a *Authenticator) Authenticate(token string) (*Identity, error) {
+	if strings.HasPrefix(token, TokenPrefix) {
+		record, owner, err := a.tokens.Verify(token)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{
+			UserID:  owner.ID,
+			Email:   owner.Email,
+			Scopes:  record.Scopes,
+			TokenID: &record.ID,
+		}, nil
+	}
+
+	claims, err := a.jwtManager.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := a.denylist.IsDenied(context.Background(), claims.ID, claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token denylist: %w", err)
+	}
+	if denied {
+		return nil, ErrTokenRevoked
+	}
+
+	return &Identity{
+		UserID:    claims.UserID,
+		Email:     claims.Email,
+		SessionID: &claims.SessionID,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	deniedTokenKeyPrefix   = "devhub:auth:denied:jti:"
+	deniedSessionKeyPrefix = "devhub:auth:denied:sid:"
+)
+
+// Denylist keeps revoked access tokens in Redis until they would expire anyway,
+// so every API replica rejects them
+type Denylist struct {
+	client *redis.Client
+}
+
+func NewDenylist(client *redis.Client) *Denylist {
+	return &Denylist{client: client}
+}
+
+// DenyToken rejects a single access token until expiresAt
+func (d *Denylist) DenyToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return d.client.Set(ctx, deniedTokenKeyPrefix+jti, 1, ttl).Err()
+}
+
+// DenySession rejects every access token of a session. Tokens live at most ttl,
+// so the entry can expire with the last of them.
+func (d *Denylist) DenySession(ctx context.Context, sessionID uuid.UUID, ttl time.Duration) error {
+	return d.client.Set(ctx, deniedSessionKeyPrefix+sessionID.String(), 1, ttl).Err()
+}
+
+func (d *Denylist) IsDenied(ctx context.Context, jti string, sessionID uuid.UUID) (bool, error) {
+	count, err := d.client.Exists(ctx, deniedTokenKeyPrefix+jti, deniedSessionKeyPrefix+sessionID.String()).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,438 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/config"
+)
+
+const (
+	githubStateKeyPrefix = "devhub:auth:github:state:"
+	githubStateTTL       = 10 * time.Minute
+	githubScope          = "read:user user:email"
+
+	githubModeLogin = "login"
+	githubModeLink  = "link"
+)
+
+var (
+	ErrGitHubNotConfigured    = errors.New("github sign-in is not configured")
+	ErrInvalidOAuthState      = errors.New("invalid or expired oauth state")
+	ErrGitHubExchangeFailed   = errors.New("github code exchange failed")
+	ErrGitHubNoVerifiedEmail  = errors.New("github account has no verified email")
+	ErrGitHubAlreadyLinked    = errors.New("github account is linked to another user")
+	ErrGitHubNotLinked        = errors.New("github account is not linked")
+	ErrCannotUnlinkLastMethod = errors.New("cannot unlink the only sign-in method")
+)
+
+// githubState is kept in Redis between the redirect to GitHub and the callback
+type githubState struct {
+	Verifier string     `json:"verifier"`
+	Mode     string     `json:"mode"`
+	UserID   *uuid.UUID `json:"user_id,omitempty"`
+	// NonceHash binds the state to the browser that started the flow
+	NonceHash string `json:"nonce_hash"`
+}
+
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// GitHubResult is the outcome of a callback. Exactly one of Tokens, MFA or
+// Linked is set.
+type GitHubResult struct {
+	User   *User
+	Tokens *TokenPair
+	MFA    *MFAChallengeResponse
+	Linked bool
+}
+
+type GitHubAuthorizeResponse struct {
+	AuthorizeURL string `json:"authorize_url"`
+}
+
+// GitHubService implements sign-in with GitHub (OAuth code flow with state
+// and PKCE) and linking GitHub to an existing account
+type GitHubService struct {
+	db          *gorm.DB
+	redis       *redis.Client
+	authService *Service
+	cfg         config.GitHubConfig
+	httpClient  *http.Client
+}
+
+func NewGitHubService(db *gorm.DB, redisClient *redis.Client, authService *Service, cfg config.GitHubConfig) *GitHubService {
+	return &GitHubService{
+		db:          db,
+		redis:       redisClient,
+		authService: authService,
+		cfg:         cfg,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *GitHubService) Enabled() bool {
+	return s.cfg.ClientID != "" && s.cfg.ClientSecret != ""
+}
+
+func (s *GitHubService) FrontendCallbackURL() string {
+	return s.cfg.FrontendCallbackURL
+}
+
+func randomURLSafe(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// AuthorizeURL starts the flow. userID is set when an existing user links GitHub.
+// The returned nonce must be kept by the browser and passed to Callback, so a
+// callback URL started by someone else is rejected.
+func (s *GitHubService) AuthorizeURL(userID *uuid.UUID) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrGitHubNotConfigured
+	}
+
+	stateToken, err := randomURLSafe(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	verifier, err := randomURLSafe(48)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	nonce, err := randomURLSafe(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	state := githubState{Verifier: verifier, Mode: githubModeLogin, NonceHash: hashNonce(nonce)}
+	if userID != nil {
+		state.Mode = githubModeLink
+		state.UserID = userID
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := s.redis.Set(context.Background(), githubStateKeyPrefix+stateToken, encoded, githubStateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("failed to store state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("client_id", s.cfg.ClientID)
+	query.Set("redirect_uri", s.cfg.CallbackURL)
+	query.Set("scope", githubScope)
+	query.Set("state", stateToken)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	query.Set("allow_signup", "true")
+
+	return s.cfg.AuthorizeURL + "?" + query.Encode(), nonce, nil
+}
+
+// consumeState loads and deletes the state, so a callback URL works only once.
+// nonce must be the one AuthorizeURL returned for the state.
+func (s *GitHubService) consumeState(stateToken, nonce string) (*githubState, error) {
+	if stateToken == "" || nonce == "" {
+		return nil, ErrInvalidOAuthState
+	}
+
+	ctx := context.Background()
+	key := githubStateKeyPrefix + stateToken
+	pipe := s.redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidOAuthState
+		}
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var state githubState
+	if err := json.Unmarshal([]byte(get.Val()), &state); err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+	if subtle.ConstantTimeCompare([]byte(state.NonceHash), []byte(hashNonce(nonce))) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+	return &state, nil
+}
+
+// Callback completes the flow: it signs a user in (creating or linking an
+// account by verified email if needed) or links GitHub to the user who started it
+func (s *GitHubService) Callback(code, stateToken, nonce string, client ClientInfo) (*GitHubResult, error) {
+	if !s.Enabled() {
+		return nil, ErrGitHubNotConfigured
+	}
+
+	state, err := s.consumeState(stateToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, ErrGitHubExchangeFailed
+	}
+
+	accessToken, err := s.exchangeCode(code, state.Verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile githubUser
+	if err := s.getJSON(accessToken, "/user", &profile); err != nil {
+		return nil, err
+	}
+	if profile.ID == 0 {
+		return nil, ErrGitHubExchangeFailed
+	}
+	githubID := strconv.FormatInt(profile.ID, 10)
+
+	if state.Mode == githubModeLink && state.UserID != nil {
+		if err := s.link(*state.UserID, githubID, profile.Login); err != nil {
+			return nil, err
+		}
+		return &GitHubResult{Linked: true}, nil
+	}
+
+	foundUser, err := s.findOrCreateUser(accessToken, githubID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authService.requireSecondFactor(foundUser.ID); err != nil {
+		var mfaRequired *MFARequiredError
+		if errors.As(err, &mfaRequired) {
+			return &GitHubResult{User: foundUser, MFA: &mfaRequired.Challenge}, nil
+		}
+		return nil, err
+	}
+
+	tokens, err := s.authService.sessions.Start(foundUser.ID, foundUser.Email, client)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubResult{User: foundUser, Tokens: tokens}, nil
+}
+
+// Unlink removes GitHub from an account that can still sign in with a password
+func (s *GitHubService) Unlink(userID uuid.UUID) error {
+	var foundUser User
+	if err := s.db.First(&foundUser, "id = ? AND is_deleted = false", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+	if foundUser.GitHubID == nil {
+		return ErrGitHubNotLinked
+	}
+	if foundUser.PasswordHash == nil {
+		return ErrCannotUnlinkLastMethod
+	}
+
+	return s.db.Model(&foundUser).Updates(map[string]interface{}{
+		"github_id":       nil,
+		"github_username": nil,
+	}).Error
+}
+
+func (s *GitHubService) link(userID uuid.UUID, githubID, login string) error {
+	var existing User
+	err := s.db.Where("github_id = ?", githubID).First(&existing).Error
+	if err == nil && existing.ID != userID {
+		return ErrGitHubAlreadyLinked
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	result := s.db.Model(&User{}).
+		Where("id = ? AND is_deleted = false", userID).
+		Updates(map[string]interface{}{
+			"github_id":       githubID,
+			"github_username": login,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to link github account: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *GitHubService) findOrCreateUser(accessToken, githubID string, profile githubUser) (*User, error) {
+	var foundUser User
+	err := s.db.Where("github_id = ? AND is_deleted = false", githubID).First(&foundUser).Error
+	if err == nil {
+		if foundUser.GitHubUsername == nil || *foundUser.GitHubUsername != profile.Login {
+			_ = s.db.Model(&foundUser).Update("github_username", profile.Login).Error
+		}
+		return &foundUser, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// Only a verified email proves the GitHub account owns the DevHub account
+	email, err := s.primaryVerifiedEmail(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Where("LOWER(email) = ? AND is_deleted = false", email).First(&foundUser).Error
+	if err == nil {
+		if foundUser.GitHubID != nil {
+			return nil, ErrGitHubAlreadyLinked
+		}
+		if err := s.link(foundUser.ID, githubID, profile.Login); err != nil {
+			return nil, err
+		}
+		foundUser.GitHubID = &githubID
+		foundUser.GitHubUsername = &profile.Login
+		return &foundUser, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	name := strings.TrimSpace(profile.Name)
+	if name == "" {
+		name = profile.Login
+	}
+	newUser := User{
+		Email:          email,
+		Name:           name,
+		GitHubID:       &githubID,
+		GitHubUsername: &profile.Login,
+	}
+	if profile.AvatarURL != "" {
+		newUser.AvatarURL = &profile.AvatarURL
+	}
+	if s.handleAvailable(profile.Login) {
+		newUser.Handle = &profile.Login
+	}
+
+	if err := s.db.Create(&newUser).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &newUser, nil
+}
+
+func (s *GitHubService) handleAvailable(handle string) bool {
+	if handle == "" {
+		return false
+	}
+	var count int64
+	if err := s.db.Model(&User{}).Where("LOWER(handle) = LOWER(?)", handle).Count(&count).Error; err != nil {
+		return false
+	}
+	return count == 0
+}
+
+func (s *GitHubService) exchangeCode(code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("client_id", s.cfg.ClientID)
+	form.Set("client_secret", s.cfg.ClientSecret)
+	form.Set("code", code)
+	form.Set("redirect_uri", s.cfg.CallbackURL)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrGitHubExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrGitHubExchangeFailed, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("%w: status %d %s", ErrGitHubExchangeFailed, resp.StatusCode, body.Error)
+	}
+	return body.AccessToken, nil
+}
+
+func (s *GitHubService) primaryVerifiedEmail(accessToken string) (string, error) {
+	var emails []githubEmail
+	if err := s.getJSON(accessToken, "/user/emails", &emails); err != nil {
+		return "", err
+	}
+
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			return strings.ToLower(email.Email), nil
+		}
+	}
+	return "", ErrGitHubNoVerifiedEmail
+}
+
+func (s *GitHubService) getJSON(accessToken, path string, dest interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(s.cfg.APIURL, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build github request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGitHubExchangeFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s returned %d", ErrGitHubExchangeFailed, path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dest); err != nil {
+		return fmt.Errorf("%w: %v", ErrGitHubExchangeFailed, err)
+	}
+	return nil
+}
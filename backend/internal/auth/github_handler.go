@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// githubNonceCookie holds the nonce that binds an OAuth state to the browser
+const githubNonceCookie = "devhub_github_nonce"
+
+type GitHubHandler struct {
+	service *GitHubService
+}
+
+func NewGitHubHandler(service *GitHubService) *GitHubHandler {
+	return &GitHubHandler{service: service}
+}
+
+// redirectToFrontend hands the result to the web app in the URL fragment,
+// which browsers never send to servers
+func (h *GitHubHandler) redirectToFrontend(c *fiber.Ctx, values url.Values) error {
+	return c.Redirect(h.service.FrontendCallbackURL()+"#"+values.Encode(), fiber.StatusFound)
+}
+
+func (h *GitHubHandler) redirectError(c *fiber.Ctx, code string) error {
+	return h.redirectToFrontend(c, url.Values{"error": {code}})
+}
+
+// setNonceCookie stores the nonce for the callback. SameSite=Lax still sends it
+// on the top-level redirect back from GitHub.
+func setNonceCookie(c *fiber.Ctx, nonce string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     githubNonceCookie,
+		Value:    nonce,
+		Path:     "/api/auth/github",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   c.Secure(),
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// Start GitHub sign-in
+// GET /api/auth/github
+func (h *GitHubHandler) Start(c *fiber.Ctx) error {
+	authorizeURL, nonce, err := h.service.AuthorizeURL(nil)
+	if err != nil {
+		if errors.Is(err, ErrGitHubNotConfigured) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "GitHub sign-in is not configured",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start GitHub sign-in",
+		})
+	}
+
+	setNonceCookie(c, nonce, time.Now().Add(githubStateTTL))
+	return c.Redirect(authorizeURL, fiber.StatusFound)
+}
+
+// Finish the GitHub OAuth flow and redirect to the web app
+// GET /api/auth/github/callback
+func (h *GitHubHandler) Callback(c *fiber.Ctx) error {
+	nonce := c.Cookies(githubNonceCookie)
+	setNonceCookie(c, "", time.Unix(0, 0))
+
+	if providerError := c.Query("error"); providerError != "" {
+		return h.redirectError(c, providerError)
+	}
+
+	result, err := h.service.Callback(c.Query("code"), c.Query("state"), nonce, ClientFromRequest(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrGitHubNotConfigured):
+			return h.redirectError(c, "not_configured")
+		case errors.Is(err, ErrInvalidOAuthState):
+			return h.redirectError(c, "invalid_state")
+		case errors.Is(err, ErrGitHubNoVerifiedEmail):
+			return h.redirectError(c, "no_verified_email")
+		case errors.Is(err, ErrGitHubAlreadyLinked):
+			return h.redirectError(c, "already_linked")
+		case errors.Is(err, ErrGitHubExchangeFailed):
+			log.Printf("github oauth: %v", err)
+			return h.redirectError(c, "exchange_failed")
+		default:
+			log.Printf("github oauth: %v", err)
+			return h.redirectError(c, "server_error")
+		}
+	}
+
+	switch {
+	case result.Linked:
+		return h.redirectToFrontend(c, url.Values{"linked": {"github"}})
+	case result.MFA != nil:
+		return h.redirectToFrontend(c, url.Values{
+			"mfa_token":  {result.MFA.MFAToken},
+			"expires_at": {result.MFA.ExpiresAt.Format(time.RFC3339)},
+		})
+	default:
+		return h.redirectToFrontend(c, url.Values{
+			"token":         {result.Tokens.AccessToken},
+			"refresh_token": {result.Tokens.RefreshToken},
+			"expires_at":    {result.Tokens.ExpiresAt.Format(time.RFC3339)},
+		})
+	}
+}
+
+// Start linking GitHub to the current account. Returns the URL the browser
+// should open, since a redirect cannot carry the Authorization header.
+// POST /api/auth/github/link
+func (h *GitHubHandler) Link(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	authorizeURL, nonce, err := h.service.AuthorizeURL(&userID)
+	if err != nil {
+		if errors.Is(err, ErrGitHubNotConfigured) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "GitHub sign-in is not configured",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start GitHub linking",
+		})
+	}
+
+	setNonceCookie(c, nonce, time.Now().Add(githubStateTTL))
+	return c.JSON(GitHubAuthorizeResponse{AuthorizeURL: authorizeURL})
+}
+
+// Unlink GitHub from the current account
+// DELETE /api/auth/github/link
+func (h *GitHubHandler) Unlink(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.service.Unlink(userID); err != nil {
+		switch {
+		case errors.Is(err, ErrGitHubNotLinked):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "GitHub account is not linked",
+			})
+		case errors.Is(err, ErrCannotUnlinkLastMethod):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Set a password before unlinking GitHub",
+			})
+		case errors.Is(err, ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to unlink GitHub",
+			})
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
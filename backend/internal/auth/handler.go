@@ -2,6 +2,8 @@ package auth
 
 import (
 	"errors"
+	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -69,7 +71,7 @@ func (h *Handler) ConfirmRegister(c *fiber.Ctx) error {
 		})
 	}
 
-	newUser, token, err := h.service.ConfirmRegistration(req)
+	newUser, tokens, err := h.service.ConfirmRegistration(req, ClientFromRequest(c))
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrEmailAlreadyExists):
@@ -99,10 +101,7 @@ func (h *Handler) ConfirmRegister(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(LoginResponse{
-		Token: token,
-		User:  *newUser,
-	})
+	return c.Status(fiber.StatusCreated).JSON(NewLoginResponse(*newUser, tokens))
 }
 
 // ResendRegister handler
@@ -155,8 +154,19 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	foundUser, token, err := h.service.Login(req)
+	foundUser, tokens, err := h.service.Login(req, ClientFromRequest(c))
 	if err != nil {
+		var mfaRequired *MFARequiredError
+		if errors.As(err, &mfaRequired) {
+			return c.JSON(mfaRequired.Challenge)
+		}
+		var locked *AccountLockedError
+		if errors.As(err, &locked) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many failed login attempts, try again later",
+			})
+		}
 		if errors.Is(err, ErrInvalidCredentials) {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid email or password",
@@ -167,10 +177,79 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(LoginResponse{
-		Token: token,
-		User:  *foundUser,
-	})
+	return c.JSON(NewLoginResponse(*foundUser, tokens))
+}
+
+// ForgotPassword sends a password reset code
+// POST /api/auth/password/forgot
+func (h *Handler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	result, err := h.service.ForgotPassword(req.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to send password reset code",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(result)
+}
+
+// ResetPassword sets a new password with a reset code
+// POST /api/auth/password/reset
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	if err := h.service.ResetPassword(req); err != nil {
+		switch {
+		case errors.Is(err, ErrConfirmationNotFound), errors.Is(err, ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Password reset not found",
+			})
+		case errors.Is(err, ErrCodeExpired):
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error": "Verification code expired",
+			})
+		case errors.Is(err, ErrTooManyAttempts):
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many verification attempts",
+			})
+		case errors.Is(err, ErrInvalidCode):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid verification code",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to reset password",
+			})
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
 // GetMe handler - returns current authenticated user
@@ -11,33 +11,60 @@ import (
 type JWTClaims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	// SessionID ties the access token to the session that issued it
+	SessionID uuid.UUID `json:"sid"`
 	jwt.RegisteredClaims
 }
 
+// MFAChallengeClaims identify a user who passed the password step of a login
+// and still has to present a second factor
+type MFAChallengeClaims struct {
+	UserID uuid.UUID `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+const (
+	mfaChallengeAudience = "devhub:mfa"
+	mfaChallengeTTL      = 5 * time.Minute
+)
+
 type JWTManager struct {
-	secret      string
-	expireHours int
+	secret    string
+	accessTTL time.Duration
 }
 
-func NewJWTManager(secret string, expireHours int) *JWTManager {
+func NewJWTManager(secret string, accessTTL time.Duration) *JWTManager {
 	return &JWTManager{
-		secret:      secret,
-		expireHours: expireHours,
+		secret:    secret,
+		accessTTL: accessTTL,
 	}
 }
 
-func (m *JWTManager) Generate(userID uuid.UUID, email string) (string, error) {
+func (m *JWTManager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// Generate issues an access token for a session. Every token gets its own jti
+// so it can be denylisted on its own.
+func (m *JWTManager) Generate(userID uuid.UUID, email string, sessionID uuid.UUID) (string, *JWTClaims, error) {
+	now := time.Now()
 	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(m.expireHours))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secret))
+	signed, err := token.SignedString([]byte(m.secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, &claims, nil
 }
 
 func (m *JWTManager) Verify(tokenString string) (*JWTClaims, error) {
@@ -52,9 +79,57 @@ func (m *JWTManager) Verify(tokenString string) (*JWTClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	// Tokens issued before sessions existed cannot be revoked
+	if claims.ID == "" || claims.SessionID == uuid.Nil {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
+// GenerateMFAChallenge issues the short-lived token of the second login step.
+// It has no session, so Verify never accepts it as an access token.
+func (m *JWTManager) GenerateMFAChallenge(userID uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(mfaChallengeTTL)
+	claims := MFAChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(m.secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+func (m *JWTManager) VerifyMFAChallenge(tokenString string) (*MFAChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MFAChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(m.secret), nil
+	}, jwt.WithAudience(mfaChallengeAudience))
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*MFAChallengeClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
 }
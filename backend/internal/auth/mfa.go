@@ -0,0 +1,491 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	recoveryCodeCount    = 10
+	maxMFAFailedAttempts = 5
+	mfaLockDuration      = 15 * time.Minute
+)
+
+var (
+	ErrMFANotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled     = errors.New("two-factor enrollment not started")
+	ErrInvalidMFACode     = errors.New("invalid two-factor code")
+	ErrMFALocked          = errors.New("too many invalid two-factor codes")
+	ErrInvalidMFAToken    = errors.New("invalid or expired two-factor challenge")
+	ErrMFARequiredForUser = errors.New("two-factor authentication required")
+)
+
+// SecretBox encrypts TOTP secrets at rest (implemented by deploy.Encryptor)
+type SecretBox interface {
+	Encrypt(plain []byte) (string, error)
+	Decrypt(encoded string) ([]byte, error)
+}
+
+// UserMFA holds the TOTP secret of a user. EnabledAt stays nil until the
+// user proved they can generate codes with it.
+type UserMFA struct {
+	UserID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	SecretEncrypted string    `gorm:"not null"`
+	EnabledAt       *time.Time
+	LastUsedStep    int64 `gorm:"not null;default:0"`
+	FailedAttempts  int   `gorm:"not null;default:0"`
+	LockedUntil     *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// MFARecoveryCode is a hashed one-time code for when the authenticator is lost
+type MFARecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID `gorm:"not null"`
+	CodeHash  string    `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+// MFARequiredError is returned by Login when the password was right but a
+// second factor is still needed
+type MFARequiredError struct {
+	Challenge MFAChallengeResponse
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequiredForUser.Error()
+}
+
+func (e *MFARequiredError) Unwrap() error {
+	return ErrMFARequiredForUser
+}
+
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	Pending                bool       `json:"pending"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=20"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,min=6,max=20"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,min=6,max=20"`
+}
+
+// MFAService manages TOTP enrollment, recovery codes and the second login step
+type MFAService struct {
+	db         *gorm.DB
+	jwtManager *JWTManager
+	sessions   *SessionService
+	secrets    SecretBox
+}
+
+func NewMFAService(db *gorm.DB, jwtManager *JWTManager, sessions *SessionService, secrets SecretBox) *MFAService {
+	return &MFAService{
+		db:         db,
+		jwtManager: jwtManager,
+		sessions:   sessions,
+		secrets:    secrets,
+	}
+}
+
+// IsEnabled reports whether the user finished TOTP enrollment
+func (s *MFAService) IsEnabled(userID uuid.UUID) (bool, error) {
+	var count int64
+	err := s.db.Model(&UserMFA{}).
+		Where("user_id = ? AND enabled_at IS NOT NULL", userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Challenge starts the second login step for a user with 2FA enabled
+func (s *MFAService) Challenge(userID uuid.UUID) (*MFAChallengeResponse, error) {
+	token, expiresAt, err := s.jwtManager.GenerateMFAChallenge(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return &MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+func (s *MFAService) Status(userID uuid.UUID) (*MFAStatusResponse, error) {
+	var mfa UserMFA
+	if err := s.db.First(&mfa, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &MFAStatusResponse{}, nil
+		}
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+
+	var remaining int64
+	if err := s.db.Model(&MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&remaining).Error; err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return &MFAStatusResponse{
+		Enabled:                mfa.EnabledAt != nil,
+		EnabledAt:              mfa.EnabledAt,
+		Pending:                mfa.EnabledAt == nil,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Enroll creates a new pending TOTP secret. Calling it again replaces the
+// pending secret, e.g. when the QR code was not scanned in time.
+func (s *MFAService) Enroll(userID uuid.UUID) (*MFAEnrollResponse, error) {
+	var owner User
+	if err := s.db.First(&owner, "id = ? AND is_deleted = false", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	encrypted, err := s.secrets.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	mfa := UserMFA{UserID: userID, SecretEncrypted: encrypted}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret_encrypted", "last_used_step", "failed_attempts", "locked_until", "updated_at"}),
+	}).Create(&mfa).Error; err != nil {
+		return nil, fmt.Errorf("failed to save two-factor settings: %w", err)
+	}
+
+	return &MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(secret, owner.Email),
+	}, nil
+}
+
+// Verify finishes enrollment with a code from the authenticator app. Other
+// sessions are signed out, so every remaining session has passed 2FA.
+func (s *MFAService) Verify(userID uuid.UUID, code string, currentSession *uuid.UUID) (*MFARecoveryCodesResponse, error) {
+	var codes []string
+	valid := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		mfa, err := s.lockMFA(tx, userID)
+		if err != nil {
+			if errors.Is(err, ErrMFANotEnabled) {
+				return ErrMFANotEnrolled
+			}
+			return err
+		}
+		if mfa.EnabledAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+
+		if valid, err = s.checkCode(tx, mfa, code, false); err != nil || !valid {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(mfa).Update("enabled_at", now).Error; err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.sessions.RevokeAll(userID, currentSession); err != nil {
+		return nil, err
+	}
+
+	return &MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns 2FA off, which needs both the password and a current code
+func (s *MFAService) Disable(userID uuid.UUID, req MFADisableRequest) error {
+	var owner User
+	if err := s.db.First(&owner, "id = ? AND is_deleted = false", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+	if owner.PasswordHash == nil {
+		return ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(*owner.PasswordHash), []byte(req.Password)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	valid := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		mfa, err := s.lockMFA(tx, userID)
+		if err != nil {
+			return err
+		}
+		if mfa.EnabledAt == nil {
+			return ErrMFANotEnabled
+		}
+
+		if valid, err = s.checkCode(tx, mfa, req.Code, true); err != nil || !valid {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&MFARecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := tx.Delete(mfa).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID, code string) (*MFARecoveryCodesResponse, error) {
+	var codes []string
+	valid := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		mfa, err := s.lockMFA(tx, userID)
+		if err != nil {
+			return err
+		}
+		if mfa.EnabledAt == nil {
+			return ErrMFANotEnabled
+		}
+
+		if valid, err = s.checkCode(tx, mfa, code, false); err != nil || !valid {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidMFACode
+	}
+
+	return &MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// CompleteLogin checks the second factor of a login and starts the session
+func (s *MFAService) CompleteLogin(req MFALoginRequest, client ClientInfo) (*User, *TokenPair, error) {
+	claims, err := s.jwtManager.VerifyMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	var owner User
+	if err := s.db.First(&owner, "id = ? AND is_deleted = false", claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidMFAToken
+		}
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+
+	valid := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		mfa, err := s.lockMFA(tx, owner.ID)
+		if err != nil {
+			return err
+		}
+		if mfa.EnabledAt == nil {
+			return ErrMFANotEnabled
+		}
+		valid, err = s.checkCode(tx, mfa, req.Code, true)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			return nil, nil, ErrInvalidMFAToken
+		}
+		return nil, nil, err
+	}
+	if !valid {
+		return nil, nil, ErrInvalidMFACode
+	}
+
+	tokens, err := s.sessions.Start(owner.ID, owner.Email, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &owner, tokens, nil
+}
+
+func (s *MFAService) lockMFA(tx *gorm.DB, userID uuid.UUID) (*UserMFA, error) {
+	var mfa UserMFA
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&mfa, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	return &mfa, nil
+}
+
+// checkCode accepts a TOTP code, or a recovery code when allowRecovery is set.
+// It returns false for a wrong code after recording the failed attempt, so the
+// caller must commit its transaction instead of rolling it back.
+// Repeated failures lock the second factor for a while.
+func (s *MFAService) checkCode(tx *gorm.DB, mfa *UserMFA, code string, allowRecovery bool) (bool, error) {
+	now := time.Now()
+	if mfa.LockedUntil != nil && now.Before(*mfa.LockedUntil) {
+		return false, ErrMFALocked
+	}
+
+	secret, err := s.secrets.Decrypt(mfa.SecretEncrypted)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	if step, ok := validateTOTP(string(secret), code, mfa.LastUsedStep, now); ok {
+		return true, tx.Model(mfa).Updates(map[string]interface{}{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+			"locked_until":    nil,
+		}).Error
+	}
+
+	if allowRecovery {
+		result := tx.Model(&MFARecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", mfa.UserID, hashToken(normalizeRecoveryCode(code))).
+			Update("used_at", now)
+		if result.Error != nil {
+			return false, fmt.Errorf("failed to use recovery code: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			return true, tx.Model(mfa).Updates(map[string]interface{}{
+				"failed_attempts": 0,
+				"locked_until":    nil,
+			}).Error
+		}
+	}
+
+	updates := map[string]interface{}{"failed_attempts": mfa.FailedAttempts + 1}
+	if mfa.FailedAttempts+1 >= maxMFAFailedAttempts {
+		updates["failed_attempts"] = 0
+		updates["locked_until"] = now.Add(mfaLockDuration)
+	}
+	if err := tx.Model(mfa).Updates(updates).Error; err != nil {
+		return false, fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+	return false, nil
+}
+
+// replaceRecoveryCodes returns the new codes in plain text, only hashes are stored
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&MFARecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]MFARecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		records = append(records, MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k3xq-7mvd-p2za"
+func generateRecoveryCode() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	buf := make([]byte, 12)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = alphabet[n.Int64()]
+	}
+	return fmt.Sprintf("%s-%s-%s", buf[0:4], buf[4:8], buf[8:12]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
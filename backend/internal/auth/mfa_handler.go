@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+type MFAHandler struct {
+	service *MFAService
+}
+
+func NewMFAHandler(service *MFAService) *MFAHandler {
+	return &MFAHandler{service: service}
+}
+
+func (h *MFAHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, ErrInvalidMFACode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid two-factor code"})
+	case errors.Is(err, ErrInvalidMFAToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired two-factor challenge"})
+	case errors.Is(err, ErrInvalidCredentials):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid password"})
+	case errors.Is(err, ErrMFALocked):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many invalid two-factor codes, try again later"})
+	case errors.Is(err, ErrMFAAlreadyEnabled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	case errors.Is(err, ErrMFANotEnabled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	case errors.Is(err, ErrMFANotEnrolled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor enrollment not started"})
+	case errors.Is(err, ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+}
+
+// Complete a login with a TOTP or recovery code
+// POST /api/auth/login/mfa
+func (h *MFAHandler) Login(c *fiber.Ctx) error {
+	var req MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	foundUser, tokens, err := h.service.CompleteLogin(req, ClientFromRequest(c))
+	if err != nil {
+		return h.respondError(c, err, "Failed to login")
+	}
+
+	return c.JSON(NewLoginResponse(*foundUser, tokens))
+}
+
+// Get two-factor status
+// GET /api/auth/mfa
+func (h *MFAHandler) Status(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	status, err := h.service.Status(userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get two-factor status")
+	}
+
+	return c.JSON(status)
+}
+
+// Start TOTP enrollment
+// POST /api/auth/mfa/enroll
+func (h *MFAHandler) Enroll(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	result, err := h.service.Enroll(userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to start two-factor enrollment")
+	}
+
+	return c.JSON(result)
+}
+
+// Finish TOTP enrollment, returns the recovery codes once
+// POST /api/auth/mfa/verify
+func (h *MFAHandler) Verify(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	result, err := h.service.Verify(userID, req.Code, CurrentSessionID(c))
+	if err != nil {
+		return h.respondError(c, err, "Failed to enable two-factor authentication")
+	}
+
+	return c.JSON(result)
+}
+
+// Disable two-factor authentication
+// POST /api/auth/mfa/disable
+func (h *MFAHandler) Disable(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req MFADisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	if err := h.service.Disable(userID, req); err != nil {
+		return h.respondError(c, err, "Failed to disable two-factor authentication")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Regenerate recovery codes
+// POST /api/auth/mfa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	result, err := h.service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		return h.respondError(c, err, "Failed to regenerate recovery codes")
+	}
+
+	return c.JSON(result)
+}
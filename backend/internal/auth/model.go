@@ -6,9 +6,16 @@ import (
 	"github.com/google/uuid"
 )
 
+// Purposes of an email confirmation code
+const (
+	ConfirmationPurposeVerifyEmail   = "verify_email"
+	ConfirmationPurposePasswordReset = "password_reset"
+)
+
 type EmailConfirmation struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
+	Email     string    `json:"email" gorm:"not null"`
+	Purpose   string    `json:"purpose" gorm:"not null;default:verify_email"`
 	Code      string    `json:"code" gorm:"not null"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	Attempts  int       `json:"attempts" gorm:"not null;default:0"`
@@ -32,6 +39,16 @@ type RegisterResendRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Email       string `json:"email" validate:"required,email"`
+	Code        string `json:"code" validate:"required,len=6"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
 type RegisterStartResponse struct {
 	ExpiresAt time.Time `json:"expires_at"`
 }
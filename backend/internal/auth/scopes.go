@@ -0,0 +1,43 @@
+package auth
+
+// Scopes a personal access token can be granted.
+// Session tokens from /api/auth/login are not scoped.
+const (
+	ScopeUserRead          = "user:read"
+	ScopeProjectsRead      = "projects:read"
+	ScopeProjectsWrite     = "projects:write"
+	ScopeTopicsRead        = "topics:read"
+	ScopeTopicsWrite       = "topics:write"
+	ScopeMessagesRead      = "messages:read"
+	ScopeMessagesWrite     = "messages:write"
+	ScopeNotificationsRead = "notifications:read"
+	ScopeCodeRead          = "code:read"
+	ScopeCodeWrite         = "code:write"
+	ScopeDeployRead        = "deploy:read"
+	ScopeDeployWrite       = "deploy:write"
+	ScopeDeployTerminal    = "deploy:terminal"
+	ScopeWebhooksManage    = "webhooks:manage"
+)
+
+// ScopeDescriptions lists every scope with a short description
+var ScopeDescriptions = map[string]string{
+	ScopeUserRead:          "Read your profile and search users",
+	ScopeProjectsRead:      "Read projects and their members",
+	ScopeProjectsWrite:     "Create and change projects, members and invitations",
+	ScopeTopicsRead:        "Read topics",
+	ScopeTopicsWrite:       "Create, change and mark topics read",
+	ScopeMessagesRead:      "Read messages, threads, files and search",
+	ScopeMessagesWrite:     "Post, edit, react to and pin messages",
+	ScopeNotificationsRead: "Read and acknowledge notifications",
+	ScopeCodeRead:          "Read repositories",
+	ScopeCodeWrite:         "Change repositories and files",
+	ScopeDeployRead:        "Read deploy servers",
+	ScopeDeployWrite:       "Add and change deploy servers, scripts and pipelines",
+	ScopeDeployTerminal:    "Open, share and watch deploy terminals, browse and transfer server files, run deploy scripts and pipelines, approve pipeline stages and read recordings and logs",
+	ScopeWebhooksManage:    "Manage outgoing and incoming webhooks",
+}
+
+func IsValidScope(scope string) bool {
+	_, ok := ScopeDescriptions[scope]
+	return ok
+}
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"log"
 	"math/big"
 	"time"
 
@@ -26,9 +29,11 @@ var (
 )
 
 type Service struct {
-	db         *gorm.DB
-	jwtManager *JWTManager
-	mailer     mailer.Sender
+	db       *gorm.DB
+	sessions *SessionService
+	mailer   mailer.Sender
+	mfa      *MFAService   // optional
+	lockout  *LoginLockout // optional
 }
 
 const (
@@ -41,21 +46,31 @@ func MaxVerificationAttempts() int {
 	return maxVerificationAttempts
 }
 
-func NewService(db *gorm.DB, jwtManager *JWTManager, mailerClient mailer.Sender) *Service {
+func NewService(db *gorm.DB, sessions *SessionService, mailerClient mailer.Sender) *Service {
 	return &Service{
-		db:         db,
-		jwtManager: jwtManager,
-		mailer:     mailerClient,
+		db:       db,
+		sessions: sessions,
+		mailer:   mailerClient,
 	}
 }
 
+// SetMFAService enables the two-factor login step (called from main.go)
+func (s *Service) SetMFAService(mfa *MFAService) {
+	s.mfa = mfa
+}
+
+// SetLoginLockout locks accounts after repeated failed logins (called from main.go)
+func (s *Service) SetLoginLockout(lockout *LoginLockout) {
+	s.lockout = lockout
+}
+
 // StartRegistration creates a pending confirmation and sends a code.
 func (s *Service) StartRegistration(req RegisterRequest) (RegisterStartResponse, error) {
 	if err := s.ensureEmailAvailable(req.Email); err != nil {
 		return RegisterStartResponse{}, err
 	}
 
-	confirmation, err := s.upsertConfirmation(req.Email)
+	confirmation, err := s.upsertConfirmation(req.Email, ConfirmationPurposeVerifyEmail)
 	if err != nil {
 		return RegisterStartResponse{}, err
 	}
@@ -73,7 +88,7 @@ func (s *Service) ResendRegistrationCode(email string) (RegisterStartResponse, e
 		return RegisterStartResponse{}, err
 	}
 
-	confirmation, err := s.upsertConfirmation(email)
+	confirmation, err := s.upsertConfirmation(email, ConfirmationPurposeVerifyEmail)
 	if err != nil {
 		return RegisterStartResponse{}, err
 	}
@@ -88,37 +103,36 @@ func (s *Service) ResendRegistrationCode(email string) (RegisterStartResponse, e
 }
 
 // ConfirmRegistration verifies the code and creates the 
-func (s *Service) ConfirmRegistration(req RegisterConfirmRequest) (*User, string, error) {
+func (s *Service) ConfirmRegistration(req RegisterConfirmRequest, client ClientInfo) (*User, *TokenPair, error) {
 	if err := s.ensureEmailAvailable(req.Email); err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	var confirmation EmailConfirmation
-	if err := s.db.Where("email = ?", req.Email).First(&confirmation).Error; err != nil {
+	if err := s.db.Where("email = ? AND purpose = ?", req.Email, ConfirmationPurposeVerifyEmail).First(&confirmation).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, "", ErrConfirmationNotFound
+			return nil, nil, ErrConfirmationNotFound
 		}
-		return nil, "", fmt.Errorf("database error: %w", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
 	}
 
 	if confirmation.ExpiresAt.Before(time.Now()) {
-		return nil, "", ErrCodeExpired
+		return nil, nil, ErrCodeExpired
 	}
 
 	if confirmation.Attempts >= maxVerificationAttempts {
-		return nil, "", ErrTooManyAttempts
+		return nil, nil, ErrTooManyAttempts
 	}
 
 	if confirmation.Code != req.Code {
 		confirmation.Attempts++
 		if err := s.db.Save(&confirmation).Error; err != nil {
-			return nil, "", fmt.Errorf("failed to update confirmation attempts: %w", err)
+			return nil, nil, fmt.Errorf("failed to update confirmation attempts: %w", err)
 		}
-		return nil, "", ErrInvalidCode
+		return nil, nil, ErrInvalidCode
 	}
 
 	var createdUser *User
-	var token string
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		newUser, err := s.createUser(tx, req)
 		if err != nil {
@@ -129,47 +143,122 @@ func (s *Service) ConfirmRegistration(req RegisterConfirmRequest) (*User, string
 			return fmt.Errorf("failed to delete confirmation: %w", err)
 		}
 
-		jwtToken, err := s.jwtManager.Generate(newUser.ID, newUser.Email)
-		if err != nil {
-			return fmt.Errorf("failed to generate token: %w", err)
-		}
-
 		createdUser = newUser
-		token = jwtToken
 		return nil
 	}); err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return createdUser, token, nil
+	tokens, err := s.sessions.Start(createdUser.ID, createdUser.Email, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return createdUser, tokens, nil
 }
 
 // Login user
-func (s *Service) Login(req LoginRequest) (*User, string, error) {
+func (s *Service) Login(req LoginRequest, client ClientInfo) (*User, *TokenPair, error) {
+	if err := s.CheckLockout(req.Email); err != nil {
+		return nil, nil, err
+	}
+
 	var foundUser User
 	if err := s.db.Where("email = ? AND is_deleted = false", req.Email).First(&foundUser).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, "", ErrInvalidCredentials
+			return nil, nil, s.loginFailed(req.Email)
 		}
-		return nil, "", fmt.Errorf("database error: %w", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
 	}
 
 	// Check password
 	if foundUser.PasswordHash == nil {
-		return nil, "", ErrInvalidCredentials
+		return nil, nil, s.loginFailed(req.Email)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(*foundUser.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, "", ErrInvalidCredentials
+		return nil, nil, s.loginFailed(req.Email)
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.Generate(foundUser.ID, foundUser.Email)
+	s.ResetLockout(req.Email)
+
+	if err := s.requireSecondFactor(foundUser.ID); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.sessions.Start(foundUser.ID, foundUser.Email, client)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, nil, err
 	}
 
-	return &foundUser, token, nil
+	return &foundUser, tokens, nil
+}
+
+// loginFailed records a failed login and returns the error to report,
+// an AccountLockedError once the failure locked the account
+func (s *Service) loginFailed(email string) error {
+	if s.lockout == nil {
+		return ErrInvalidCredentials
+	}
+	if err := s.lockout.RecordFailure(context.Background(), email); err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			return err
+		}
+		log.Printf("failed to record failed login: %v", err)
+	}
+	return ErrInvalidCredentials
+}
+
+// CheckLockout returns an AccountLockedError while failed password checks lock
+// the account. Other password checks of a signed in user share the lockout.
+func (s *Service) CheckLockout(email string) error {
+	if s.lockout == nil {
+		return nil
+	}
+	if err := s.lockout.Check(context.Background(), email); err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			return err
+		}
+		log.Printf("failed to check login lockout: %v", err)
+	}
+	return nil
+}
+
+// PasswordFailed records a wrong password like a failed login. It returns
+// ErrInvalidCredentials, or an AccountLockedError once the account is locked.
+func (s *Service) PasswordFailed(email string) error {
+	return s.loginFailed(email)
+}
+
+// ResetLockout forgets failed password checks after a correct password
+func (s *Service) ResetLockout(email string) {
+	if s.lockout == nil {
+		return
+	}
+	if err := s.lockout.Reset(context.Background(), email); err != nil {
+		log.Printf("failed to reset login lockout: %v", err)
+	}
+}
+
+// requireSecondFactor returns an MFARequiredError when the user has 2FA enabled
+func (s *Service) requireSecondFactor(userID uuid.UUID) error {
+	if s.mfa == nil {
+		return nil
+	}
+
+	enabled, err := s.mfa.IsEnabled(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	challenge, err := s.mfa.Challenge(userID)
+	if err != nil {
+		return err
+	}
+	return &MFARequiredError{Challenge: *challenge}
 }
 
 // GetUserByID
@@ -184,21 +273,107 @@ func (s *Service) GetUserByID(userID uuid.UUID) (*User, error) {
 	return &foundUser, nil
 }
 
-// VerifyToken
-func (s *Service) VerifyToken(tokenString string) (*JWTClaims, error) {
-	return s.jwtManager.Verify(tokenString)
-}
-
 func (s *Service) EnsureEmailAvailable(email string) error {
 	return s.ensureEmailAvailable(email)
 }
 
 func (s *Service) UpsertConfirmation(email string) (*EmailConfirmation, error) {
-	return s.upsertConfirmation(email)
+	return s.upsertConfirmation(email, ConfirmationPurposeVerifyEmail)
 }
 
-func (s *Service) GenerateToken(userID uuid.UUID, email string) (string, error) {
-	return s.jwtManager.Generate(userID, email)
+// StartSession opens a session for a user, e.g. after their email changed
+func (s *Service) StartSession(userID uuid.UUID, email string, client ClientInfo) (*TokenPair, error) {
+	return s.sessions.Start(userID, email, client)
+}
+
+// RevokeSessions signs a user out everywhere except the given session, if any
+func (s *Service) RevokeSessions(userID uuid.UUID, except *uuid.UUID) error {
+	return s.sessions.RevokeAll(userID, except)
+}
+
+// ForgotPassword sends a password reset code. Unknown emails are not reported
+// so the endpoint cannot be used to find out who has an account.
+func (s *Service) ForgotPassword(email string) (RegisterStartResponse, error) {
+	response := RegisterStartResponse{ExpiresAt: time.Now().Add(verificationTTL)}
+
+	var foundUser User
+	if err := s.db.Where("email = ? AND is_deleted = false AND is_bot = false", email).First(&foundUser).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response, nil
+		}
+		return RegisterStartResponse{}, fmt.Errorf("database error: %w", err)
+	}
+
+	confirmation, err := s.upsertConfirmation(foundUser.Email, ConfirmationPurposePasswordReset)
+	if err != nil {
+		return RegisterStartResponse{}, err
+	}
+
+	if err := s.mailer.SendPasswordResetCode(foundUser.Email, confirmation.Code); err != nil {
+		return RegisterStartResponse{}, fmt.Errorf("failed to send password reset code: %w", err)
+	}
+
+	return RegisterStartResponse{ExpiresAt: confirmation.ExpiresAt}, nil
+}
+
+// ResetPassword sets a new password with a reset code and signs the user out everywhere
+func (s *Service) ResetPassword(req ResetPasswordRequest) error {
+	var confirmation EmailConfirmation
+	if err := s.db.Where("email = ? AND purpose = ?", req.Email, ConfirmationPurposePasswordReset).First(&confirmation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrConfirmationNotFound
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if confirmation.ExpiresAt.Before(time.Now()) {
+		return ErrCodeExpired
+	}
+
+	// The attempt is counted before the code is compared so concurrent
+	// requests cannot try more codes than allowed
+	var attempts []int
+	if err := s.db.Raw(
+		"UPDATE email_confirmations SET attempts = attempts + 1, updated_at = NOW() WHERE id = ? AND attempts < ? RETURNING attempts",
+		confirmation.ID, maxVerificationAttempts,
+	).Scan(&attempts).Error; err != nil {
+		return fmt.Errorf("failed to update confirmation attempts: %w", err)
+	}
+	if len(attempts) == 0 {
+		return ErrTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(confirmation.Code), []byte(req.Code)) != 1 {
+		return ErrInvalidCode
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var foundUser User
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ? AND is_deleted = false", req.Email).First(&foundUser).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if err := tx.Model(&foundUser).Update("password_hash", string(hashedPassword)).Error; err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		if err := tx.Delete(&confirmation).Error; err != nil {
+			return fmt.Errorf("failed to delete confirmation: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeAll(foundUser.ID, nil)
 }
 
 func (s *Service) ensureEmailAvailable(email string) error {
@@ -209,7 +384,7 @@ func (s *Service) ensureEmailAvailable(email string) error {
 	return nil
 }
 
-func (s *Service) upsertConfirmation(email string) (*EmailConfirmation, error) {
+func (s *Service) upsertConfirmation(email, purpose string) (*EmailConfirmation, error) {
 	code, err := generateVerificationCode(verificationCodeLength)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate verification code: %w", err)
@@ -218,13 +393,14 @@ func (s *Service) upsertConfirmation(email string) (*EmailConfirmation, error) {
 	expiresAt := time.Now().Add(verificationTTL)
 
 	var confirmation EmailConfirmation
-	if err := s.db.Where("email = ?", email).First(&confirmation).Error; err != nil {
+	if err := s.db.Where("email = ? AND purpose = ?", email, purpose).First(&confirmation).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("database error: %w", err)
 		}
 
 		confirmation = EmailConfirmation{
 			Email:     email,
+			Purpose:   purpose,
 			Code:      code,
 			ExpiresAt: expiresAt,
 			Attempts:  0,
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const refreshTokenPrefix = "dhr_"
+
+var (
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrInvalidRefreshToken  = errors.New("invalid refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token reused")
+	errSessionOwnerNotFound = errors.New("session owner not found")
+)
+
+// Session is a signed-in device. Its refresh token rotates on every use,
+// only the hashes of the current and the previous token are stored.
+type Session struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"not null"`
+	RefreshTokenHash  string     `json:"-" gorm:"not null;uniqueIndex"`
+	PreviousTokenHash *string    `json:"-"`
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt         *time.Time `json:"-"`
+}
+
+func (Session) TableName() string {
+	return "auth_sessions"
+}
+
+type SessionResponse struct {
+	Session
+	Current bool `json:"current"`
+}
+
+// ClientInfo describes the device a session is started from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+	SessionID    uuid.UUID
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// SessionService starts, refreshes and revokes sessions
+type SessionService struct {
+	db         *gorm.DB
+	jwtManager *JWTManager
+	denylist   *Denylist
+	refreshTTL time.Duration
+}
+
+func NewSessionService(db *gorm.DB, jwtManager *JWTManager, denylist *Denylist, refreshTTL time.Duration) *SessionService {
+	return &SessionService{
+		db:         db,
+		jwtManager: jwtManager,
+		denylist:   denylist,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func generateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return refreshTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
+// Start opens a new session for a user who just proved their identity
+func (s *SessionService) Start(userID uuid.UUID, email string, client ClientInfo) (*TokenPair, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now()
+	session := Session{
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        truncate(client.UserAgent, 512),
+		IPAddress:        truncate(client.IP, 64),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.refreshTTL),
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.issue(&session, userID, email, refreshToken)
+}
+
+func (s *SessionService) issue(session *Session, userID uuid.UUID, email, refreshToken string) (*TokenPair, error) {
+	accessToken, claims, err := s.jwtManager.Generate(userID, email, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    claims.ExpiresAt.Time,
+		SessionID:    session.ID,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a refresh
+// token that was already rotated means it leaked, so the whole session is revoked.
+func (s *SessionService) Refresh(refreshToken string, client ClientInfo) (*User, *TokenPair, error) {
+	var owner User
+	var pair *TokenPair
+	var reused *Session
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		hash := hashToken(refreshToken)
+
+		var session Session
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).
+			First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+
+		now := time.Now()
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if session.RefreshTokenHash != hash {
+			if err := tx.Model(&session).Update("revoked_at", now).Error; err != nil {
+				return fmt.Errorf("failed to revoke session: %w", err)
+			}
+			reused = &session
+			return nil
+		}
+
+		if err := tx.First(&owner, "id = ? AND is_deleted = false", session.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errSessionOwnerNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		nextToken, err := generateRefreshToken()
+		if err != nil {
+			return fmt.Errorf("failed to generate refresh token: %w", err)
+		}
+
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"refresh_token_hash":  hashToken(nextToken),
+			"previous_token_hash": hash,
+			"user_agent":          truncate(client.UserAgent, 512),
+			"ip_address":          truncate(client.IP, 64),
+			"last_used_at":        now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", err)
+		}
+
+		pair, err = s.issue(&session, owner.ID, owner.Email, nextToken)
+		return err
+	})
+	if errors.Is(err, errSessionOwnerNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if reused != nil {
+		log.Printf("refresh token reuse detected, revoked session %s of user %s", reused.ID, reused.UserID)
+		s.denySessions(reused.ID)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	return &owner, pair, nil
+}
+
+// List returns the active sessions of a user, most recently used first
+func (s *SessionService) List(userID uuid.UUID) ([]Session, error) {
+	var sessions []Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke signs a session out. Its access tokens stop working immediately.
+func (s *SessionService) Revoke(userID, sessionID uuid.UUID) error {
+	result := s.db.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	s.denySessions(sessionID)
+	return nil
+}
+
+// RevokeAll signs out every session of a user except the given one, if any.
+// Used when credentials change or the account is deleted.
+func (s *SessionService) RevokeAll(userID uuid.UUID, except *uuid.UUID) error {
+	var revoked []Session
+	query := s.db.Model(&revoked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now())
+	if except != nil {
+		query = query.Where("id <> ?", *except)
+	}
+	if err := query.Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(revoked))
+	for _, session := range revoked {
+		ids = append(ids, session.ID)
+	}
+	s.denySessions(ids...)
+	return nil
+}
+
+// denySessions puts revoked sessions on the denylist for as long as their
+// access tokens may still be valid. The database row is already revoked, so
+// a Redis failure only delays the sign-out until the tokens expire.
+func (s *SessionService) denySessions(ids ...uuid.UUID) {
+	ctx := context.Background()
+	for _, id := range ids {
+		if err := s.denylist.DenySession(ctx, id, s.jwtManager.AccessTTL()); err != nil {
+			log.Printf("failed to denylist session %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TokenPrefix marks personal access tokens so Auth can tell them from JWTs
+const TokenPrefix = "dhp_"
+
+const (
+	maxTokenLifetimeDays = 365
+	lastUsedResolution   = time.Minute
+)
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrInvalidScope  = errors.New("invalid scope")
+)
+
+// PersonalAccessToken is a long-lived, scoped API token of a user or a bot.
+// Only the SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"not null"`
+	Name        string     `json:"name" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex"`
+	TokenPrefix string     `json:"token_prefix" gorm:"not null"`
+	Scopes      []string   `json:"scopes" gorm:"type:jsonb;serializer:json;not null"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   uuid.UUID  `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+type CreateTokenRequest struct {
+	Name   string   `json:"name" validate:"required,min=2,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+	// Omit for a token that does not expire
+	ExpiresInDays *int `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// CreatedToken is returned once, when the plain token is still known
+type CreatedToken struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// TokenService issues and verifies personal access tokens
+type TokenService struct {
+	db *gorm.DB
+}
+
+func NewTokenService(db *gorm.DB) *TokenService {
+	return &TokenService{db: db}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create issues a token for userID. createdBy differs from userID for bot tokens.
+func (s *TokenService) Create(userID, createdBy uuid.UUID, req CreateTokenRequest) (*CreatedToken, error) {
+	for _, scope := range req.Scopes {
+		if !IsValidScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := TokenPrefix + hex.EncodeToString(buf)
+
+	token := PersonalAccessToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenHash:   hashToken(plain),
+		TokenPrefix: plain[:len(TokenPrefix)+8],
+		Scopes:      req.Scopes,
+		CreatedBy:   createdBy,
+	}
+	if req.ExpiresInDays != nil {
+		days := *req.ExpiresInDays
+		if days > maxTokenLifetimeDays {
+			days = maxTokenLifetimeDays
+		}
+		expiresAt := time.Now().AddDate(0, 0, days)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(&token).Error; err != nil {
+		return nil, fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return &CreatedToken{PersonalAccessToken: token, Token: plain}, nil
+}
+
+// List returns the tokens of a user that have not been revoked
+func (s *TokenService) List(userID uuid.UUID) ([]PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (s *TokenService) Revoke(userID, tokenID uuid.UUID) error {
+	result := s.db.Model(&PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAll revokes every token of a user, e.g. when a bot is deleted
+func (s *TokenService) RevokeAll(userID uuid.UUID) error {
+	return s.db.Model(&PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Verify resolves a plain token to its record and owner
+func (s *TokenService) Verify(plain string) (*PersonalAccessToken, *User, error) {
+	if !strings.HasPrefix(plain, TokenPrefix) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var token PersonalAccessToken
+	if err := s.db.First(&token, "token_hash = ? AND revoked_at IS NULL", hashToken(plain)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var owner User
+	if err := s.db.First(&owner, "id = ? AND is_deleted = false", token.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("failed to get token owner: %w", err)
+	}
+
+	// Coarse last-used tracking avoids a write on every request
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		token.LastUsedAt = &now
+		_ = s.db.Model(&PersonalAccessToken{}).Where("id = ?", token.ID).UpdateColumn("last_used_at", now).Error
+	}
+
+	return &token, &owner, nil
+}
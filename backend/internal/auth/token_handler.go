@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+type TokenHandler struct {
+	tokens *TokenService
+}
+
+func NewTokenHandler(tokens *TokenService) *TokenHandler {
+	return &TokenHandler{tokens: tokens}
+}
+
+func currentUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+	return uuid.Parse(userIDStr)
+}
+
+// List available token scopes
+// GET /api/auth/scopes
+func (h *TokenHandler) ListScopes(c *fiber.Ctx) error {
+	return c.JSON(ScopeDescriptions)
+}
+
+// Create personal access token
+// POST /api/users/me/tokens
+func (h *TokenHandler) Create(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req CreateTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	token, err := h.tokens.Create(userID, userID, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create token",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(token)
+}
+
+// List personal access tokens
+// GET /api/users/me/tokens
+func (h *TokenHandler) List(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	tokens, err := h.tokens.List(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list tokens",
+		})
+	}
+
+	return c.JSON(tokens)
+}
+
+// Revoke personal access token
+// DELETE /api/users/me/tokens/:id
+func (h *TokenHandler) Revoke(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	tokenID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid token ID",
+		})
+	}
+
+	if err := h.tokens.Revoke(userID, tokenID); err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Token not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke token",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -8,14 +8,16 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	Email        string    `json:"email" gorm:"unique;not null"`
-	Name         string    `json:"name"`
-	Handle       *string   `json:"handle" gorm:"unique;not null"`
-	AvatarURL    *string   `json:"avatar_url" gorm:"column:avatar_url"`
-	PasswordHash *string   `json:"-" gorm:"column:password_hash"`
-	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Email          string    `json:"email" gorm:"unique;not null"`
+	Name           string    `json:"name"`
+	Handle         *string   `json:"handle" gorm:"unique;not null"`
+	AvatarURL      *string   `json:"avatar_url" gorm:"column:avatar_url"`
+	PasswordHash   *string   `json:"-" gorm:"column:password_hash"`
+	GitHubID       *string   `json:"github_id" gorm:"column:github_id"`
+	GitHubUsername *string   `json:"github_username" gorm:"column:github_username"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
 }
 
 type RegisterRequest struct {
@@ -31,8 +33,19 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         User      `json:"user"`
+}
+
+func NewLoginResponse(user User, tokens *TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         user,
+	}
 }
 
 func NormalizeHandle(handle *string) *string {
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/m0khm/devhub/backend/internal/auth"
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, ErrNotProjectMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+	case errors.Is(err, ErrNotProjectAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	case errors.Is(err, ErrBotNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Bot not found"})
+	case errors.Is(err, ErrHandleTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Handle is already taken"})
+	case errors.Is(err, auth.ErrTokenNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Token not found"})
+	case errors.Is(err, auth.ErrInvalidScope):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+}
+
+func parseIDs(c *fiber.Ctx) (userID, projectID uuid.UUID, err error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, uuid.Nil, fiber.ErrUnauthorized
+	}
+	userID, err = uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fiber.ErrUnauthorized
+	}
+	projectID, err = uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid project ID")
+	}
+	return userID, projectID, nil
+}
+
+func parseBotID(c *fiber.Ctx) (uuid.UUID, error) {
+	botID, err := uuid.Parse(c.Params("botId"))
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid bot ID")
+	}
+	return botID, nil
+}
+
+// Create bot
+// POST /api/projects/:projectId/bots
+func (h *Handler) Create(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateBotRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if handle := auth.NormalizeHandle(&req.Handle); handle != nil {
+		req.Handle = *handle
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	bot, err := h.service.Create(projectID, userID, req)
+	if err != nil {
+		return h.respondError(c, err, "Failed to create bot")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(bot)
+}
+
+// List project bots
+// GET /api/projects/:projectId/bots
+func (h *Handler) List(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+
+	bots, err := h.service.List(projectID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to list bots")
+	}
+
+	return c.JSON(bots)
+}
+
+// Delete bot
+// DELETE /api/projects/:projectId/bots/:botId
+func (h *Handler) Delete(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	botID, err := parseBotID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(projectID, botID, userID); err != nil {
+		return h.respondError(c, err, "Failed to delete bot")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Create bot token
+// POST /api/projects/:projectId/bots/:botId/tokens
+func (h *Handler) CreateToken(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	botID, err := parseBotID(c)
+	if err != nil {
+		return err
+	}
+
+	var req auth.CreateTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	token, err := h.service.CreateToken(projectID, botID, userID, req)
+	if err != nil {
+		return h.respondError(c, err, "Failed to create token")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(token)
+}
+
+// List bot tokens
+// GET /api/projects/:projectId/bots/:botId/tokens
+func (h *Handler) ListTokens(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	botID, err := parseBotID(c)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := h.service.ListTokens(projectID, botID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to list tokens")
+	}
+
+	return c.JSON(tokens)
+}
+
+// Revoke bot token
+// DELETE /api/projects/:projectId/bots/:botId/tokens/:tokenId
+func (h *Handler) RevokeToken(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	botID, err := parseBotID(c)
+	if err != nil {
+		return err
+	}
+	tokenID, err := uuid.Parse(c.Params("tokenId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid token ID"})
+	}
+
+	if err := h.service.RevokeToken(projectID, botID, tokenID, userID); err != nil {
+		return h.respondError(c, err, "Failed to revoke token")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,17 @@
+package bot
+
+import (
+	"github.com/m0khm/devhub/backend/internal/user"
+)
+
+type CreateBotRequest struct {
+	Name   string `json:"name" validate:"required,min=2,max=100"`
+	Handle string `json:"handle" validate:"required,alphanum,min=3,max=20"`
+	Role   string `json:"role" validate:"omitempty,oneof=member admin"`
+}
+
+// BotResponse is a bot user with its project role
+type BotResponse struct {
+	user.User
+	Role string `json:"role"`
+}
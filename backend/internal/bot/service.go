@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/auth"
+	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/user"
+)
+
+var (
+	ErrNotProjectMember = errors.New("not a project member")
+	ErrNotProjectAdmin  = errors.New("not a project admin")
+	ErrBotNotFound      = errors.New("bot not found")
+	ErrHandleTaken      = errors.New("handle is already taken")
+)
+
+// Service manages bot users. A bot is a user without a password that belongs
+// to one project and authenticates with personal access tokens.
+type Service struct {
+	db               *gorm.DB
+	projectRepo      *project.Repository
+	userRepo         *user.Repository
+	tokens           *auth.TokenService
+	accessChangeHook project.AccessChangeFunc
+}
+
+func NewService(db *gorm.DB, projectRepo *project.Repository, userRepo *user.Repository, tokens *auth.TokenService) *Service {
+	return &Service{
+		db:          db,
+		projectRepo: projectRepo,
+		userRepo:    userRepo,
+		tokens:      tokens,
+	}
+}
+
+// SetAccessChangeHook sets the callback used to re-check live connections (called from main.go)
+func (s *Service) SetAccessChangeHook(hook project.AccessChangeFunc) {
+	s.accessChangeHook = hook
+}
+
+func (s *Service) requireAdmin(projectID, userID uuid.UUID) error {
+	role, err := s.projectRepo.GetUserRole(projectID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotProjectMember
+		}
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+	if role != "owner" && role != "admin" {
+		return ErrNotProjectAdmin
+	}
+	return nil
+}
+
+func (s *Service) getBot(projectID, botID uuid.UUID) (*user.User, error) {
+	var bot user.User
+	err := s.db.First(&bot, "id = ? AND is_bot = true AND bot_project_id = ? AND is_deleted = false", botID, projectID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBotNotFound
+		}
+		return nil, fmt.Errorf("failed to get bot: %w", err)
+	}
+	return &bot, nil
+}
+
+// Create bot and add it to the project
+func (s *Service) Create(projectID, userID uuid.UUID, req CreateBotRequest) (*BotResponse, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByHandle(req.Handle); err == nil {
+		return nil, ErrHandleTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check handle: %w", err)
+	}
+
+	role := req.Role
+	if role == "" {
+		role = "member"
+	}
+
+	botID := uuid.New()
+	handle := req.Handle
+	bot := user.User{
+		ID: botID,
+		// Bots never sign in, the address only satisfies the unique email column
+		Email:        fmt.Sprintf("bot-%s@bots.devhub.invalid", botID),
+		Name:         req.Name,
+		Handle:       &handle,
+		IsBot:        true,
+		BotProjectID: &projectID,
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bot).Error; err != nil {
+			return err
+		}
+		return tx.Create(&project.ProjectMember{
+			ProjectID: projectID,
+			UserID:    botID,
+			Role:      role,
+		}).Error
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create bot: %w", err)
+	}
+
+	return &BotResponse{User: bot, Role: role}, nil
+}
+
+func (s *Service) List(projectID, userID uuid.UUID) ([]BotResponse, error) {
+	isMember, err := s.projectRepo.IsUserMember(projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotProjectMember
+	}
+
+	var bots []BotResponse
+	err = s.db.Table("users").
+		Select("users.*, project_members.role").
+		Joins("LEFT JOIN project_members ON project_members.user_id = users.id AND project_members.project_id = users.bot_project_id").
+		Where("users.is_bot = true AND users.bot_project_id = ? AND users.is_deleted = false", projectID).
+		Order("users.created_at").
+		Scan(&bots).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bots: %w", err)
+	}
+	return bots, nil
+}
+
+// Delete removes the bot from the project and revokes its tokens
+func (s *Service) Delete(projectID, botID, userID uuid.UUID) error {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return err
+	}
+	bot, err := s.getBot(projectID, botID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	bot.IsDeleted = true
+	bot.DeletedAt = &now
+	if err := s.userRepo.Update(bot); err != nil {
+		return fmt.Errorf("failed to delete bot: %w", err)
+	}
+	if err := s.projectRepo.RemoveMember(projectID, botID); err != nil {
+		return fmt.Errorf("failed to remove bot from project: %w", err)
+	}
+	if err := s.tokens.RevokeAll(botID); err != nil {
+		return fmt.Errorf("failed to revoke bot tokens: %w", err)
+	}
+
+	if s.accessChangeHook != nil {
+		s.accessChangeHook(projectID, botID)
+	}
+	return nil
+}
+
+func (s *Service) CreateToken(projectID, botID, userID uuid.UUID, req auth.CreateTokenRequest) (*auth.CreatedToken, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getBot(projectID, botID); err != nil {
+		return nil, err
+	}
+	return s.tokens.Create(botID, userID, req)
+}
+
+func (s *Service) ListTokens(projectID, botID, userID uuid.UUID) ([]auth.PersonalAccessToken, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getBot(projectID, botID); err != nil {
+		return nil, err
+	}
+	return s.tokens.List(botID)
+}
+
+func (s *Service) RevokeToken(projectID, botID, tokenID, userID uuid.UUID) error {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return err
+	}
+	if _, err := s.getBot(projectID, botID); err != nil {
+		return err
+	}
+	return s.tokens.Revoke(botID, tokenID)
+}
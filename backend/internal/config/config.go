@@ -3,28 +3,38 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	S3       S3Config
-	SMTP     SMTPConfig
-	GitHub   GitHubConfig
-	Admin    AdminConfig
-	Deploy   DeployConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	WS        WSConfig
+	JWT       JWTConfig
+	S3        S3Config
+	SMTP      SMTPConfig
+	GitHub    GitHubConfig
+	Admin     AdminConfig
+	Deploy    DeployConfig
+	Webhook   WebhookConfig
+	Search    SearchConfig
+	RateLimit RateLimitConfig
 }
 
 type ServerConfig struct {
 	Port         int
 	Environment  string
 	AllowOrigins []string
+	// Header carrying the client IP when running behind a reverse proxy, e.g. X-Real-IP
+	ProxyHeader string
+	// Proxies allowed to set ProxyHeader, any peer may when empty
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -43,9 +53,16 @@ type RedisConfig struct {
 	DB       int
 }
 
+type WSConfig struct {
+	Broker  string // redis | memory
+	Channel string
+}
+
 type JWTConfig struct {
-	Secret      string
-	ExpireHours int
+	Secret string
+	// Access tokens are short-lived, sessions are kept alive by refresh tokens
+	AccessTTLMinutes int
+	RefreshTTLDays   int
 }
 
 type S3Config struct {
@@ -69,6 +86,12 @@ type GitHubConfig struct {
 	ClientID     string
 	ClientSecret string
 	CallbackURL  string
+	// Where the browser lands after the OAuth flow, tokens are passed in the fragment
+	FrontendCallbackURL string
+	// Provider endpoints, overridable to test against a local stand-in
+	AuthorizeURL string
+	TokenURL     string
+	APIURL       string
 }
 
 type AdminConfig struct {
@@ -78,7 +101,65 @@ type AdminConfig struct {
 }
 
 type DeployConfig struct {
+	// SecretsKey reads values encrypted before key IDs were added, until they are rotated
 	SecretsKey string
+	// DropLegacySecrets lets the API start in production while values encrypted
+	// with the default DEPLOY_SECRETS_KEY remain, they become unreadable
+	DropLegacySecrets bool
+	// SecretsKeys maps key IDs to secrets, new values are encrypted with SecretsActiveKey.
+	// Retired keys stay listed until the rotate-secrets command re-encrypted their values.
+	SecretsKeys      map[string]string
+	SecretsActiveKey string
+	// Terminal recordings always hold the output, input is opt-in as it may contain typed secrets
+	RecordTerminalInput bool
+	// Script runs use the default timeout unless a shorter or longer one up to the maximum is asked for
+	ScriptDefaultTimeout time.Duration
+	ScriptMaxTimeout     time.Duration
+	// ScriptMaxConcurrency is the number of servers a run works on at the same time
+	ScriptMaxConcurrency int
+}
+
+type WebhookConfig struct {
+	// Lets webhooks target private and loopback addresses, for local development
+	AllowPrivateTargets bool
+	MaxAttempts         int
+	TimeoutSeconds      int
+}
+
+type SearchConfig struct {
+	// Postgres text search configs that queries may use
+	Languages       []string
+	DefaultLanguage string
+}
+
+// RateLimitPolicy allows Limit requests per Window
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Every API request, by client IP
+	PerIP RateLimitPolicy
+	// Every authenticated request, by user
+	PerUser RateLimitPolicy
+	// Credential checks: login, second factor, password reset and registration confirm, by client IP
+	Auth RateLimitPolicy
+	// Requests that send an email with a code, by client IP
+	EmailCodes    RateLimitPolicy
+	MessageCreate RateLimitPolicy
+	Upload        RateLimitPolicy
+	LoginLockout  LoginLockoutConfig
+}
+
+// LoginLockoutConfig locks an account after MaxFailures failed logins within
+// FailureWindow. Each further lockout doubles, from BaseDuration up to MaxDuration.
+type LoginLockoutConfig struct {
+	MaxFailures   int
+	FailureWindow time.Duration
+	BaseDuration  time.Duration
+	MaxDuration   time.Duration
 }
 
 func Load() (*Config, error) {
@@ -87,11 +168,19 @@ func Load() (*Config, error) {
 	originsRaw := getEnv("CORS_ORIGIN", "http://localhost:3000")
 	allowOrigins := normalizeOrigins(strings.Split(originsRaw, ","))
 
+	secretsKeys, activeKey, err := loadSecretsKeyring()
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port:         getEnvAsInt("PORT", 8080),
 			Environment:  getEnv("ENVIRONMENT", "development"),
 			AllowOrigins: allowOrigins,
+
+			ProxyHeader:    getEnv("PROXY_HEADER", ""),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -107,9 +196,14 @@ func Load() (*Config, error) {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
+		WS: WSConfig{
+			Broker:  getEnv("WS_BROKER", "redis"),
+			Channel: getEnv("WS_BROKER_CHANNEL", "devhub:ws:broadcast"),
+		},
 		JWT: JWTConfig{
-			Secret:      getEnv("JWT_SECRET", "change-me-in-production"),
-			ExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 168),
+			Secret:           getEnv("JWT_SECRET", "change-me-in-production"),
+			AccessTTLMinutes: getEnvAsInt("JWT_ACCESS_TTL_MINUTES", 15),
+			RefreshTTLDays:   getEnvAsInt("JWT_REFRESH_TTL_DAYS", 30),
 		},
 		S3: S3Config{
 			Endpoint:      getEnv("S3_ENDPOINT", "localhost:9000"),
@@ -130,6 +224,11 @@ func Load() (*Config, error) {
 			ClientID:     getEnv("GITHUB_CLIENT_ID", ""),
 			ClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
 			CallbackURL:  getEnv("GITHUB_CALLBACK_URL", "http://localhost:8080/api/auth/github/callback"),
+
+			FrontendCallbackURL: getEnv("GITHUB_FRONTEND_CALLBACK_URL", "http://localhost:3000/auth/github/callback"),
+			AuthorizeURL:        getEnv("GITHUB_AUTHORIZE_URL", "https://github.com/login/oauth/authorize"),
+			TokenURL:            getEnv("GITHUB_TOKEN_URL", "https://github.com/login/oauth/access_token"),
+			APIURL:              getEnv("GITHUB_API_URL", "https://api.github.com"),
 		},
 		Admin: AdminConfig{
 			User:               getEnv("ADMIN_USER", "admin"),
@@ -137,7 +236,38 @@ func Load() (*Config, error) {
 			SessionTTLInMinute: getEnvAsInt("ADMIN_SESSION_TTL_MINUTES", 60),
 		},
 		Deploy: DeployConfig{
-			SecretsKey: getEnv("DEPLOY_SECRETS_KEY", "change-me-in-production"),
+			SecretsKey:           getEnv("DEPLOY_SECRETS_KEY", defaultSecretsKey),
+			DropLegacySecrets:    getEnvAsBool("DEPLOY_DROP_LEGACY_SECRETS", false),
+			SecretsKeys:          secretsKeys,
+			SecretsActiveKey:     activeKey,
+			RecordTerminalInput:  getEnvAsBool("DEPLOY_RECORD_TERMINAL_INPUT", false),
+			ScriptDefaultTimeout: getEnvAsDuration("DEPLOY_SCRIPT_DEFAULT_TIMEOUT", 10*time.Minute),
+			ScriptMaxTimeout:     getEnvAsDuration("DEPLOY_SCRIPT_MAX_TIMEOUT", time.Hour),
+			ScriptMaxConcurrency: getEnvAsInt("DEPLOY_SCRIPT_MAX_CONCURRENCY", 5),
+		},
+		Webhook: WebhookConfig{
+			AllowPrivateTargets: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+			MaxAttempts:         getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			TimeoutSeconds:      getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+		},
+		Search: SearchConfig{
+			Languages:       getEnvAsList("SEARCH_LANGUAGES", "english,russian"),
+			DefaultLanguage: getEnv("SEARCH_DEFAULT_LANGUAGE", "english"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:       getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			PerIP:         getEnvAsRate("RATE_LIMIT_PER_IP", "600/1m"),
+			PerUser:       getEnvAsRate("RATE_LIMIT_PER_USER", "600/1m"),
+			Auth:          getEnvAsRate("RATE_LIMIT_AUTH", "20/5m"),
+			EmailCodes:    getEnvAsRate("RATE_LIMIT_EMAIL_CODES", "5/15m"),
+			MessageCreate: getEnvAsRate("RATE_LIMIT_MESSAGE_CREATE", "60/1m"),
+			Upload:        getEnvAsRate("RATE_LIMIT_UPLOAD", "30/10m"),
+			LoginLockout: LoginLockoutConfig{
+				MaxFailures:   getEnvAsInt("LOGIN_LOCKOUT_MAX_FAILURES", 5),
+				FailureWindow: getEnvAsDuration("LOGIN_LOCKOUT_FAILURE_WINDOW", 15*time.Minute),
+				BaseDuration:  getEnvAsDuration("LOGIN_LOCKOUT_BASE_DURATION", time.Minute),
+				MaxDuration:   getEnvAsDuration("LOGIN_LOCKOUT_MAX_DURATION", time.Hour),
+			},
 		},
 	}
 
@@ -145,13 +275,63 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET must be set in production")
 	}
 
-	if cfg.Deploy.SecretsKey == "change-me-in-production" && cfg.Server.Environment == "production" {
-		return nil, fmt.Errorf("DEPLOY_SECRETS_KEY must be set in production")
+	if cfg.Server.Environment == "production" {
+		for id, secret := range cfg.Deploy.SecretsKeys {
+			if secret == defaultSecretsKey {
+				return nil, fmt.Errorf("deploy secrets key %q is the default key, set DEPLOY_SECRETS_KEYS in production", id)
+			}
+		}
 	}
 
 	return cfg, nil
 }
 
+// LegacyKeyIsDefault reports whether values without a key ID are read with the
+// development default. The API must not run like that in production, but
+// rotate-secrets still needs it to re-encrypt them.
+func (c DeployConfig) LegacyKeyIsDefault() bool {
+	return c.SecretsKey == defaultSecretsKey
+}
+
+// defaultSecretsKey only exists so development works without configuration
+const defaultSecretsKey = "change-me-in-production"
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// loadSecretsKeyring reads DEPLOY_SECRETS_KEYS as "id:secret,id:secret". Without
+// it DEPLOY_SECRETS_KEY is the only key, under the ID "default".
+func loadSecretsKeyring() (map[string]string, string, error) {
+	keys := map[string]string{}
+	for _, entry := range getEnvAsList("DEPLOY_SECRETS_KEYS", "") {
+		id, secret, found := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !found || secret == "" {
+			return nil, "", fmt.Errorf("DEPLOY_SECRETS_KEYS entries must look like id:secret")
+		}
+		if !keyIDPattern.MatchString(id) {
+			return nil, "", fmt.Errorf("DEPLOY_SECRETS_KEYS key id %q may only contain letters, digits, - and _", id)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, "", fmt.Errorf("DEPLOY_SECRETS_KEYS lists key %q twice", id)
+		}
+		keys[id] = secret
+	}
+	if len(keys) == 0 {
+		keys["default"] = getEnv("DEPLOY_SECRETS_KEY", defaultSecretsKey)
+	}
+
+	active := getEnv("DEPLOY_SECRETS_ACTIVE_KEY", "")
+	if active == "" && len(keys) == 1 {
+		for id := range keys {
+			active = id
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, "", fmt.Errorf("DEPLOY_SECRETS_ACTIVE_KEY must name one of the DEPLOY_SECRETS_KEYS")
+	}
+	return keys, active, nil
+}
+
 func normalizeOrigins(in []string) []string {
 	out := make([]string, 0, len(in))
 	seen := map[string]bool{}
@@ -199,3 +379,46 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvAsList(key, defaultValue string) []string {
+	values := []string{}
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsRate parses "<limit>/<window>", e.g. "10/1m". A limit of 0 disables the policy.
+func getEnvAsRate(key, defaultValue string) RateLimitPolicy {
+	if policy, ok := parseRate(getEnv(key, defaultValue)); ok {
+		return policy
+	}
+	policy, _ := parseRate(defaultValue)
+	return policy
+}
+
+func parseRate(value string) (RateLimitPolicy, bool) {
+	limitStr, windowStr, found := strings.Cut(value, "/")
+	if !found {
+		return RateLimitPolicy{}, false
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit < 0 {
+		return RateLimitPolicy{}, false
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(windowStr))
+	if err != nil || window <= 0 {
+		return RateLimitPolicy{}, false
+	}
+	return RateLimitPolicy{Limit: limit, Window: window}, true
+}
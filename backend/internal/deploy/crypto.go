@@ -6,20 +6,32 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
+// dataKeySize is the size of the random key each value is encrypted with
+const dataKeySize = 32
+
+var ErrUnknownKeyID = errors.New("value is encrypted with a key that is not in the keyring")
+
+// Encryptor encrypts secrets with envelope encryption. Every value gets its own
+// data key, which is wrapped by the active key of the keyring. Values carry the
+// ID of that key as a prefix, "<key id>:<base64 payload>", so keys can be
+// rotated while older values stay readable.
+//
+// Values written before key IDs existed have no prefix and are read with the
+// legacy key until they are re-encrypted.
 type Encryptor struct {
-	gcm cipher.AEAD
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+	legacy      cipher.AEAD // optional
 }
 
-func NewEncryptor(secret string) (*Encryptor, error) {
-	if secret == "" {
-		return nil, fmt.Errorf("empty encryption secret")
-	}
-	key := sha256.Sum256([]byte(secret))
-	block, err := aes.NewCipher(key[:])
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("init cipher: %w", err)
 	}
@@ -27,31 +39,169 @@ func NewEncryptor(secret string) (*Encryptor, error) {
 	if err != nil {
 		return nil, fmt.Errorf("init gcm: %w", err)
 	}
-	return &Encryptor{gcm: gcm}, nil
+	return gcm, nil
+}
+
+// deriveKey turns a configured secret into an AES-256 key
+func deriveKey(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("empty encryption secret")
+	}
+	key := sha256.Sum256([]byte(secret))
+	return newGCM(key[:])
+}
+
+// NewEncryptor builds the keyring. keys maps key IDs to secrets, new values
+// are encrypted with activeKeyID. legacySecret may be empty once no value
+// without a key ID is left.
+func NewEncryptor(keys map[string]string, activeKeyID, legacySecret string) (*Encryptor, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeKeyID)
+	}
+
+	e := &Encryptor{activeKeyID: activeKeyID, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, secret := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		gcm, err := deriveKey(secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		e.keys[id] = gcm
+	}
+	if legacySecret != "" {
+		gcm, err := deriveKey(legacySecret)
+		if err != nil {
+			return nil, fmt.Errorf("legacy key: %w", err)
+		}
+		e.legacy = gcm
+	}
+	return e, nil
+}
+
+// ActiveKeyID is the key new values are encrypted with
+func (e *Encryptor) ActiveKeyID() string {
+	return e.activeKeyID
+}
+
+func randomBytes(size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
 }
 
+// Encrypt seals plain with a new data key wrapped by the active key.
+// The payload is wrap nonce | wrapped data key | data nonce | ciphertext.
 func (e *Encryptor) Encrypt(plain []byte) (string, error) {
-	nonce := make([]byte, e.gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+	kek := e.keys[e.activeKeyID]
+
+	dataKey, err := randomBytes(dataKeySize)
+	if err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+	dataGCM, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	wrapNonce, err := randomBytes(kek.NonceSize())
+	if err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	dataNonce, err := randomBytes(dataGCM.NonceSize())
+	if err != nil {
 		return "", fmt.Errorf("generate nonce: %w", err)
 	}
-	ciphertext := e.gcm.Seal(nil, nonce, plain, nil)
-	payload := append(nonce, ciphertext...)
-	return base64.StdEncoding.EncodeToString(payload), nil
+
+	// The key ID is authenticated so a payload cannot be moved under another key
+	payload := append(wrapNonce, kek.Seal(nil, wrapNonce, dataKey, []byte(e.activeKeyID))...)
+	payload = append(payload, dataNonce...)
+	payload = append(payload, dataGCM.Seal(nil, dataNonce, plain, nil)...)
+	return e.activeKeyID + ":" + base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// KeyID returns the ID of the key a value is encrypted with, empty for legacy values
+func KeyID(encoded string) string {
+	id, _, found := strings.Cut(encoded, ":")
+	if !found {
+		return ""
+	}
+	return id
+}
+
+// NeedsRotation reports whether a value is not encrypted with the active key
+func (e *Encryptor) NeedsRotation(encoded string) bool {
+	return KeyID(encoded) != e.activeKeyID
+}
+
+// Reencrypt encrypts a value again with the active key
+func (e *Encryptor) Reencrypt(encoded string) (string, error) {
+	plain, err := e.Decrypt(encoded)
+	if err != nil {
+		return "", err
+	}
+	return e.Encrypt(plain)
 }
 
 func (e *Encryptor) Decrypt(encoded string) ([]byte, error) {
+	id, body, found := strings.Cut(encoded, ":")
+	if !found {
+		return e.decryptLegacy(encoded)
+	}
+	kek, ok := e.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	wrapNonceSize := kek.NonceSize()
+	wrappedSize := dataKeySize + kek.Overhead()
+	if len(payload) < wrapNonceSize+wrappedSize {
+		return nil, fmt.Errorf("payload too short")
+	}
+	wrapNonce := payload[:wrapNonceSize]
+	wrapped := payload[wrapNonceSize : wrapNonceSize+wrappedSize]
+	payload = payload[wrapNonceSize+wrappedSize:]
+
+	dataKey, err := kek.Open(nil, wrapNonce, wrapped, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	dataGCM, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < dataGCM.NonceSize() {
+		return nil, fmt.Errorf("payload too short")
+	}
+	plain, err := dataGCM.Open(nil, payload[:dataGCM.NonceSize()], payload[dataGCM.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt payload: %w", err)
+	}
+	return plain, nil
+}
+
+// decryptLegacy reads values sealed directly with the key derived from DEPLOY_SECRETS_KEY
+func (e *Encryptor) decryptLegacy(encoded string) ([]byte, error) {
+	if e.legacy == nil {
+		return nil, fmt.Errorf("%w: value has no key id and no legacy key is set", ErrUnknownKeyID)
+	}
 	payload, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, fmt.Errorf("decode payload: %w", err)
 	}
-	nonceSize := e.gcm.NonceSize()
+	nonceSize := e.legacy.NonceSize()
 	if len(payload) < nonceSize {
 		return nil, fmt.Errorf("payload too short")
 	}
 	nonce := payload[:nonceSize]
 	ciphertext := payload[nonceSize:]
-	plain, err := e.gcm.Open(nil, nonce, ciphertext, nil)
+	plain, err := e.legacy.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt payload: %w", err)
 	}
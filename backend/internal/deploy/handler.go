@@ -2,6 +2,7 @@ package deploy
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -37,7 +38,12 @@ func (h *Handler) CreateServer(c *fiber.Ctx) error {
 
 	server, err := h.service.CreateServer(projectID, userID, req)
 	if err != nil {
+		var confirmation *HostKeyConfirmationError
 		switch {
+		case errors.As(err, &confirmation):
+			return respondHostKeyConfirmation(c, confirmation)
+		case errors.Is(err, ErrHostUnreachable):
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Could not read the server host key"})
 		case errors.Is(err, ErrNotProjectMember):
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
 		case errors.Is(err, ErrNotProjectAdmin):
@@ -107,3 +113,177 @@ func (h *Handler) GetServer(c *fiber.Ctx) error {
 
 	return c.JSON(server.ToResponse())
 }
+
+// POST /api/projects/:projectId/deploy/servers/:serverId/host-key/approve
+func (h *Handler) ApproveHostKey(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req ApproveHostKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	server, err := h.service.ApproveHostKey(projectID, serverID, userID, req)
+	if err != nil {
+		var confirmation *HostKeyConfirmationError
+		switch {
+		case errors.As(err, &confirmation):
+			return respondHostKeyConfirmation(c, confirmation)
+		case errors.Is(err, ErrHostUnreachable):
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Could not read the server host key"})
+		case errors.Is(err, ErrNotProjectMember):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+		case errors.Is(err, ErrNotProjectAdmin):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		case errors.Is(err, ErrServerNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Server not found"})
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(server.ToResponse())
+}
+
+// GET /api/projects/:projectId/deploy/servers/:serverId/sessions
+func (h *Handler) ListTerminalSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	sessions, err := h.service.ListTerminalSessions(projectID, serverID, userID, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotProjectMember):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+		case errors.Is(err, ErrNotProjectAdmin):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		case errors.Is(err, ErrServerNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Server not found"})
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	responses := make([]TerminalSessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse())
+	}
+
+	return c.JSON(responses)
+}
+
+// List shared terminals that are running now
+// GET /api/projects/:projectId/deploy/sessions/live
+func (h *Handler) ListLiveTerminals(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	terminals, err := h.service.ListLiveTerminals(projectID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotProjectMember):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+		case errors.Is(err, ErrNotProjectAdmin):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(terminals)
+}
+
+// Stream a session recording in asciicast v2 format, playable with asciinema
+// GET /api/projects/:projectId/deploy/servers/:serverId/sessions/:sessionId/recording
+func (h *Handler) GetRecording(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	session, recording, err := h.service.OpenRecording(projectID, serverID, sessionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotProjectMember):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+		case errors.Is(err, ErrNotProjectAdmin):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		case errors.Is(err, ErrTerminalSessionNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+		case errors.Is(err, ErrRecordingNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session has no recording"})
+		case errors.Is(err, ErrStorageUnavailable):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage is unavailable, please try again later"})
+		default:
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, recordingContentType)
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="`+session.ID.String()+`.cast"`)
+	return c.SendStream(recording, int(session.RecordingSize))
+}
+
+// respondHostKeyConfirmation shows the key the server presents so the admin can
+// compare it out of band and retry with its fingerprint
+func respondHostKeyConfirmation(c *fiber.Ctx, err *HostKeyConfirmationError) error {
+	status := fiber.StatusPreconditionRequired
+	message := "Confirm the server host key fingerprint"
+	if err.Mismatch {
+		status = fiber.StatusConflict
+		message = "Server host key does not match the confirmed fingerprint"
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":    message,
+		"host_key": err.HostKey,
+	})
+}
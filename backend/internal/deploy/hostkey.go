@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const hostKeyScanTimeout = 10 * time.Second
+
+// errHostKeyCaptured aborts a scan once the server has presented its key
+var errHostKeyCaptured = errors.New("host key captured")
+
+// HostKeyInfo describes a host key for an admin to compare out of band,
+// e.g. with ssh-keygen -lf /etc/ssh/ssh_host_ed25519_key.pub
+type HostKeyInfo struct {
+	Algorithm   string `json:"algorithm"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func hostKeyInfo(key ssh.PublicKey) HostKeyInfo {
+	return HostKeyInfo{
+		Algorithm:   key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+	}
+}
+
+// scanHostKey connects to an SSH server only far enough to read its host key
+func scanHostKey(host string, port int) (ssh.PublicKey, error) {
+	var captured ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "devhub",
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			captured = key
+			return errHostKeyCaptured
+		},
+		Timeout: hostKeyScanTimeout,
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), config)
+	if err == nil {
+		_ = client.Close()
+	}
+	if captured == nil {
+		if err == nil {
+			err = errors.New("server presented no host key")
+		}
+		return nil, fmt.Errorf("%w: %v", ErrHostUnreachable, err)
+	}
+	return captured, nil
+}
+
+func marshalHostKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+func parseHostKey(encoded string) (ssh.PublicKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(encoded))
+	return key, err
+}
+
+// sameFingerprint compares fingerprints as admins may paste them, with or without the SHA256: prefix
+func sameFingerprint(confirmed, actual string) bool {
+	confirmed = strings.TrimPrefix(strings.TrimSpace(confirmed), "SHA256:")
+	return confirmed != "" && confirmed == strings.TrimPrefix(actual, "SHA256:")
+}
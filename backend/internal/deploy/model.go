@@ -20,6 +20,13 @@ type DeployServer struct {
 	CreatedAt           time.Time  `json:"created_at"`
 	UpdatedAt           time.Time  `json:"updated_at"`
 	LastConnectedAt     *time.Time `json:"last_connected_at"`
+
+	// Pinned host key in authorized_keys format, connections presenting another key are refused
+	HostKey                   *string    `json:"-" gorm:"column:host_key"`
+	HostKeyFingerprint        *string    `json:"host_key_fingerprint"`
+	HostKeyApprovedBy         *uuid.UUID `json:"host_key_approved_by"`
+	HostKeyApprovedAt         *time.Time `json:"host_key_approved_at"`
+	PendingHostKeyFingerprint *string    `json:"pending_host_key_fingerprint"`
 }
 
 type DeployAuditEvent struct {
@@ -28,10 +35,295 @@ type DeployAuditEvent struct {
 	ServerID  *uuid.UUID     `json:"server_id" gorm:"index"`
 	UserID    uuid.UUID      `json:"user_id" gorm:"not null;index"`
 	Action    string         `json:"action" gorm:"not null"`
-	Metadata  map[string]any `json:"metadata" gorm:"type:jsonb"`
+	Metadata  map[string]any `json:"metadata" gorm:"type:jsonb;serializer:json"`
 	CreatedAt time.Time      `json:"created_at"`
 }
 
+// TerminalSession is one deploy terminal connection and its asciicast recording
+type TerminalSession struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID    uuid.UUID  `json:"project_id" gorm:"not null"`
+	ServerID     uuid.UUID  `json:"server_id" gorm:"not null"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"not null"`
+	AuditEventID *uuid.UUID `json:"audit_event_id"`
+	Cols         int        `json:"cols" gorm:"not null"`
+	Rows         int        `json:"rows" gorm:"not null"`
+	StartedAt    time.Time  `json:"started_at" gorm:"not null"`
+	EndedAt      *time.Time `json:"ended_at"`
+	DurationMs   *int64     `json:"duration_ms"`
+	ExitCode     *int       `json:"exit_code"`
+	ExitSignal   *string    `json:"exit_signal"`
+	// RecordingKey is the S3 key of the recording, nil when storage was unavailable
+	RecordingKey  *string   `json:"-"`
+	RecordingSize int64     `json:"recording_size"`
+	InputRecorded bool      `json:"input_recorded"`
+	Truncated     bool      `json:"truncated"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	recorder *terminalRecorder `gorm:"-"`
+}
+
+// TerminalSessionResponse tells clients whether a recording can be played
+type TerminalSessionResponse struct {
+	TerminalSession
+	HasRecording bool `json:"has_recording"`
+}
+
+// DeployScript is a saved command sequence, every change of its content is a new version
+type DeployScript struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID      uuid.UUID  `json:"project_id" gorm:"not null"`
+	Name           string     `json:"name" gorm:"not null"`
+	Description    *string    `json:"description"`
+	CurrentVersion int        `json:"current_version" gorm:"not null"`
+	CreatedBy      uuid.UUID  `json:"created_by" gorm:"not null"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"-"`
+}
+
+type DeployScriptVersion struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ScriptID  uuid.UUID `json:"script_id" gorm:"not null"`
+	Version   int       `json:"version" gorm:"not null"`
+	Content   string    `json:"content" gorm:"not null"`
+	CreatedBy uuid.UUID `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeployScriptWithContent is a script with the content of its current version
+type DeployScriptWithContent struct {
+	DeployScript
+	Content string `json:"content"`
+}
+
+// Script run and target statuses
+const (
+	RunStatusPending   = "pending"
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+	// RunStatusTimedOut is a target killed after the run timeout
+	RunStatusTimedOut = "timed_out"
+	// RunStatusError is a target the script could not be started on, e.g. when SSH failed
+	RunStatusError = "error"
+)
+
+// ScriptRun is one execution of a script version on one or more servers
+type ScriptRun struct {
+	ID             uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID      uuid.UUID         `json:"project_id" gorm:"not null"`
+	ScriptID       uuid.UUID         `json:"script_id" gorm:"not null"`
+	ScriptVersion  int               `json:"script_version" gorm:"not null"`
+	UserID         uuid.UUID         `json:"user_id" gorm:"not null"`
+	Status         string            `json:"status" gorm:"not null"`
+	TimeoutSeconds int               `json:"timeout_seconds" gorm:"not null"`
+	Concurrency    int               `json:"concurrency" gorm:"not null"`
+	StartedAt      time.Time         `json:"started_at" gorm:"not null"`
+	FinishedAt     *time.Time        `json:"finished_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Targets        []ScriptRunTarget `json:"-" gorm:"foreignKey:RunID"`
+	// OwnerInstance is the API instance executing the run, it refreshes HeartbeatAt
+	OwnerInstance *uuid.UUID `json:"-"`
+	HeartbeatAt   *time.Time `json:"-"`
+}
+
+// ScriptRunTarget is the execution of a run on one server
+type ScriptRunTarget struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	RunID      uuid.UUID  `json:"run_id" gorm:"not null"`
+	ServerID   *uuid.UUID `json:"server_id"`
+	ServerName string     `json:"server_name" gorm:"not null"`
+	Status     string     `json:"status" gorm:"not null"`
+	ExitCode   *int       `json:"exit_code"`
+	ExitSignal *string    `json:"exit_signal"`
+	Error      *string    `json:"error"`
+	// LogKey is the S3 key of the output, nil when storage was unavailable
+	LogKey     *string    `json:"-"`
+	LogSize    int64      `json:"log_size"`
+	Truncated  bool       `json:"truncated"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+type ScriptRunTargetResponse struct {
+	ScriptRunTarget
+	HasLog bool `json:"has_log"`
+}
+
+type ScriptRunResponse struct {
+	ScriptRun
+	Targets []ScriptRunTargetResponse `json:"targets"`
+}
+
+type CreateDeployScriptRequest struct {
+	Name        string  `json:"name" validate:"required,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Content     string  `json:"content" validate:"required,max=65536"`
+}
+
+// UpdateDeployScriptRequest changes a script, a changed content is saved as a new version
+type UpdateDeployScriptRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Content     *string `json:"content" validate:"omitempty,min=1,max=65536"`
+}
+
+type RunDeployScriptRequest struct {
+	ServerIDs []uuid.UUID `json:"server_ids" validate:"required,min=1,max=50"`
+	// Version defaults to the current version
+	Version        *int `json:"version" validate:"omitempty,min=1"`
+	TimeoutSeconds *int `json:"timeout_seconds" validate:"omitempty,min=1"`
+	Concurrency    *int `json:"concurrency" validate:"omitempty,min=1"`
+}
+
+// Pipeline stage types
+const (
+	StageTypeScript   = "script"
+	StageTypeApproval = "approval"
+)
+
+// Pipeline run and stage statuses, next to the script run statuses
+const (
+	PipelineStatusWaitingApproval = "waiting_approval"
+	PipelineStatusRejected        = "rejected"
+	PipelineStatusSkipped         = "skipped"
+)
+
+// PipelineStage is a stage of a pipeline definition. Scripts and servers are
+// referenced by name so definitions stay readable.
+type PipelineStage struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is script or approval, stages naming a script default to script
+	Type           string   `json:"type" yaml:"type"`
+	Script         string   `json:"script,omitempty" yaml:"script"`
+	Version        *int     `json:"version,omitempty" yaml:"version"`
+	Servers        []string `json:"servers,omitempty" yaml:"servers"`
+	TimeoutSeconds *int     `json:"timeout_seconds,omitempty" yaml:"timeout_seconds"`
+	Concurrency    *int     `json:"concurrency,omitempty" yaml:"concurrency"`
+	// Message is shown to the approvers of an approval stage
+	Message string `json:"message,omitempty" yaml:"message"`
+}
+
+// PipelineDefinition is the YAML or JSON source of a pipeline
+type PipelineDefinition struct {
+	Stages []PipelineStage `json:"stages" yaml:"stages"`
+}
+
+// DeployPipeline is an ordered list of script and approval stages
+type DeployPipeline struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID   uuid.UUID       `json:"project_id" gorm:"not null"`
+	Name        string          `json:"name" gorm:"not null"`
+	Description *string         `json:"description"`
+	Definition  string          `json:"definition" gorm:"not null"`
+	Stages      []PipelineStage `json:"stages" gorm:"type:jsonb;serializer:json;not null"`
+	CreatedBy   uuid.UUID       `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeletedAt   *time.Time      `json:"-"`
+}
+
+// PipelineRun is one execution of a pipeline. Stages are resolved to script
+// versions and servers when the run starts.
+type PipelineRun struct {
+	ID           uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID    uuid.UUID          `json:"project_id" gorm:"not null"`
+	PipelineID   uuid.UUID          `json:"pipeline_id" gorm:"not null"`
+	PipelineName string             `json:"pipeline_name" gorm:"not null"`
+	UserID       uuid.UUID          `json:"user_id" gorm:"not null"`
+	Status       string             `json:"status" gorm:"not null"`
+	CurrentStage int                `json:"current_stage" gorm:"not null"`
+	StartedAt    time.Time          `json:"started_at" gorm:"not null"`
+	FinishedAt   *time.Time         `json:"finished_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Stages       []PipelineStageRun `json:"stages" gorm:"foreignKey:PipelineRunID"`
+	// OwnerInstance is the API instance advancing the run, it refreshes HeartbeatAt
+	OwnerInstance *uuid.UUID `json:"-"`
+	HeartbeatAt   *time.Time `json:"-"`
+}
+
+type PipelineStageRun struct {
+	ID             uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PipelineRunID  uuid.UUID   `json:"pipeline_run_id" gorm:"not null"`
+	Position       int         `json:"position" gorm:"not null"`
+	Name           string      `json:"name" gorm:"not null"`
+	Type           string      `json:"type" gorm:"not null"`
+	ScriptID       *uuid.UUID  `json:"script_id"`
+	ScriptVersion  *int        `json:"script_version"`
+	ServerIDs      []uuid.UUID `json:"server_ids" gorm:"type:jsonb;serializer:json"`
+	TimeoutSeconds *int        `json:"timeout_seconds"`
+	Concurrency    *int        `json:"concurrency"`
+	Message        *string     `json:"message"`
+	Status         string      `json:"status" gorm:"not null"`
+	ScriptRunID    *uuid.UUID  `json:"script_run_id"`
+	// DecidedBy approved or rejected an approval stage
+	DecidedBy  *uuid.UUID `json:"decided_by"`
+	DecidedAt  *time.Time `json:"decided_at"`
+	Comment    *string    `json:"comment"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+type CreateDeployPipelineRequest struct {
+	Name        string  `json:"name" validate:"required,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	// Definition is YAML or JSON with a list of stages
+	Definition string `json:"definition" validate:"required,max=65536"`
+}
+
+type UpdateDeployPipelineRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Definition  *string `json:"definition" validate:"omitempty,min=1,max=65536"`
+}
+
+type DecidePipelineStageRequest struct {
+	Comment *string `json:"comment" validate:"omitempty,max=1000"`
+}
+
+// RemoteFile is an entry of a directory on a deploy server
+type RemoteFile struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+	IsLink  bool      `json:"is_link"`
+}
+
+// RemoteFileContent is a small text file opened for editing. ModTime is sent
+// back when saving so changes made on the server in between are not overwritten.
+type RemoteFileContent struct {
+	Path    string    `json:"path"`
+	Content string    `json:"content"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Attachment is a file posted in chat that can be copied to a server
+type Attachment struct {
+	MessageID  uuid.UUID
+	Filename   string
+	MimeType   string
+	Size       int64
+	StorageKey string
+}
+
+type WriteRemoteFileRequest struct {
+	Content string `json:"content" validate:"max=1048576"`
+	// ModTime is the modification time the file had when it was read, leave it
+	// empty to create a new file
+	ModTime *time.Time `json:"mod_time"`
+}
+
+type TransferAttachmentRequest struct {
+	MessageID uuid.UUID `json:"message_id" validate:"required"`
+	// Path is the target file, or a directory to copy the file into under its own name
+	Path string `json:"path" validate:"required,max=4096"`
+}
+
 type CreateDeployServerRequest struct {
 	Name       string  `json:"name" validate:"required,min=2,max=100"`
 	Host       string  `json:"host" validate:"required"`
@@ -40,6 +332,12 @@ type CreateDeployServerRequest struct {
 	AuthType   string  `json:"auth_type" validate:"required,oneof=password key"`
 	Password   *string `json:"password"`
 	PrivateKey *string `json:"private_key"`
+	// Fingerprint of the host key the admin confirmed, as returned when it is missing
+	HostKeyFingerprint *string `json:"host_key_fingerprint"`
+}
+
+type ApproveHostKeyRequest struct {
+	Fingerprint string `json:"fingerprint" validate:"required"`
 }
 
 type DeployServerResponse struct {
@@ -52,6 +350,10 @@ type DeployServerResponse struct {
 	AuthType  string    `json:"auth_type"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	HostKeyFingerprint        *string    `json:"host_key_fingerprint"`
+	HostKeyApprovedAt         *time.Time `json:"host_key_approved_at"`
+	PendingHostKeyFingerprint *string    `json:"pending_host_key_fingerprint"`
 }
 
 func (DeployServer) TableName() string {
@@ -62,6 +364,60 @@ func (DeployAuditEvent) TableName() string {
 	return "deploy_audit_events"
 }
 
+func (TerminalSession) TableName() string {
+	return "deploy_terminal_sessions"
+}
+
+func (DeployScript) TableName() string {
+	return "deploy_scripts"
+}
+
+func (DeployScriptVersion) TableName() string {
+	return "deploy_script_versions"
+}
+
+func (ScriptRun) TableName() string {
+	return "deploy_script_runs"
+}
+
+func (ScriptRunTarget) TableName() string {
+	return "deploy_script_run_targets"
+}
+
+func (DeployPipeline) TableName() string {
+	return "deploy_pipelines"
+}
+
+func (PipelineRun) TableName() string {
+	return "deploy_pipeline_runs"
+}
+
+func (PipelineStageRun) TableName() string {
+	return "deploy_pipeline_stage_runs"
+}
+
+func (target ScriptRunTarget) ToResponse() ScriptRunTargetResponse {
+	return ScriptRunTargetResponse{
+		ScriptRunTarget: target,
+		HasLog:          target.LogKey != nil,
+	}
+}
+
+func (run ScriptRun) ToResponse() ScriptRunResponse {
+	targets := make([]ScriptRunTargetResponse, 0, len(run.Targets))
+	for _, target := range run.Targets {
+		targets = append(targets, target.ToResponse())
+	}
+	return ScriptRunResponse{ScriptRun: run, Targets: targets}
+}
+
+func (session TerminalSession) ToResponse() TerminalSessionResponse {
+	return TerminalSessionResponse{
+		TerminalSession: session,
+		HasRecording:    session.RecordingKey != nil,
+	}
+}
+
 func (server DeployServer) ToResponse() DeployServerResponse {
 	return DeployServerResponse{
 		ID:        server.ID,
@@ -73,5 +429,9 @@ func (server DeployServer) ToResponse() DeployServerResponse {
 		AuthType:  server.AuthType,
 		CreatedAt: server.CreatedAt,
 		UpdatedAt: server.UpdatedAt,
+
+		HostKeyFingerprint:        server.HostKeyFingerprint,
+		HostKeyApprovedAt:         server.HostKeyApprovedAt,
+		PendingHostKeyFingerprint: server.PendingHostKeyFingerprint,
 	}
 }
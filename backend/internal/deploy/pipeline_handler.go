@@ -0,0 +1,268 @@
+package deploy
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+func respondPipelineError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidPipeline):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrPipelineNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pipeline not found"})
+	case errors.Is(err, ErrPipelineNameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A pipeline with this name already exists"})
+	case errors.Is(err, ErrPipelineRunActive):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pipeline is already running"})
+	case errors.Is(err, ErrPipelineRunNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pipeline run not found"})
+	case errors.Is(err, ErrNoPendingApproval):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pipeline run is not waiting for an approval"})
+	case errors.Is(err, ErrSelfApproval):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Another owner or admin must approve this run"})
+	default:
+		return respondScriptError(c, err)
+	}
+}
+
+// POST /api/projects/:projectId/deploy/pipelines
+func (h *Handler) CreatePipeline(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req CreateDeployPipelineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	pipeline, err := h.service.CreatePipeline(projectID, userID, req)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(pipeline)
+}
+
+// GET /api/projects/:projectId/deploy/pipelines
+func (h *Handler) ListPipelines(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	pipelines, err := h.service.ListPipelines(projectID, userID)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.JSON(pipelines)
+}
+
+// GET /api/projects/:projectId/deploy/pipelines/:pipelineId
+func (h *Handler) GetPipeline(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	pipelineID, err := uuid.Parse(c.Params("pipelineId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	pipeline, err := h.service.GetPipeline(projectID, pipelineID, userID)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.JSON(pipeline)
+}
+
+// PUT /api/projects/:projectId/deploy/pipelines/:pipelineId
+func (h *Handler) UpdatePipeline(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	pipelineID, err := uuid.Parse(c.Params("pipelineId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req UpdateDeployPipelineRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	pipeline, err := h.service.UpdatePipeline(projectID, pipelineID, userID, req)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.JSON(pipeline)
+}
+
+// DELETE /api/projects/:projectId/deploy/pipelines/:pipelineId
+func (h *Handler) DeletePipeline(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	pipelineID, err := uuid.Parse(c.Params("pipelineId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if err := h.service.DeletePipeline(projectID, pipelineID, userID); err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Start a pipeline, progress is posted to the deploy topic
+// POST /api/projects/:projectId/deploy/pipelines/:pipelineId/runs
+func (h *Handler) StartPipeline(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	pipelineID, err := uuid.Parse(c.Params("pipelineId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	run, err := h.service.StartPipeline(projectID, pipelineID, userID)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(run)
+}
+
+// GET /api/projects/:projectId/deploy/pipelines/:pipelineId/runs?limit=
+func (h *Handler) ListPipelineRuns(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	pipelineID, err := uuid.Parse(c.Params("pipelineId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	runs, err := h.service.ListPipelineRuns(projectID, pipelineID, userID, limit)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.JSON(runs)
+}
+
+// GET /api/projects/:projectId/deploy/pipeline-runs/:runId
+func (h *Handler) GetPipelineRun(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	run, err := h.service.GetPipelineRun(projectID, runID, userID)
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.JSON(run)
+}
+
+// POST /api/projects/:projectId/deploy/pipeline-runs/:runId/approve
+func (h *Handler) ApprovePipelineStage(c *fiber.Ctx) error {
+	return h.decidePipelineStage(c, true)
+}
+
+// POST /api/projects/:projectId/deploy/pipeline-runs/:runId/reject
+func (h *Handler) RejectPipelineStage(c *fiber.Ctx) error {
+	return h.decidePipelineStage(c, false)
+}
+
+func (h *Handler) decidePipelineStage(c *fiber.Ctx, approve bool) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req DecidePipelineStageRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.ErrBadRequest
+		}
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	var run *PipelineRun
+	if approve {
+		run, err = h.service.ApprovePipelineStage(projectID, runID, userID, req)
+	} else {
+		run, err = h.service.RejectPipelineStage(projectID, runID, userID, req)
+	}
+	if err != nil {
+		return respondPipelineError(c, err)
+	}
+	return c.JSON(run)
+}
@@ -0,0 +1,674 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.yaml.in/yaml/v3"
+	"gorm.io/gorm"
+)
+
+const maxPipelineStages = 50
+
+var (
+	ErrPipelineNotFound    = errors.New("pipeline not found")
+	ErrPipelineNameTaken   = errors.New("pipeline name already exists")
+	ErrInvalidPipeline     = errors.New("invalid pipeline definition")
+	ErrPipelineRunActive   = errors.New("pipeline is already running")
+	ErrPipelineRunNotFound = errors.New("pipeline run not found")
+	ErrNoPendingApproval   = errors.New("pipeline run is not waiting for an approval")
+	ErrSelfApproval        = errors.New("the initiator of a run cannot approve it")
+)
+
+// parsePipelineDefinition reads a YAML definition. JSON is valid YAML, so both are accepted.
+func parsePipelineDefinition(source string) (*PipelineDefinition, error) {
+	var definition PipelineDefinition
+	if err := yaml.Unmarshal([]byte(source), &definition); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPipeline, err)
+	}
+	if len(definition.Stages) == 0 {
+		return nil, fmt.Errorf("%w: no stages", ErrInvalidPipeline)
+	}
+	if len(definition.Stages) > maxPipelineStages {
+		return nil, fmt.Errorf("%w: more than %d stages", ErrInvalidPipeline, maxPipelineStages)
+	}
+
+	names := make(map[string]bool, len(definition.Stages))
+	for i := range definition.Stages {
+		stage := &definition.Stages[i]
+		stage.Name = strings.TrimSpace(stage.Name)
+		if stage.Name == "" {
+			return nil, fmt.Errorf("%w: stage %d has no name", ErrInvalidPipeline, i+1)
+		}
+		if len(stage.Name) > 100 {
+			return nil, fmt.Errorf("%w: stage name %q is too long", ErrInvalidPipeline, stage.Name)
+		}
+		if names[strings.ToLower(stage.Name)] {
+			return nil, fmt.Errorf("%w: stage %q is defined twice", ErrInvalidPipeline, stage.Name)
+		}
+		names[strings.ToLower(stage.Name)] = true
+
+		if stage.Type == "" && stage.Script != "" {
+			stage.Type = StageTypeScript
+		}
+		switch stage.Type {
+		case StageTypeScript:
+			if stage.Script == "" {
+				return nil, fmt.Errorf("%w: stage %q has no script", ErrInvalidPipeline, stage.Name)
+			}
+			if len(stage.Servers) == 0 {
+				return nil, fmt.Errorf("%w: stage %q has no servers", ErrInvalidPipeline, stage.Name)
+			}
+			if stage.Version != nil && *stage.Version < 1 {
+				return nil, fmt.Errorf("%w: stage %q version must be at least 1", ErrInvalidPipeline, stage.Name)
+			}
+			if stage.TimeoutSeconds != nil && *stage.TimeoutSeconds < 1 {
+				return nil, fmt.Errorf("%w: stage %q timeout_seconds must be at least 1", ErrInvalidPipeline, stage.Name)
+			}
+			if stage.Concurrency != nil && *stage.Concurrency < 1 {
+				return nil, fmt.Errorf("%w: stage %q concurrency must be at least 1", ErrInvalidPipeline, stage.Name)
+			}
+		case StageTypeApproval:
+			if stage.Script != "" || len(stage.Servers) > 0 {
+				return nil, fmt.Errorf("%w: approval stage %q cannot run a script", ErrInvalidPipeline, stage.Name)
+			}
+		default:
+			return nil, fmt.Errorf("%w: stage %q must be of type script or approval", ErrInvalidPipeline, stage.Name)
+		}
+	}
+	return &definition, nil
+}
+
+// resolveStage finds the script version and servers a stage refers to by name
+func (s *Service) resolveStage(projectID uuid.UUID, stage PipelineStage) (*DeployScript, *DeployScriptVersion, []uuid.UUID, error) {
+	script, err := s.repo.GetScriptByName(projectID, stage.Script)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, fmt.Errorf("%w: stage %q uses unknown script %q", ErrInvalidPipeline, stage.Name, stage.Script)
+		}
+		return nil, nil, nil, err
+	}
+	versionNumber := script.CurrentVersion
+	if stage.Version != nil {
+		versionNumber = *stage.Version
+	}
+	version, err := s.getScriptVersion(script.ID, versionNumber)
+	if err != nil {
+		if errors.Is(err, ErrScriptVersionNotFound) {
+			return nil, nil, nil, fmt.Errorf("%w: script %q has no version %d", ErrInvalidPipeline, script.Name, versionNumber)
+		}
+		return nil, nil, nil, err
+	}
+
+	serverIDs := make([]uuid.UUID, 0, len(stage.Servers))
+	for _, name := range stage.Servers {
+		server, err := s.repo.GetServerByName(projectID, name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, nil, fmt.Errorf("%w: stage %q uses unknown server %q", ErrInvalidPipeline, stage.Name, name)
+			}
+			return nil, nil, nil, err
+		}
+		serverIDs = append(serverIDs, server.ID)
+	}
+	return script, version, serverIDs, nil
+}
+
+// validatePipeline parses a definition and checks that its scripts and servers exist
+func (s *Service) validatePipeline(projectID uuid.UUID, source string) (*PipelineDefinition, error) {
+	definition, err := parsePipelineDefinition(source)
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range definition.Stages {
+		if stage.Type != StageTypeScript {
+			continue
+		}
+		if _, _, _, err := s.resolveStage(projectID, stage); err != nil {
+			return nil, err
+		}
+	}
+	return definition, nil
+}
+
+func (s *Service) getPipeline(projectID, pipelineID uuid.UUID) (*DeployPipeline, error) {
+	pipeline, err := s.repo.GetPipeline(projectID, pipelineID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPipelineNotFound
+		}
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+func (s *Service) getPipelineRun(projectID, runID uuid.UUID) (*PipelineRun, error) {
+	run, err := s.repo.GetPipelineRun(projectID, runID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPipelineRunNotFound
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *Service) auditPipeline(projectID, userID uuid.UUID, action string, metadata map[string]any) {
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		UserID:    userID,
+		Action:    action,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *Service) CreatePipeline(projectID, userID uuid.UUID, req CreateDeployPipelineRequest) (*DeployPipeline, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	taken, err := s.repo.PipelineNameExists(projectID, name, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrPipelineNameTaken
+	}
+	definition, err := s.validatePipeline(projectID, req.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := &DeployPipeline{
+		ProjectID:   projectID,
+		Name:        name,
+		Description: req.Description,
+		Definition:  req.Definition,
+		Stages:      definition.Stages,
+		CreatedBy:   userID,
+	}
+	if err := s.repo.CreatePipeline(pipeline); err != nil {
+		return nil, err
+	}
+
+	s.auditPipeline(projectID, userID, "pipeline_created", map[string]any{
+		"pipeline_id":   pipeline.ID,
+		"pipeline_name": pipeline.Name,
+	})
+	return pipeline, nil
+}
+
+func (s *Service) ListPipelines(projectID, userID uuid.UUID) ([]DeployPipeline, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPipelines(projectID)
+}
+
+func (s *Service) GetPipeline(projectID, pipelineID, userID uuid.UUID) (*DeployPipeline, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.getPipeline(projectID, pipelineID)
+}
+
+// UpdatePipeline changes a pipeline, runs that already started keep their stages
+func (s *Service) UpdatePipeline(projectID, pipelineID, userID uuid.UUID, req UpdateDeployPipelineRequest) (*DeployPipeline, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	pipeline, err := s.getPipeline(projectID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		taken, err := s.repo.PipelineNameExists(projectID, name, pipeline.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrPipelineNameTaken
+		}
+		pipeline.Name = name
+	}
+	if req.Description != nil {
+		pipeline.Description = req.Description
+	}
+	if req.Definition != nil {
+		definition, err := s.validatePipeline(projectID, *req.Definition)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Definition = *req.Definition
+		pipeline.Stages = definition.Stages
+	}
+
+	if err := s.repo.UpdatePipeline(pipeline); err != nil {
+		return nil, err
+	}
+
+	s.auditPipeline(projectID, userID, "pipeline_updated", map[string]any{
+		"pipeline_id":   pipeline.ID,
+		"pipeline_name": pipeline.Name,
+	})
+	return pipeline, nil
+}
+
+// DeletePipeline hides a pipeline, its runs are kept for the history
+func (s *Service) DeletePipeline(projectID, pipelineID, userID uuid.UUID) error {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return err
+	}
+	pipeline, err := s.getPipeline(projectID, pipelineID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	pipeline.DeletedAt = &now
+	if err := s.repo.UpdatePipeline(pipeline); err != nil {
+		return err
+	}
+
+	s.auditPipeline(projectID, userID, "pipeline_deleted", map[string]any{
+		"pipeline_id":   pipeline.ID,
+		"pipeline_name": pipeline.Name,
+	})
+	return nil
+}
+
+// copyPipelineRun returns a run the caller may read while the pipeline advances
+func copyPipelineRun(run *PipelineRun) *PipelineRun {
+	copied := *run
+	copied.Stages = append([]PipelineStageRun(nil), run.Stages...)
+	return &copied
+}
+
+// StartPipeline resolves the stages of a pipeline and runs them in the background.
+// Only one run of a pipeline may be active at a time.
+func (s *Service) StartPipeline(projectID, pipelineID, userID uuid.UUID) (*PipelineRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireTwoFactor(projectID, userID); err != nil {
+		return nil, err
+	}
+	pipeline, err := s.getPipeline(projectID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.repo.HasActivePipelineRun(pipeline.ID)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrPipelineRunActive
+	}
+
+	run := &PipelineRun{
+		ProjectID:    projectID,
+		PipelineID:   pipeline.ID,
+		PipelineName: pipeline.Name,
+		UserID:       userID,
+		Status:       RunStatusRunning,
+		StartedAt:    time.Now(),
+	}
+	s.claimPipelineRun(run)
+	for i, stage := range pipeline.Stages {
+		stageRun := PipelineStageRun{
+			Position: i,
+			Name:     stage.Name,
+			Type:     stage.Type,
+			Status:   RunStatusPending,
+		}
+		switch stage.Type {
+		case StageTypeScript:
+			script, version, serverIDs, err := s.resolveStage(projectID, stage)
+			if err != nil {
+				return nil, err
+			}
+			stageRun.ScriptID = &script.ID
+			stageRun.ScriptVersion = &version.Version
+			stageRun.ServerIDs = serverIDs
+			stageRun.TimeoutSeconds = stage.TimeoutSeconds
+			stageRun.Concurrency = stage.Concurrency
+		case StageTypeApproval:
+			if stage.Message != "" {
+				message := stage.Message
+				stageRun.Message = &message
+			}
+		}
+		run.Stages = append(run.Stages, stageRun)
+	}
+	if err := s.repo.CreatePipelineRun(run); err != nil {
+		return nil, fmt.Errorf("failed to create pipeline run: %w", err)
+	}
+
+	s.auditPipeline(projectID, userID, "pipeline_started", map[string]any{
+		"pipeline_id":     pipeline.ID,
+		"pipeline_name":   pipeline.Name,
+		"pipeline_run_id": run.ID,
+	})
+	s.postPipelineMessage(run, "pipeline_started",
+		fmt.Sprintf("%s started pipeline %s (%d stages)", s.memberName(projectID, userID), run.PipelineName, len(run.Stages)))
+
+	started := copyPipelineRun(run)
+	go s.advancePipeline(run)
+	return started, nil
+}
+
+// Runs are owned by the instance executing them. It refreshes their heartbeat
+// every runHeartbeatInterval, runs without a heartbeat for runStaleAfter are
+// failed by the other instances.
+const (
+	runHeartbeatInterval = 30 * time.Second
+	runStaleAfter        = 2 * time.Minute
+)
+
+// claimPipelineRun makes this instance the owner of a run it advances
+func (s *Service) claimPipelineRun(run *PipelineRun) {
+	now := time.Now()
+	run.OwnerInstance = &s.instanceID
+	run.HeartbeatAt = &now
+}
+
+// RunHeartbeats keeps the runs of this instance alive and fails the runs of
+// instances that stopped while executing them (started from main.go)
+func (s *Service) RunHeartbeats(ctx context.Context) {
+	ticker := time.NewTicker(runHeartbeatInterval)
+	defer ticker.Stop()
+
+	s.failStaleRuns()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.repo.TouchRuns(s.instanceID); err != nil {
+				log.Printf("failed to refresh run heartbeats: %v", err)
+			}
+			s.failStaleRuns()
+		}
+	}
+}
+
+func (s *Service) failStaleRuns() {
+	cutoff := time.Now().Add(-runStaleAfter)
+
+	count, err := s.repo.FailStaleScriptRuns(s.instanceID, cutoff)
+	if err != nil {
+		log.Printf("failed to fail interrupted script runs: %v", err)
+	} else if count > 0 {
+		log.Printf("marked %d interrupted script runs as failed", count)
+	}
+
+	runs, err := s.repo.FailStalePipelineRuns(s.instanceID, cutoff)
+	if err != nil {
+		log.Printf("failed to fail interrupted pipeline runs: %v", err)
+		return
+	}
+	for i := range runs {
+		run := &runs[i]
+		run.Status = RunStatusFailed
+		s.auditPipeline(run.ProjectID, run.UserID, "pipeline_finished", map[string]any{
+			"pipeline_id":     run.PipelineID,
+			"pipeline_name":   run.PipelineName,
+			"pipeline_run_id": run.ID,
+			"status":          run.Status,
+		})
+		s.postPipelineMessage(run, "pipeline_failed",
+			fmt.Sprintf("Pipeline %s failed: %s", run.PipelineName, errRunInterrupted))
+	}
+}
+
+// advancePipeline runs stages from the current one until the pipeline ends
+// or reaches an approval stage. Approving the stage advances it again.
+func (s *Service) advancePipeline(run *PipelineRun) {
+	for run.CurrentStage < len(run.Stages) {
+		stage := &run.Stages[run.CurrentStage]
+		now := time.Now()
+		stage.StartedAt = &now
+
+		if stage.Type == StageTypeApproval {
+			stage.Status = PipelineStatusWaitingApproval
+			run.Status = PipelineStatusWaitingApproval
+			s.savePipeline(run, stage)
+
+			content := fmt.Sprintf("Pipeline %s is waiting for approval at stage %s. An owner or admin other than %s can approve it.",
+				run.PipelineName, stage.Name, s.memberName(run.ProjectID, run.UserID))
+			if stage.Message != nil {
+				content += "\n" + *stage.Message
+			}
+			s.postPipelineMessage(run, "pipeline_approval_required", content)
+			return
+		}
+
+		stage.Status = RunStatusRunning
+		s.savePipeline(run, stage)
+		if reason := s.runPipelineStage(run, stage); reason != "" {
+			s.finishPipeline(run, RunStatusFailed, fmt.Sprintf("Pipeline %s failed at stage %s: %s", run.PipelineName, stage.Name, reason))
+			return
+		}
+		run.CurrentStage++
+	}
+
+	duration := time.Since(run.StartedAt).Round(time.Second)
+	s.finishPipeline(run, RunStatusSucceeded, fmt.Sprintf("Pipeline %s succeeded in %s", run.PipelineName, duration))
+}
+
+// runPipelineStage runs the script of a stage and waits for it. It returns
+// why the stage failed, or an empty string when it succeeded.
+func (s *Service) runPipelineStage(run *PipelineRun, stage *PipelineStageRun) string {
+	reason := ""
+	script, err := s.getScript(run.ProjectID, *stage.ScriptID)
+	var version *DeployScriptVersion
+	if err == nil {
+		version, err = s.getScriptVersion(script.ID, *stage.ScriptVersion)
+	}
+	var servers []*DeployServer
+	if err == nil {
+		servers, err = s.resolveServers(run.ProjectID, stage.ServerIDs)
+	}
+	var live *liveRun
+	if err == nil {
+		var scriptRun *ScriptRun
+		scriptRun, live, err = s.startScriptRun(script, version, run.UserID, servers, stage.TimeoutSeconds, stage.Concurrency)
+		if err == nil {
+			stage.ScriptRunID = &scriptRun.ID
+			s.savePipeline(run, stage)
+		}
+	}
+
+	if err != nil {
+		reason = err.Error()
+	} else {
+		<-live.done
+		failed := make([]string, 0)
+		for _, target := range live.current().Targets {
+			if target.Status != RunStatusSucceeded {
+				failed = append(failed, fmt.Sprintf("%s (%s)", target.ServerName, target.Status))
+			}
+		}
+		if len(failed) > 0 {
+			reason = "script failed on " + strings.Join(failed, ", ")
+		}
+	}
+
+	finished := time.Now()
+	stage.FinishedAt = &finished
+	stage.Status = RunStatusSucceeded
+	if reason != "" {
+		stage.Status = RunStatusFailed
+	}
+	s.savePipeline(run, stage)
+	return reason
+}
+
+// finishPipeline ends a run, the stages after the current one are skipped
+func (s *Service) finishPipeline(run *PipelineRun, status, content string) {
+	for i := run.CurrentStage + 1; i < len(run.Stages); i++ {
+		stage := &run.Stages[i]
+		if stage.Status == RunStatusPending {
+			stage.Status = PipelineStatusSkipped
+			if err := s.repo.UpdatePipelineStageRun(stage); err != nil {
+				log.Printf("failed to update pipeline stage %s: %v", stage.ID, err)
+			}
+		}
+	}
+
+	now := time.Now()
+	run.Status = status
+	run.FinishedAt = &now
+	s.savePipeline(run, nil)
+
+	s.auditPipeline(run.ProjectID, run.UserID, "pipeline_finished", map[string]any{
+		"pipeline_id":     run.PipelineID,
+		"pipeline_name":   run.PipelineName,
+		"pipeline_run_id": run.ID,
+		"status":          status,
+	})
+	s.postPipelineMessage(run, "pipeline_"+status, content)
+}
+
+func (s *Service) savePipeline(run *PipelineRun, stage *PipelineStageRun) {
+	if stage != nil {
+		if err := s.repo.UpdatePipelineStageRun(stage); err != nil {
+			log.Printf("failed to update pipeline stage %s: %v", stage.ID, err)
+		}
+	}
+	if err := s.repo.UpdatePipelineRun(run); err != nil {
+		log.Printf("failed to update pipeline run %s: %v", run.ID, err)
+	}
+}
+
+// postPipelineMessage reports the progress of a run in the project's deploy topic
+func (s *Service) postPipelineMessage(run *PipelineRun, action, content string) {
+	if s.systemMessageHook == nil {
+		return
+	}
+	metadata := map[string]any{
+		"action":          action,
+		"pipeline_id":     run.PipelineID,
+		"pipeline_name":   run.PipelineName,
+		"pipeline_run_id": run.ID,
+		"status":          run.Status,
+	}
+	if run.CurrentStage < len(run.Stages) {
+		metadata["stage"] = run.Stages[run.CurrentStage].Name
+	}
+	s.systemMessageHook(run.ProjectID, "deploy", content, metadata)
+}
+
+// ApprovePipelineStage approves the approval stage a run waits at and continues the run
+func (s *Service) ApprovePipelineStage(projectID, runID, userID uuid.UUID, req DecidePipelineStageRequest) (*PipelineRun, error) {
+	return s.decidePipelineStage(projectID, runID, userID, req, true)
+}
+
+// RejectPipelineStage rejects the approval stage a run waits at and ends the run
+func (s *Service) RejectPipelineStage(projectID, runID, userID uuid.UUID, req DecidePipelineStageRequest) (*PipelineRun, error) {
+	return s.decidePipelineStage(projectID, runID, userID, req, false)
+}
+
+// decidePipelineStage records the decision of an admin other than the initiator
+func (s *Service) decidePipelineStage(projectID, runID, userID uuid.UUID, req DecidePipelineStageRequest, approve bool) (*PipelineRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireTwoFactor(projectID, userID); err != nil {
+		return nil, err
+	}
+	run, err := s.getPipelineRun(projectID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != PipelineStatusWaitingApproval || run.CurrentStage >= len(run.Stages) {
+		return nil, ErrNoPendingApproval
+	}
+	stage := &run.Stages[run.CurrentStage]
+	if stage.Type != StageTypeApproval || stage.Status != PipelineStatusWaitingApproval {
+		return nil, ErrNoPendingApproval
+	}
+	if userID == run.UserID {
+		return nil, ErrSelfApproval
+	}
+
+	now := time.Now()
+	stage.Status = RunStatusSucceeded
+	if !approve {
+		stage.Status = PipelineStatusRejected
+	}
+	stage.DecidedBy = &userID
+	stage.DecidedAt = &now
+	stage.Comment = req.Comment
+	stage.FinishedAt = &now
+	decided, err := s.repo.DecidePipelineStage(stage)
+	if err != nil {
+		return nil, err
+	}
+	if !decided {
+		return nil, ErrNoPendingApproval
+	}
+
+	action := "pipeline_stage_approved"
+	if !approve {
+		action = "pipeline_stage_rejected"
+	}
+	s.auditPipeline(projectID, userID, action, map[string]any{
+		"pipeline_id":     run.PipelineID,
+		"pipeline_name":   run.PipelineName,
+		"pipeline_run_id": run.ID,
+		"stage":           stage.Name,
+		"comment":         req.Comment,
+	})
+
+	approver := s.memberName(projectID, userID)
+	if !approve {
+		content := fmt.Sprintf("%s rejected pipeline %s at stage %s", approver, run.PipelineName, stage.Name)
+		if req.Comment != nil && *req.Comment != "" {
+			content += ": " + *req.Comment
+		}
+		s.finishPipeline(run, PipelineStatusRejected, content)
+		return run, nil
+	}
+
+	s.postPipelineMessage(run, action, fmt.Sprintf("%s approved stage %s of pipeline %s", approver, stage.Name, run.PipelineName))
+	run.CurrentStage++
+	run.Status = RunStatusRunning
+	// The approving instance advances the run from now on. The heartbeat is
+	// written before the run is running again, so it never looks stale.
+	s.claimPipelineRun(run)
+	if err := s.repo.ClaimPipelineRun(run.ID, s.instanceID); err != nil {
+		log.Printf("failed to claim pipeline run %s: %v", run.ID, err)
+	}
+	s.savePipeline(run, nil)
+
+	decidedRun := copyPipelineRun(run)
+	go s.advancePipeline(run)
+	return decidedRun, nil
+}
+
+func (s *Service) ListPipelineRuns(projectID, pipelineID, userID uuid.UUID, limit int) ([]PipelineRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getPipeline(projectID, pipelineID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPipelineRuns(pipelineID, limit)
+}
+
+func (s *Service) GetPipelineRun(projectID, runID, userID uuid.UUID) (*PipelineRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.getPipelineRun(projectID, runID)
+}
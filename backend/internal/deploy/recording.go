@@ -0,0 +1,161 @@
+package deploy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxRecordingSize caps a recording, later events are dropped and the session is marked truncated
+const maxRecordingSize = 64 << 20
+
+const recordingContentType = "application/x-asciicast"
+
+// asciicastHeader is the first line of an asciicast v2 file
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// terminalRecorder writes a terminal session as asciicast v2 to a temporary
+// file, which is uploaded when the session ends. A nil recorder records nothing.
+type terminalRecorder struct {
+	mu          sync.Mutex
+	file        *os.File
+	writer      *bufio.Writer
+	start       time.Time
+	recordInput bool
+	size        int64
+	truncated   bool
+	// Output may split a UTF-8 sequence across reads, the tail waits for the next read
+	pending []byte
+}
+
+func newTerminalRecorder(start time.Time, cols, rows int, title string, recordInput bool) (*terminalRecorder, error) {
+	file, err := os.CreateTemp("", "devhub-terminal-*.cast")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	r := &terminalRecorder{
+		file:        file,
+		writer:      bufio.NewWriter(file),
+		start:       start,
+		recordInput: recordInput,
+	}
+	if err := r.writeLine(asciicastHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	}); err != nil {
+		r.discard()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *terminalRecorder) Output(data []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data = append(r.pending, data...)
+	complete := completeUTF8(data)
+	r.pending = append([]byte(nil), data[complete:]...)
+	if complete > 0 {
+		r.event("o", string(data[:complete]))
+	}
+}
+
+func (r *terminalRecorder) Input(data []byte) {
+	if r == nil || !r.recordInput {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event("i", string(data))
+}
+
+func (r *terminalRecorder) Resize(cols, rows int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// event appends [elapsed, code, data], the caller holds mu
+func (r *terminalRecorder) event(code, data string) {
+	if r.truncated {
+		return
+	}
+	if r.size >= maxRecordingSize {
+		r.truncated = true
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	_ = r.writeLine([]any{elapsed, code, data})
+}
+
+func (r *terminalRecorder) writeLine(value any) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	n, err := r.writer.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// finish flushes the recording and returns it for upload. The caller must call discard afterwards.
+func (r *terminalRecorder) finish() (io.Reader, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+		r.pending = nil
+	}
+	if err := r.writer.Flush(); err != nil {
+		return nil, 0, fmt.Errorf("failed to write recording: %w", err)
+	}
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return r.file, r.size, nil
+}
+
+func (r *terminalRecorder) discard() {
+	_ = r.file.Close()
+	_ = os.Remove(r.file.Name())
+}
+
+// completeUTF8 returns the length of the prefix of data that does not end in
+// an incomplete UTF-8 sequence
+func completeUTF8(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
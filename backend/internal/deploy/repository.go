@@ -1,8 +1,11 @@
 package deploy
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -29,10 +32,311 @@ func (r *Repository) GetServer(projectID, serverID uuid.UUID) (*DeployServer, er
 	return &server, err
 }
 
+func (r *Repository) GetServerByName(projectID uuid.UUID, name string) (*DeployServer, error) {
+	var server DeployServer
+	err := r.db.First(&server, "project_id = ? AND LOWER(name) = LOWER(?)", projectID, name).Error
+	return &server, err
+}
+
 func (r *Repository) UpdateServer(server *DeployServer) error {
 	return r.db.Save(server).Error
 }
 
+// SetPendingHostKey records a host key presented by the server that is not pinned
+func (r *Repository) SetPendingHostKey(serverID uuid.UUID, fingerprint string) error {
+	return r.db.Model(&DeployServer{}).Where("id = ?", serverID).
+		UpdateColumn("pending_host_key_fingerprint", fingerprint).Error
+}
+
 func (r *Repository) CreateAuditEvent(event *DeployAuditEvent) error {
 	return r.db.Create(event).Error
 }
+
+func (r *Repository) CreateTerminalSession(session *TerminalSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *Repository) UpdateTerminalSession(session *TerminalSession) error {
+	return r.db.Save(session).Error
+}
+
+func (r *Repository) ListTerminalSessions(serverID uuid.UUID, limit int) ([]TerminalSession, error) {
+	var sessions []TerminalSession
+	err := r.db.Where("server_id = ?", serverID).Order("started_at DESC").Limit(limit).Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *Repository) GetTerminalSession(projectID, serverID, sessionID uuid.UUID) (*TerminalSession, error) {
+	var session TerminalSession
+	err := r.db.First(&session, "id = ? AND project_id = ? AND server_id = ?", sessionID, projectID, serverID).Error
+	return &session, err
+}
+
+// CreateScript stores a script and its first version
+func (r *Repository) CreateScript(script *DeployScript, version *DeployScriptVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(script).Error; err != nil {
+			return err
+		}
+		version.ScriptID = script.ID
+		return tx.Create(version).Error
+	})
+}
+
+// UpdateScript saves a script, with a new version when its content changed
+func (r *Repository) UpdateScript(script *DeployScript, version *DeployScriptVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if version != nil {
+			if err := tx.Create(version).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(script).Error
+	})
+}
+
+func (r *Repository) ListScripts(projectID uuid.UUID) ([]DeployScript, error) {
+	var scripts []DeployScript
+	err := r.db.Where("project_id = ? AND deleted_at IS NULL", projectID).Order("name ASC").Find(&scripts).Error
+	return scripts, err
+}
+
+func (r *Repository) GetScript(projectID, scriptID uuid.UUID) (*DeployScript, error) {
+	var script DeployScript
+	err := r.db.First(&script, "id = ? AND project_id = ? AND deleted_at IS NULL", scriptID, projectID).Error
+	return &script, err
+}
+
+func (r *Repository) ScriptNameExists(projectID uuid.UUID, name string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&DeployScript{}).
+		Where("project_id = ? AND LOWER(name) = LOWER(?) AND id <> ? AND deleted_at IS NULL", projectID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *Repository) GetScriptVersion(scriptID uuid.UUID, version int) (*DeployScriptVersion, error) {
+	var scriptVersion DeployScriptVersion
+	err := r.db.First(&scriptVersion, "script_id = ? AND version = ?", scriptID, version).Error
+	return &scriptVersion, err
+}
+
+func (r *Repository) ListScriptVersions(scriptID uuid.UUID) ([]DeployScriptVersion, error) {
+	var versions []DeployScriptVersion
+	err := r.db.Where("script_id = ?", scriptID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+func orderTargets(db *gorm.DB) *gorm.DB {
+	return db.Order("server_name ASC")
+}
+
+// CreateScriptRun stores a run with its targets
+func (r *Repository) CreateScriptRun(run *ScriptRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *Repository) UpdateScriptRun(run *ScriptRun) error {
+	// The heartbeat is only written by TouchRuns
+	return r.db.Omit("Targets", "HeartbeatAt").Save(run).Error
+}
+
+func (r *Repository) UpdateScriptRunTarget(target *ScriptRunTarget) error {
+	return r.db.Save(target).Error
+}
+
+// ListScriptRuns returns the latest runs of a project, of one script when scriptID is set
+func (r *Repository) ListScriptRuns(projectID uuid.UUID, scriptID *uuid.UUID, limit int) ([]ScriptRun, error) {
+	query := r.db.Preload("Targets", orderTargets).Where("project_id = ?", projectID)
+	if scriptID != nil {
+		query = query.Where("script_id = ?", *scriptID)
+	}
+	var runs []ScriptRun
+	err := query.Order("started_at DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+func (r *Repository) GetScriptRun(projectID, runID uuid.UUID) (*ScriptRun, error) {
+	var run ScriptRun
+	err := r.db.Preload("Targets", orderTargets).First(&run, "id = ? AND project_id = ?", runID, projectID).Error
+	return &run, err
+}
+
+func (r *Repository) CreatePipeline(pipeline *DeployPipeline) error {
+	return r.db.Create(pipeline).Error
+}
+
+func (r *Repository) UpdatePipeline(pipeline *DeployPipeline) error {
+	return r.db.Save(pipeline).Error
+}
+
+func (r *Repository) ListPipelines(projectID uuid.UUID) ([]DeployPipeline, error) {
+	var pipelines []DeployPipeline
+	err := r.db.Where("project_id = ? AND deleted_at IS NULL", projectID).Order("name ASC").Find(&pipelines).Error
+	return pipelines, err
+}
+
+func (r *Repository) GetPipeline(projectID, pipelineID uuid.UUID) (*DeployPipeline, error) {
+	var pipeline DeployPipeline
+	err := r.db.First(&pipeline, "id = ? AND project_id = ? AND deleted_at IS NULL", pipelineID, projectID).Error
+	return &pipeline, err
+}
+
+func (r *Repository) PipelineNameExists(projectID uuid.UUID, name string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&DeployPipeline{}).
+		Where("project_id = ? AND LOWER(name) = LOWER(?) AND id <> ? AND deleted_at IS NULL", projectID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *Repository) GetScriptByName(projectID uuid.UUID, name string) (*DeployScript, error) {
+	var script DeployScript
+	err := r.db.First(&script, "project_id = ? AND LOWER(name) = LOWER(?) AND deleted_at IS NULL", projectID, name).Error
+	return &script, err
+}
+
+// HasActivePipelineRun reports whether a run of the pipeline is running or waiting for an approval
+func (r *Repository) HasActivePipelineRun(pipelineID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&PipelineRun{}).
+		Where("pipeline_id = ? AND status IN ?", pipelineID, []string{RunStatusRunning, PipelineStatusWaitingApproval}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// TouchRuns refreshes the heartbeat of the runs an instance executes
+func (r *Repository) TouchRuns(instanceID uuid.UUID) error {
+	if err := r.db.Model(&ScriptRun{}).
+		Where("owner_instance = ? AND status = ?", instanceID, RunStatusRunning).
+		Update("heartbeat_at", gorm.Expr("NOW()")).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&PipelineRun{}).
+		Where("owner_instance = ? AND status = ?", instanceID, RunStatusRunning).
+		Update("heartbeat_at", gorm.Expr("NOW()")).Error
+}
+
+// ClaimPipelineRun makes an instance the owner of a run and refreshes its heartbeat
+func (r *Repository) ClaimPipelineRun(runID, instanceID uuid.UUID) error {
+	return r.db.Model(&PipelineRun{}).
+		Where("id = ?", runID).
+		Updates(map[string]any{"owner_instance": instanceID, "heartbeat_at": gorm.Expr("NOW()")}).Error
+}
+
+// staleRuns selects running runs of other instances whose heartbeat is older than cutoff.
+// Runs from before heartbeats existed count from their start.
+func staleRuns(tx *gorm.DB, model any, instanceID uuid.UUID, cutoff time.Time) *gorm.DB {
+	return tx.Model(model).
+		Where("status = ? AND (owner_instance IS NULL OR owner_instance <> ?)", RunStatusRunning, instanceID).
+		Where("COALESCE(heartbeat_at, started_at) < ?", cutoff)
+}
+
+// FailStaleScriptRuns fails script runs whose instance stopped before they finished
+func (r *Repository) FailStaleScriptRuns(instanceID uuid.UUID, cutoff time.Time) (int, error) {
+	var ids []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := staleRuns(tx, &ScriptRun{}, instanceID, cutoff).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&ScriptRunTarget{}).
+			Where("run_id IN ? AND status IN ?", ids, []string{RunStatusPending, RunStatusRunning}).
+			Updates(map[string]any{
+				"status":      RunStatusError,
+				"error":       errRunInterrupted.Error(),
+				"finished_at": gorm.Expr("NOW()"),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ScriptRun{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{"status": RunStatusError, "finished_at": gorm.Expr("NOW()")}).Error
+	})
+	return len(ids), err
+}
+
+// FailStalePipelineRuns fails pipeline runs whose instance stopped while a stage
+// was running. Runs waiting for an approval have no owner and are kept.
+func (r *Repository) FailStalePipelineRuns(instanceID uuid.UUID, cutoff time.Time) ([]PipelineRun, error) {
+	var runs []PipelineRun
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := staleRuns(tx, &PipelineRun{}, instanceID, cutoff).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&runs).Error; err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			return nil
+		}
+		ids := make([]uuid.UUID, 0, len(runs))
+		for _, run := range runs {
+			ids = append(ids, run.ID)
+		}
+		if err := tx.Model(&PipelineStageRun{}).
+			Where("pipeline_run_id IN ? AND status = ?", ids, RunStatusRunning).
+			Updates(map[string]any{"status": RunStatusFailed, "finished_at": gorm.Expr("NOW()")}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&PipelineStageRun{}).
+			Where("pipeline_run_id IN ? AND status = ?", ids, RunStatusPending).
+			Update("status", PipelineStatusSkipped).Error; err != nil {
+			return err
+		}
+		return tx.Model(&PipelineRun{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{"status": RunStatusFailed, "finished_at": gorm.Expr("NOW()")}).Error
+	})
+	return runs, err
+}
+
+// CreatePipelineRun stores a run with its stages
+func (r *Repository) CreatePipelineRun(run *PipelineRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *Repository) UpdatePipelineRun(run *PipelineRun) error {
+	return r.db.Omit("Stages", "HeartbeatAt").Save(run).Error
+}
+
+func (r *Repository) UpdatePipelineStageRun(stage *PipelineStageRun) error {
+	return r.db.Save(stage).Error
+}
+
+// DecidePipelineStage moves an approval stage out of waiting. It returns false
+// when another admin decided first.
+func (r *Repository) DecidePipelineStage(stage *PipelineStageRun) (bool, error) {
+	result := r.db.Model(&PipelineStageRun{}).
+		Where("id = ? AND status = ?", stage.ID, PipelineStatusWaitingApproval).
+		Updates(map[string]any{
+			"status":      stage.Status,
+			"decided_by":  stage.DecidedBy,
+			"decided_at":  stage.DecidedAt,
+			"comment":     stage.Comment,
+			"finished_at": stage.FinishedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func orderStages(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func (r *Repository) ListPipelineRuns(pipelineID uuid.UUID, limit int) ([]PipelineRun, error) {
+	var runs []PipelineRun
+	err := r.db.Preload("Stages", orderStages).
+		Where("pipeline_id = ?", pipelineID).
+		Order("started_at DESC").Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+func (r *Repository) GetPipelineRun(projectID, runID uuid.UUID) (*PipelineRun, error) {
+	var run PipelineRun
+	err := r.db.Preload("Stages", orderStages).First(&run, "id = ? AND project_id = ?", runID, projectID).Error
+	return &run, err
+}
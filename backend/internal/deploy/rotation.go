@@ -0,0 +1,167 @@
+package deploy
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SecretTable is a table with columns holding values of the Encryptor. Key is
+// its UUID primary key, rows are paged in key order.
+type SecretTable struct {
+	Name    string
+	Key     string
+	Columns []string
+}
+
+// SecretTables lists every table the Encryptor writes to. deploy_servers comes
+// first, MFA and webhook secrets share the keyring.
+var SecretTables = []SecretTable{
+	{Name: "deploy_servers", Key: "id", Columns: []string{"encrypted_password", "encrypted_private_key"}},
+	{Name: "user_mfa", Key: "user_id", Columns: []string{"secret_encrypted"}},
+	{Name: "webhooks", Key: "id", Columns: []string{"encrypted_secret", "encrypted_previous_secret"}},
+}
+
+// RotationStats counts the rows a rotation went through
+type RotationStats struct {
+	Scanned int
+	Rotated int
+	// Changed rows were written by the API while they were re-encrypted, they
+	// already use the key the API runs with
+	Changed int
+}
+
+// RotateSecrets re-encrypts the values of a table that are not encrypted with
+// the active key, batchSize rows per transaction. Rows are compared with what
+// was read before they are updated, so it can run while the API is up.
+func RotateSecrets(db *gorm.DB, encryptor *Encryptor, table SecretTable, batchSize int, dryRun bool) (RotationStats, error) {
+	var stats RotationStats
+	selectSQL := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s > ?::uuid ORDER BY %s LIMIT ?",
+		table.Key, strings.Join(table.Columns, ", "), table.Name, table.Key, table.Key)
+
+	after := uuid.Nil.String()
+	for {
+		rows, err := readSecretRows(db, selectSQL, after, batchSize, len(table.Columns))
+		if err != nil {
+			return stats, fmt.Errorf("%s: %w", table.Name, err)
+		}
+		if len(rows) == 0 {
+			return stats, nil
+		}
+		after = rows[len(rows)-1].key
+		stats.Scanned += len(rows)
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				rotated, changed, err := rotateSecretRow(tx, encryptor, table, row, dryRun)
+				if err != nil {
+					return fmt.Errorf("%s %s: %w", table.Name, row.key, err)
+				}
+				if rotated {
+					stats.Rotated++
+				}
+				if changed {
+					stats.Changed++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+}
+
+// CountLegacySecrets counts the rows holding values encrypted before key IDs
+// were added, they can only be read with DEPLOY_SECRETS_KEY
+func CountLegacySecrets(db *gorm.DB) (int64, error) {
+	var total int64
+	for _, table := range SecretTables {
+		conditions := make([]string, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			conditions = append(conditions, fmt.Sprintf("(%s IS NOT NULL AND position(':' in %s) = 0)", column, column))
+		}
+		var count int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table.Name, strings.Join(conditions, " OR "))
+		if err := db.Raw(query).Scan(&count).Error; err != nil {
+			return 0, fmt.Errorf("%s: %w", table.Name, err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+type secretRow struct {
+	key    string
+	values []sql.NullString
+}
+
+func readSecretRows(db *gorm.DB, query, after string, limit, columns int) ([]secretRow, error) {
+	rows, err := db.Raw(query, after, limit).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]secretRow, 0, limit)
+	for rows.Next() {
+		row := secretRow{values: make([]sql.NullString, columns)}
+		dest := []any{&row.key}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// rotateSecretRow re-encrypts the columns of one row. It reports whether the
+// row needed rotating and whether it changed since it was read.
+func rotateSecretRow(tx *gorm.DB, encryptor *Encryptor, table SecretTable, row secretRow, dryRun bool) (bool, bool, error) {
+	sets := make([]string, 0, len(table.Columns))
+	conditions := []string{table.Key + " = ?::uuid"}
+	setArgs := make([]any, 0, len(table.Columns))
+	conditionArgs := []any{row.key}
+
+	for i, column := range table.Columns {
+		value := row.values[i]
+		if !value.Valid {
+			conditions = append(conditions, column+" IS NULL")
+			continue
+		}
+		conditions = append(conditions, column+" = ?")
+		conditionArgs = append(conditionArgs, value.String)
+		if !encryptor.NeedsRotation(value.String) {
+			continue
+		}
+
+		rotated, err := encryptor.Reencrypt(value.String)
+		if err != nil {
+			return false, false, fmt.Errorf("%s: %w", column, err)
+		}
+		sets = append(sets, column+" = ?")
+		setArgs = append(setArgs, rotated)
+	}
+	if len(sets) == 0 {
+		return false, false, nil
+	}
+	if dryRun {
+		return true, false, nil
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table.Name, strings.Join(sets, ", "), strings.Join(conditions, " AND "))
+	result := tx.Exec(query, append(setArgs, conditionArgs...)...)
+	if result.Error != nil {
+		return false, false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, true, nil
+	}
+	return true, false, nil
+}
@@ -0,0 +1,297 @@
+package deploy
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+func respondScriptError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrNotProjectMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+	case errors.Is(err, ErrNotProjectAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	case errors.Is(err, ErrTwoFactorRequired):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication required"})
+	case errors.Is(err, ErrScriptNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Script not found"})
+	case errors.Is(err, ErrScriptVersionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Script version not found"})
+	case errors.Is(err, ErrScriptNameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A script with this name already exists"})
+	case errors.Is(err, ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Server not found"})
+	case errors.Is(err, ErrScriptRunNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Run not found"})
+	case errors.Is(err, ErrRunLogNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Run has no log for this server"})
+	case errors.Is(err, ErrStorageUnavailable):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage is unavailable, please try again later"})
+	default:
+		return fiber.ErrInternalServerError
+	}
+}
+
+// POST /api/projects/:projectId/deploy/scripts
+func (h *Handler) CreateScript(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req CreateDeployScriptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	script, err := h.service.CreateScript(projectID, userID, req)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(script)
+}
+
+// GET /api/projects/:projectId/deploy/scripts
+func (h *Handler) ListScripts(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	scripts, err := h.service.ListScripts(projectID, userID)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.JSON(scripts)
+}
+
+// GET /api/projects/:projectId/deploy/scripts/:scriptId
+func (h *Handler) GetScript(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	scriptID, err := uuid.Parse(c.Params("scriptId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	script, err := h.service.GetScript(projectID, scriptID, userID)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.JSON(script)
+}
+
+// PUT /api/projects/:projectId/deploy/scripts/:scriptId
+func (h *Handler) UpdateScript(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	scriptID, err := uuid.Parse(c.Params("scriptId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req UpdateDeployScriptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	script, err := h.service.UpdateScript(projectID, scriptID, userID, req)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.JSON(script)
+}
+
+// DELETE /api/projects/:projectId/deploy/scripts/:scriptId
+func (h *Handler) DeleteScript(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	scriptID, err := uuid.Parse(c.Params("scriptId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if err := h.service.DeleteScript(projectID, scriptID, userID); err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GET /api/projects/:projectId/deploy/scripts/:scriptId/versions
+func (h *Handler) ListScriptVersions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	scriptID, err := uuid.Parse(c.Params("scriptId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	versions, err := h.service.ListScriptVersions(projectID, scriptID, userID)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.JSON(versions)
+}
+
+// Start a script on servers, progress is streamed on /deploy/runs/:runId/ws
+// POST /api/projects/:projectId/deploy/scripts/:scriptId/runs
+func (h *Handler) RunScript(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	scriptID, err := uuid.Parse(c.Params("scriptId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req RunDeployScriptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	run, err := h.service.RunScript(projectID, scriptID, userID, req)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(run.ToResponse())
+}
+
+// GET /api/projects/:projectId/deploy/runs?script_id=&limit=
+func (h *Handler) ListScriptRuns(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var scriptID *uuid.UUID
+	if scriptIDStr := c.Query("script_id"); scriptIDStr != "" {
+		parsed, err := uuid.Parse(scriptIDStr)
+		if err != nil {
+			return fiber.ErrBadRequest
+		}
+		scriptID = &parsed
+	}
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	runs, err := h.service.ListScriptRuns(projectID, userID, scriptID, limit)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+
+	responses := make([]ScriptRunResponse, 0, len(runs))
+	for _, run := range runs {
+		responses = append(responses, run.ToResponse())
+	}
+	return c.JSON(responses)
+}
+
+// GET /api/projects/:projectId/deploy/runs/:runId
+func (h *Handler) GetScriptRun(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	run, err := h.service.GetScriptRun(projectID, runID, userID)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+	return c.JSON(run.ToResponse())
+}
+
+// Stream the output of a run on one server
+// GET /api/projects/:projectId/deploy/runs/:runId/targets/:targetId/log
+func (h *Handler) GetRunLog(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	targetID, err := uuid.Parse(c.Params("targetId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	target, output, err := h.service.OpenRunLog(projectID, runID, targetID, userID)
+	if err != nil {
+		return respondScriptError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, runLogContentType)
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="`+target.ID.String()+`.log"`)
+	return c.SendStream(output, int(target.LogSize))
+}
@@ -0,0 +1,790 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/project"
+)
+
+// maxRunLogSize caps the stored output of a script on one server, the stream is not capped
+const maxRunLogSize = 16 << 20
+
+const runLogContentType = "text/plain; charset=utf-8"
+
+// Messages on the run websocket. A watcher gets the run first, then target
+// updates and output as they happen, and the final run before the socket closes.
+//
+//	{"type":"run","run":{...}}
+//	{"type":"target","target":{...}}
+//	{"type":"output","target_id":"...","data":"..."}
+//
+// Clients may send {"type":"ping"} and get {"type":"pong"}.
+const (
+	RunEventRun    = "run"
+	RunEventTarget = "target"
+	RunEventOutput = "output"
+)
+
+var (
+	ErrScriptNotFound        = errors.New("script not found")
+	ErrScriptVersionNotFound = errors.New("script version not found")
+	ErrScriptNameTaken       = errors.New("script name already exists")
+	ErrScriptRunNotFound     = errors.New("script run not found")
+	ErrRunLogNotFound        = errors.New("run log not found")
+
+	errScriptTimeout  = errors.New("script timed out")
+	errRunInterrupted = errors.New("the server executing the run stopped")
+)
+
+// ScriptLimits bound script runs, requests may only lower them
+type ScriptLimits struct {
+	DefaultTimeout time.Duration
+	MaxTimeout     time.Duration
+	MaxConcurrency int
+}
+
+var defaultScriptLimits = ScriptLimits{
+	DefaultTimeout: 10 * time.Minute,
+	MaxTimeout:     time.Hour,
+	MaxConcurrency: 5,
+}
+
+// ScriptRunEvent is a message to the watchers of a run
+type ScriptRunEvent struct {
+	Type     string                   `json:"type"`
+	TargetID *uuid.UUID               `json:"target_id,omitempty"`
+	Data     string                   `json:"data,omitempty"`
+	Target   *ScriptRunTargetResponse `json:"target,omitempty"`
+	Run      *ScriptRunResponse       `json:"run,omitempty"`
+}
+
+// liveRun is the state of a run in progress and the sockets watching it
+type liveRun struct {
+	mu       sync.Mutex
+	run      ScriptRun
+	watchers map[*terminalConn]bool
+	ended    bool
+	// done is closed when the run has ended
+	done chan struct{}
+}
+
+func newLiveRun(run *ScriptRun) *liveRun {
+	live := &liveRun{run: *run, watchers: make(map[*terminalConn]bool), done: make(chan struct{})}
+	live.run.Targets = append([]ScriptRunTarget(nil), run.Targets...)
+	return live
+}
+
+func (l *liveRun) currentLocked() ScriptRun {
+	run := l.run
+	run.Targets = append([]ScriptRunTarget(nil), l.run.Targets...)
+	return run
+}
+
+// current returns a copy of the run
+func (l *liveRun) current() ScriptRun {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.currentLocked()
+}
+
+func (l *liveRun) broadcastLocked(event ScriptRunEvent) {
+	for conn := range l.watchers {
+		_ = conn.writeJSON(event)
+	}
+}
+
+// watch sends the run to a socket and streams updates to it. It returns false
+// once the run has ended.
+func (l *liveRun) watch(conn *terminalConn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ended {
+		return false
+	}
+	l.watchers[conn] = true
+	response := l.currentLocked().ToResponse()
+	_ = conn.writeJSON(ScriptRunEvent{Type: RunEventRun, Run: &response})
+	return true
+}
+
+// unwatch stops writing to a socket
+func (l *liveRun) unwatch(conn *terminalConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.watchers, conn)
+}
+
+func (l *liveRun) updateTarget(target ScriptRunTarget) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.run.Targets {
+		if l.run.Targets[i].ID == target.ID {
+			l.run.Targets[i] = target
+		}
+	}
+	response := target.ToResponse()
+	l.broadcastLocked(ScriptRunEvent{Type: RunEventTarget, Target: &response})
+}
+
+func (l *liveRun) output(targetID uuid.UUID, data string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.broadcastLocked(ScriptRunEvent{Type: RunEventOutput, TargetID: &targetID, Data: data})
+}
+
+// end sends the final run to every socket and closes them
+func (l *liveRun) end(status string, finishedAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ended = true
+	defer close(l.done)
+	l.run.Status = status
+	l.run.FinishedAt = &finishedAt
+	response := l.currentLocked().ToResponse()
+	for conn := range l.watchers {
+		_ = conn.writeJSON(ScriptRunEvent{Type: RunEventRun, Run: &response})
+		conn.close(websocket.CloseNormalClosure, "run finished")
+		_ = conn.conn.SetReadDeadline(time.Now().Add(terminalCloseTimeout))
+	}
+}
+
+// runRegistry holds the runs in progress on this API instance
+type runRegistry struct {
+	mu   sync.Mutex
+	runs map[uuid.UUID]*liveRun
+}
+
+func newRunRegistry() *runRegistry {
+	return &runRegistry{runs: make(map[uuid.UUID]*liveRun)}
+}
+
+func (r *runRegistry) add(live *liveRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[live.run.ID] = live
+}
+
+func (r *runRegistry) remove(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, id)
+}
+
+func (r *runRegistry) get(id uuid.UUID) (*liveRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	live, ok := r.runs[id]
+	return live, ok
+}
+
+// runLog receives the output of a script on one server. Output is streamed to
+// the watchers of the run and, with storage, kept in a temporary file that is
+// uploaded when the script ends.
+type runLog struct {
+	mu        sync.Mutex
+	live      *liveRun
+	targetID  uuid.UUID
+	file      *os.File
+	size      int64
+	truncated bool
+	// Output may split a UTF-8 sequence across writes, the tail waits for the next write
+	pending []byte
+}
+
+func newRunLog(live *liveRun, targetID uuid.UUID, store bool) *runLog {
+	l := &runLog{live: live, targetID: targetID}
+	if store {
+		file, err := os.CreateTemp("", "devhub-run-*.log")
+		if err != nil {
+			log.Printf("failed to create run log file: %v", err)
+		} else {
+			l.file = file
+		}
+	}
+	return l
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil && !l.truncated {
+		chunk := p
+		if room := maxRunLogSize - l.size; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+			l.truncated = true
+		}
+		n, err := l.file.Write(chunk)
+		l.size += int64(n)
+		if err != nil {
+			log.Printf("failed to write run log: %v", err)
+			l.truncated = true
+		}
+	}
+
+	data := append(l.pending, p...)
+	complete := completeUTF8(data)
+	l.pending = append([]byte(nil), data[complete:]...)
+	if complete > 0 {
+		l.live.output(l.targetID, string(data[:complete]))
+	}
+	return len(p), nil
+}
+
+// finish streams what is left and rewinds the file for the upload
+func (l *runLog) finish() (io.Reader, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) > 0 {
+		l.live.output(l.targetID, strings.ToValidUTF8(string(l.pending), "�"))
+		l.pending = nil
+	}
+	if l.file == nil {
+		return nil, 0, nil
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to rewind run log: %w", err)
+	}
+	return l.file, l.size, nil
+}
+
+// discard removes the temporary file
+func (l *runLog) discard() {
+	if l.file != nil {
+		_ = l.file.Close()
+		_ = os.Remove(l.file.Name())
+	}
+}
+
+// SetScriptLimits sets the timeout and concurrency limits of script runs (called from main.go)
+func (s *Service) SetScriptLimits(limits ScriptLimits) {
+	s.scriptLimits = limits
+}
+
+func (s *Service) getScript(projectID, scriptID uuid.UUID) (*DeployScript, error) {
+	script, err := s.repo.GetScript(projectID, scriptID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScriptNotFound
+		}
+		return nil, err
+	}
+	return script, nil
+}
+
+func (s *Service) getScriptVersion(scriptID uuid.UUID, version int) (*DeployScriptVersion, error) {
+	scriptVersion, err := s.repo.GetScriptVersion(scriptID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScriptVersionNotFound
+		}
+		return nil, err
+	}
+	return scriptVersion, nil
+}
+
+func (s *Service) auditScript(script *DeployScript, userID uuid.UUID, action string, metadata map[string]any) {
+	metadata["script_id"] = script.ID
+	metadata["script_name"] = script.Name
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: script.ProjectID,
+		UserID:    userID,
+		Action:    action,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *Service) CreateScript(projectID, userID uuid.UUID, req CreateDeployScriptRequest) (*DeployScriptWithContent, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	taken, err := s.repo.ScriptNameExists(projectID, name, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrScriptNameTaken
+	}
+
+	script := &DeployScript{
+		ProjectID:      projectID,
+		Name:           name,
+		Description:    req.Description,
+		CurrentVersion: 1,
+		CreatedBy:      userID,
+	}
+	version := &DeployScriptVersion{
+		Version:   1,
+		Content:   req.Content,
+		CreatedBy: userID,
+	}
+	if err := s.repo.CreateScript(script, version); err != nil {
+		return nil, err
+	}
+
+	s.auditScript(script, userID, "script_created", map[string]any{"version": 1})
+	return &DeployScriptWithContent{DeployScript: *script, Content: version.Content}, nil
+}
+
+func (s *Service) ListScripts(projectID, userID uuid.UUID) ([]DeployScript, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListScripts(projectID)
+}
+
+func (s *Service) GetScript(projectID, scriptID, userID uuid.UUID) (*DeployScriptWithContent, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	script, err := s.getScript(projectID, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.getScriptVersion(script.ID, script.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &DeployScriptWithContent{DeployScript: *script, Content: version.Content}, nil
+}
+
+// UpdateScript changes a script. Versions are never changed, a new content
+// becomes the next version so earlier runs stay reproducible.
+func (s *Service) UpdateScript(projectID, scriptID, userID uuid.UUID, req UpdateDeployScriptRequest) (*DeployScriptWithContent, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	script, err := s.getScript(projectID, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.getScriptVersion(script.ID, script.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		taken, err := s.repo.ScriptNameExists(projectID, name, script.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrScriptNameTaken
+		}
+		script.Name = name
+	}
+	if req.Description != nil {
+		script.Description = req.Description
+	}
+
+	var version *DeployScriptVersion
+	content := current.Content
+	if req.Content != nil && *req.Content != current.Content {
+		script.CurrentVersion++
+		content = *req.Content
+		version = &DeployScriptVersion{
+			ScriptID:  script.ID,
+			Version:   script.CurrentVersion,
+			Content:   content,
+			CreatedBy: userID,
+		}
+	}
+
+	if err := s.repo.UpdateScript(script, version); err != nil {
+		return nil, err
+	}
+
+	s.auditScript(script, userID, "script_updated", map[string]any{
+		"version":     script.CurrentVersion,
+		"new_version": version != nil,
+	})
+	return &DeployScriptWithContent{DeployScript: *script, Content: content}, nil
+}
+
+// DeleteScript hides a script, its versions and runs are kept for the history
+func (s *Service) DeleteScript(projectID, scriptID, userID uuid.UUID) error {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return err
+	}
+	script, err := s.getScript(projectID, scriptID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	script.DeletedAt = &now
+	if err := s.repo.UpdateScript(script, nil); err != nil {
+		return err
+	}
+
+	s.auditScript(script, userID, "script_deleted", map[string]any{})
+	return nil
+}
+
+func (s *Service) ListScriptVersions(projectID, scriptID, userID uuid.UUID) ([]DeployScriptVersion, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	script, err := s.getScript(projectID, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListScriptVersions(script.ID)
+}
+
+// RunScript starts a script version on servers of the project and returns
+// right away. Progress is streamed to the run websocket.
+func (s *Service) RunScript(projectID, scriptID, userID uuid.UUID, req RunDeployScriptRequest) (*ScriptRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireTwoFactor(projectID, userID); err != nil {
+		return nil, err
+	}
+	script, err := s.getScript(projectID, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	versionNumber := script.CurrentVersion
+	if req.Version != nil {
+		versionNumber = *req.Version
+	}
+	version, err := s.getScriptVersion(script.ID, versionNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.resolveServers(projectID, req.ServerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	run, _, err := s.startScriptRun(script, version, userID, servers, req.TimeoutSeconds, req.Concurrency)
+	return run, err
+}
+
+// resolveServers loads the servers of a run in order, without duplicates
+func (s *Service) resolveServers(projectID uuid.UUID, serverIDs []uuid.UUID) ([]*DeployServer, error) {
+	seen := make(map[uuid.UUID]bool, len(serverIDs))
+	servers := make([]*DeployServer, 0, len(serverIDs))
+	for _, serverID := range serverIDs {
+		if seen[serverID] {
+			continue
+		}
+		seen[serverID] = true
+		server, err := s.repo.GetServer(projectID, serverID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrServerNotFound
+			}
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// startScriptRun stores a run and executes it in the background. The returned
+// live run is done once every target has finished.
+func (s *Service) startScriptRun(script *DeployScript, version *DeployScriptVersion, userID uuid.UUID, servers []*DeployServer, timeoutSeconds, maxConcurrency *int) (*ScriptRun, *liveRun, error) {
+	limits := s.scriptLimits
+	timeout := limits.DefaultTimeout
+	if timeoutSeconds != nil && *timeoutSeconds >= 1 {
+		timeout = time.Duration(*timeoutSeconds) * time.Second
+	}
+	if timeout > limits.MaxTimeout {
+		timeout = limits.MaxTimeout
+	}
+	concurrency := limits.MaxConcurrency
+	if maxConcurrency != nil && *maxConcurrency < concurrency {
+		concurrency = *maxConcurrency
+	}
+	if concurrency > len(servers) {
+		concurrency = len(servers)
+	}
+	// An empty slot channel would block the run forever
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	now := time.Now()
+	run := &ScriptRun{
+		ProjectID:      script.ProjectID,
+		ScriptID:       script.ID,
+		ScriptVersion:  version.Version,
+		UserID:         userID,
+		Status:         RunStatusRunning,
+		TimeoutSeconds: int(timeout / time.Second),
+		Concurrency:    concurrency,
+		StartedAt:      now,
+		OwnerInstance:  &s.instanceID,
+		HeartbeatAt:    &now,
+	}
+	byID := make(map[uuid.UUID]*DeployServer, len(servers))
+	serverIDs := make([]uuid.UUID, 0, len(servers))
+	for _, server := range servers {
+		byID[server.ID] = server
+		serverIDs = append(serverIDs, server.ID)
+		run.Targets = append(run.Targets, ScriptRunTarget{
+			ServerID:   &server.ID,
+			ServerName: server.Name,
+			Status:     RunStatusPending,
+		})
+	}
+	if err := s.repo.CreateScriptRun(run); err != nil {
+		return nil, nil, fmt.Errorf("failed to create script run: %w", err)
+	}
+
+	s.auditScript(script, userID, "script_run", map[string]any{
+		"run_id":          run.ID,
+		"version":         version.Version,
+		"server_ids":      serverIDs,
+		"timeout_seconds": run.TimeoutSeconds,
+		"concurrency":     concurrency,
+	})
+
+	live := newLiveRun(run)
+	s.runs.add(live)
+	go s.executeRun(live, script, version.Content, byID, timeout)
+
+	return run, live, nil
+}
+
+// executeRun runs the script on every target, at most Concurrency at a time
+func (s *Service) executeRun(live *liveRun, script *DeployScript, content string, servers map[uuid.UUID]*DeployServer, timeout time.Duration) {
+	run := live.current()
+	slots := make(chan struct{}, run.Concurrency)
+	var wg sync.WaitGroup
+	for _, target := range run.Targets {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.runTarget(live, run, target, servers[*target.ServerID], content, timeout)
+		}()
+	}
+	wg.Wait()
+
+	status := RunStatusSucceeded
+	for _, target := range live.current().Targets {
+		if target.Status != RunStatusSucceeded {
+			status = RunStatusFailed
+			break
+		}
+	}
+
+	finished := live.current()
+	finishedAt := time.Now()
+	finished.Status = status
+	finished.FinishedAt = &finishedAt
+	if err := s.repo.UpdateScriptRun(&finished); err != nil {
+		log.Printf("failed to update script run %s: %v", finished.ID, err)
+	}
+	live.end(status, finishedAt)
+	s.runs.remove(finished.ID)
+
+	s.auditScript(script, finished.UserID, "script_run_finished", map[string]any{
+		"run_id":      finished.ID,
+		"status":      status,
+		"duration_ms": finished.FinishedAt.Sub(finished.StartedAt).Milliseconds(),
+	})
+	if s.projectEventHook != nil {
+		s.projectEventHook(finished.ProjectID, project.EventDeployScriptRunFinished, map[string]any{
+			"run_id":      finished.ID,
+			"script_id":   script.ID,
+			"script_name": script.Name,
+			"version":     finished.ScriptVersion,
+			"status":      status,
+			"user_id":     finished.UserID,
+		})
+	}
+}
+
+// runTarget runs the script on one server and stores its output and exit status
+func (s *Service) runTarget(live *liveRun, run ScriptRun, target ScriptRunTarget, server *DeployServer, content string, timeout time.Duration) {
+	started := time.Now()
+	target.Status = RunStatusRunning
+	target.StartedAt = &started
+	_ = s.repo.UpdateScriptRunTarget(&target)
+	live.updateTarget(target)
+
+	output := newRunLog(live, target.ID, s.recordings != nil && s.recordings.IsReady())
+	defer output.discard()
+	err := s.runCommand(run.ProjectID, run.UserID, server, content, output, timeout)
+
+	finished := time.Now()
+	target.FinishedAt = &finished
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		code := 0
+		target.Status = RunStatusSucceeded
+		target.ExitCode = &code
+	case errors.Is(err, errScriptTimeout):
+		message := err.Error()
+		target.Status = RunStatusTimedOut
+		target.Error = &message
+	case errors.As(err, &exitErr):
+		target.Status = RunStatusFailed
+		if signal := exitErr.Signal(); signal != "" {
+			target.ExitSignal = &signal
+		} else {
+			code := exitErr.ExitStatus()
+			target.ExitCode = &code
+		}
+	default:
+		message := err.Error()
+		target.Status = RunStatusError
+		target.Error = &message
+	}
+
+	reader, size, err := output.finish()
+	if err == nil && reader != nil {
+		key := fmt.Sprintf("deploy-runs/%s/%s/%s.log", run.ProjectID, run.ID, target.ID)
+		if err = s.recordings.PutObject(context.Background(), key, reader, size, runLogContentType); err == nil {
+			target.LogKey = &key
+			target.LogSize = size
+			target.Truncated = output.truncated
+		}
+	}
+	if err != nil {
+		log.Printf("failed to store run log %s: %v", target.ID, err)
+	}
+
+	if err := s.repo.UpdateScriptRunTarget(&target); err != nil {
+		log.Printf("failed to update script run target %s: %v", target.ID, err)
+	}
+	live.updateTarget(target)
+}
+
+// runCommand runs a script with session.Run, it is killed once the timeout passes
+func (s *Service) runCommand(projectID, userID uuid.UUID, server *DeployServer, content string, output io.Writer, timeout time.Duration) error {
+	client, err := s.dial(server, s.HostKeyCallback(projectID, userID, server))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	session.Stdout = output
+	session.Stderr = output
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(content)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		// Not every server delivers signals, closing the connection ends the command anyway
+		_ = session.Signal(ssh.SIGKILL)
+		_ = client.Close()
+		<-done
+		return errScriptTimeout
+	}
+}
+
+// ListScriptRuns returns the run history of a project, or of one script
+func (s *Service) ListScriptRuns(projectID, userID uuid.UUID, scriptID *uuid.UUID, limit int) ([]ScriptRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListScriptRuns(projectID, scriptID, limit)
+}
+
+// GetScriptRun returns a run, with the live state of its targets while it runs here
+func (s *Service) GetScriptRun(projectID, runID, userID uuid.UUID) (*ScriptRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if live, ok := s.runs.get(runID); ok && live.run.ProjectID == projectID {
+		run := live.current()
+		return &run, nil
+	}
+	run, err := s.repo.GetScriptRun(projectID, runID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScriptRunNotFound
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// WatchRun returns a run in progress to stream, or nil when it is not running on this instance
+func (s *Service) WatchRun(projectID, runID, userID uuid.UUID) (*liveRun, error) {
+	run, err := s.GetScriptRun(projectID, runID, userID)
+	if err != nil {
+		return nil, err
+	}
+	live, ok := s.runs.get(run.ID)
+	if !ok {
+		return nil, nil
+	}
+	return live, nil
+}
+
+// OpenRunLog returns the stored output of a run on one server. Viewing is audited like recordings.
+func (s *Service) OpenRunLog(projectID, runID, targetID, userID uuid.UUID) (*ScriptRunTarget, io.ReadCloser, error) {
+	run, err := s.GetScriptRun(projectID, runID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var target *ScriptRunTarget
+	for i := range run.Targets {
+		if run.Targets[i].ID == targetID {
+			target = &run.Targets[i]
+		}
+	}
+	if target == nil || target.LogKey == nil {
+		return nil, nil, ErrRunLogNotFound
+	}
+	if s.recordings == nil || !s.recordings.IsReady() {
+		return nil, nil, ErrStorageUnavailable
+	}
+
+	output, err := s.recordings.GetObject(context.Background(), *target.LogKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get run log: %w", err)
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  target.ServerID,
+		UserID:    userID,
+		Action:    "run_log_viewed",
+		Metadata: map[string]any{
+			"run_id":    run.ID,
+			"target_id": target.ID,
+		},
+		CreatedAt: time.Now(),
+	})
+
+	return target, output, nil
+}
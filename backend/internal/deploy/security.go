@@ -32,7 +32,7 @@ func ValidateHost(host string) error {
 	}
 
 	if ip := net.ParseIP(trimmed); ip != nil {
-		if isPrivateIP(ip) {
+		if IsPrivateIP(ip) {
 			return fmt.Errorf("private or loopback addresses are not allowed")
 		}
 		return nil
@@ -43,14 +43,15 @@ func ValidateHost(host string) error {
 		return fmt.Errorf("failed to resolve host")
 	}
 	for _, ip := range ips {
-		if isPrivateIP(ip) {
+		if IsPrivateIP(ip) {
 			return fmt.Errorf("host resolves to private or loopback address")
 		}
 	}
 	return nil
 }
 
-func isPrivateIP(ip net.IP) bool {
+// IsPrivateIP reports whether ip is loopback, link-local, multicast or in a private range
+func IsPrivateIP(ip net.IP) bool {
 	if ip == nil {
 		return true
 	}
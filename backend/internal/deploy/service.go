@@ -1,30 +1,134 @@
 package deploy
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/storage"
+	"golang.org/x/crypto/ssh"
 	"gorm.io/gorm"
 )
 
 var (
-	ErrNotProjectMember = errors.New("not a project member")
-	ErrNotProjectAdmin  = errors.New("not a project admin")
-	ErrServerNotFound   = errors.New("server not found")
-	ErrInvalidHost      = errors.New("invalid host")
+	ErrNotProjectMember  = errors.New("not a project member")
+	ErrNotProjectAdmin   = errors.New("not a project admin")
+	ErrServerNotFound    = errors.New("server not found")
+	ErrInvalidHost       = errors.New("invalid host")
+	ErrTwoFactorRequired = errors.New("two-factor authentication required")
+	ErrHostUnreachable   = errors.New("could not read the server host key")
+	ErrHostKeyMismatch   = errors.New("server host key does not match the pinned key")
+	ErrHostKeyNotPinned  = errors.New("server host key has not been approved")
+
+	ErrTerminalSessionNotFound = errors.New("terminal session not found")
+	ErrRecordingNotFound       = errors.New("recording not found")
+	ErrStorageUnavailable      = errors.New("storage unavailable")
 )
 
+// HostKeyConfirmationError is returned until an admin confirms the fingerprint
+// of the key the server presents
+type HostKeyConfirmationError struct {
+	HostKey HostKeyInfo
+	// Mismatch is set when a fingerprint was confirmed but the server presents another key
+	Mismatch bool
+}
+
+func (e *HostKeyConfirmationError) Error() string {
+	if e.Mismatch {
+		return "host key does not match the confirmed fingerprint"
+	}
+	return "host key must be confirmed"
+}
+
+// SystemMessageFunc posts a system message to the first topic of a type in a project
+type SystemMessageFunc func(projectID uuid.UUID, topicType, content string, metadata map[string]any)
+
+// TwoFactorCheckFunc reports whether a user has two-factor authentication enabled
+type TwoFactorCheckFunc func(userID uuid.UUID) (bool, error)
+
 type Service struct {
 	repo        *Repository
 	projectRepo *project.Repository
 	encryptor   *Encryptor
+
+	projectEventHook  project.ProjectEventFunc
+	twoFactorCheck    TwoFactorCheckFunc
+	systemMessageHook SystemMessageFunc
+	attachmentLookup  AttachmentLookupFunc
+
+	recordings  *storage.S3Client // optional, also holds run logs and chat attachments
+	recordInput bool
+
+	terminals    *terminalRegistry
+	runs         *runRegistry
+	scriptLimits ScriptLimits
+
+	// instanceID identifies this process as the owner of the runs it executes
+	instanceID uuid.UUID
 }
 
 func NewService(repo *Repository, projectRepo *project.Repository, encryptor *Encryptor) *Service {
-	return &Service{repo: repo, projectRepo: projectRepo, encryptor: encryptor}
+	return &Service{
+		repo:         repo,
+		projectRepo:  projectRepo,
+		encryptor:    encryptor,
+		terminals:    newTerminalRegistry(),
+		runs:         newRunRegistry(),
+		scriptLimits: defaultScriptLimits,
+		instanceID:   uuid.New(),
+	}
+}
+
+// SetProjectEventHook sets the callback used to publish project events (called from main.go)
+func (s *Service) SetProjectEventHook(hook project.ProjectEventFunc) {
+	s.projectEventHook = hook
+}
+
+// SetTwoFactorCheck sets the callback used to enforce the project 2FA policy (called from main.go)
+func (s *Service) SetTwoFactorCheck(check TwoFactorCheckFunc) {
+	s.twoFactorCheck = check
+}
+
+// SetSystemMessageHook sets the callback used to announce shared terminals and pipeline runs in chat (called from main.go)
+func (s *Service) SetSystemMessageHook(hook SystemMessageFunc) {
+	s.systemMessageHook = hook
+}
+
+// SetRecordingStorage enables terminal session recordings (called from main.go)
+func (s *Service) SetRecordingStorage(recordings *storage.S3Client, recordInput bool) {
+	s.recordings = recordings
+	s.recordInput = recordInput
+}
+
+// requireTwoFactor enforces the project policy. Without a checker a project
+// that requires 2FA stays closed.
+func (s *Service) requireTwoFactor(projectID, userID uuid.UUID) error {
+	projectObj, err := s.projectRepo.GetByID(projectID)
+	if err != nil {
+		return err
+	}
+	if !projectObj.RequireTwoFactor {
+		return nil
+	}
+	if s.twoFactorCheck == nil {
+		return ErrTwoFactorRequired
+	}
+
+	enabled, err := s.twoFactorCheck(userID)
+	if err != nil {
+		return fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+	if !enabled {
+		return ErrTwoFactorRequired
+	}
+	return nil
 }
 
 func (s *Service) requireAdmin(projectID, userID uuid.UUID) error {
@@ -84,6 +188,21 @@ func (s *Service) CreateServer(projectID, userID uuid.UUID, req CreateDeployServ
 		return nil, fmt.Errorf("unsupported auth type")
 	}
 
+	// Trust on first use, but only the key the admin has seen
+	hostKey, err := scanHostKey(req.Host, req.Port)
+	if err != nil {
+		return nil, err
+	}
+	info := hostKeyInfo(hostKey)
+	if req.HostKeyFingerprint == nil || *req.HostKeyFingerprint == "" {
+		return nil, &HostKeyConfirmationError{HostKey: info}
+	}
+	if !sameFingerprint(*req.HostKeyFingerprint, info.Fingerprint) {
+		return nil, &HostKeyConfirmationError{HostKey: info, Mismatch: true}
+	}
+
+	encodedKey := marshalHostKey(hostKey)
+	now := time.Now()
 	server := &DeployServer{
 		ProjectID:           projectID,
 		Name:                req.Name,
@@ -94,6 +213,10 @@ func (s *Service) CreateServer(projectID, userID uuid.UUID, req CreateDeployServ
 		EncryptedPassword:   encryptedPassword,
 		EncryptedPrivateKey: encryptedKey,
 		CreatedBy:           userID,
+		HostKey:             &encodedKey,
+		HostKeyFingerprint:  &info.Fingerprint,
+		HostKeyApprovedBy:   &userID,
+		HostKeyApprovedAt:   &now,
 	}
 
 	if err := s.repo.CreateServer(server); err != nil {
@@ -106,10 +229,11 @@ func (s *Service) CreateServer(projectID, userID uuid.UUID, req CreateDeployServ
 		UserID:    userID,
 		Action:    "server_created",
 		Metadata: map[string]any{
-			"host": server.Host,
-			"port": server.Port,
+			"host":                 server.Host,
+			"port":                 server.Port,
+			"host_key_fingerprint": info.Fingerprint,
 		},
-		CreatedAt: time.Now(),
+		CreatedAt: now,
 	})
 
 	return server, nil
@@ -148,6 +272,9 @@ func (s *Service) GetServerForTerminal(projectID, serverID, userID uuid.UUID) (*
 	if err := s.requireAdmin(projectID, userID); err != nil {
 		return nil, err
 	}
+	if err := s.requireTwoFactor(projectID, userID); err != nil {
+		return nil, err
+	}
 	server, err := s.repo.GetServer(projectID, serverID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -158,11 +285,152 @@ func (s *Service) GetServerForTerminal(projectID, serverID, userID uuid.UUID) (*
 	return server, nil
 }
 
-func (s *Service) RecordTerminalConnection(projectID, userID uuid.UUID, server *DeployServer) {
+// ApproveHostKey pins the key the server presents now, e.g. after it was reinstalled.
+// The admin confirms its fingerprint so a key swapped in between is not pinned.
+func (s *Service) ApproveHostKey(projectID, serverID, userID uuid.UUID, req ApproveHostKeyRequest) (*DeployServer, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	server, err := s.repo.GetServer(projectID, serverID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServerNotFound
+		}
+		return nil, err
+	}
+
+	hostKey, err := scanHostKey(server.Host, server.Port)
+	if err != nil {
+		return nil, err
+	}
+	info := hostKeyInfo(hostKey)
+	if !sameFingerprint(req.Fingerprint, info.Fingerprint) {
+		return nil, &HostKeyConfirmationError{HostKey: info, Mismatch: true}
+	}
+
+	previous := server.HostKeyFingerprint
+	encodedKey := marshalHostKey(hostKey)
+	now := time.Now()
+	server.HostKey = &encodedKey
+	server.HostKeyFingerprint = &info.Fingerprint
+	server.HostKeyApprovedBy = &userID
+	server.HostKeyApprovedAt = &now
+	server.PendingHostKeyFingerprint = nil
+	if err := s.repo.UpdateServer(server); err != nil {
+		return nil, err
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  &server.ID,
+		UserID:    userID,
+		Action:    "host_key_approved",
+		Metadata: map[string]any{
+			"host":                 server.Host,
+			"port":                 server.Port,
+			"fingerprint":          info.Fingerprint,
+			"previous_fingerprint": previous,
+		},
+		CreatedAt: now,
+	})
+
+	return server, nil
+}
+
+// HostKeyCallback accepts only the pinned host key of server. Any other key is
+// recorded for an admin to review and audited as a possible interception.
+func (s *Service) HostKeyCallback(projectID, userID uuid.UUID, server *DeployServer) ssh.HostKeyCallback {
+	return func(_ string, remote net.Addr, key ssh.PublicKey) error {
+		if server.HostKey != nil {
+			pinned, err := parseHostKey(*server.HostKey)
+			if err == nil && bytes.Equal(pinned.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		_ = s.repo.SetPendingHostKey(server.ID, fingerprint)
+		server.PendingHostKeyFingerprint = &fingerprint
+		_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+			ProjectID: projectID,
+			ServerID:  &server.ID,
+			UserID:    userID,
+			Action:    "host_key_mismatch",
+			Metadata: map[string]any{
+				"host":                  server.Host,
+				"port":                  server.Port,
+				"remote_addr":           remote.String(),
+				"expected_fingerprint":  server.HostKeyFingerprint,
+				"presented_fingerprint": fingerprint,
+			},
+			CreatedAt: time.Now(),
+		})
+
+		if server.HostKey == nil {
+			return ErrHostKeyNotPinned
+		}
+		return ErrHostKeyMismatch
+	}
+}
+
+// DefaultDeployScript is the script /deploy runs when no script is named
+const DefaultDeployScript = "deploy"
+
+// RequestDeploy runs the current version of a script, found by name, on a
+// server found by name. It is how chat-triggered deploys start.
+func (s *Service) RequestDeploy(projectID, userID uuid.UUID, serverName, scriptName string) (*ScriptRun, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireTwoFactor(projectID, userID); err != nil {
+		return nil, err
+	}
+	server, err := s.repo.GetServerByName(projectID, serverName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServerNotFound
+		}
+		return nil, err
+	}
+	script, err := s.repo.GetScriptByName(projectID, scriptName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScriptNotFound
+		}
+		return nil, err
+	}
+	version, err := s.getScriptVersion(script.ID, script.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	run, _, err := s.startScriptRun(script, version, userID, []*DeployServer{server}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  &server.ID,
+		UserID:    userID,
+		Action:    "deploy_requested",
+		Metadata: map[string]any{
+			"host":   server.Host,
+			"run_id": run.ID,
+			"script": script.Name,
+			"source": "slash_command",
+		},
+		CreatedAt: time.Now(),
+	})
+
+	return run, nil
+}
+
+func (s *Service) RecordTerminalConnection(projectID, userID uuid.UUID, server *DeployServer) *DeployAuditEvent {
 	now := time.Now()
 	server.LastConnectedAt = &now
 	_ = s.repo.UpdateServer(server)
-	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+	event := &DeployAuditEvent{
 		ProjectID: projectID,
 		ServerID:  &server.ID,
 		UserID:    userID,
@@ -172,7 +440,304 @@ func (s *Service) RecordTerminalConnection(projectID, userID uuid.UUID, server *
 			"port": server.Port,
 		},
 		CreatedAt: now,
+	}
+	if err := s.repo.CreateAuditEvent(event); err != nil {
+		log.Printf("failed to audit terminal connection to %s: %v", server.ID, err)
+		event = nil
+	}
+
+	if s.projectEventHook != nil {
+		s.projectEventHook(projectID, project.EventDeployTerminalOpened, map[string]any{
+			"server_id":   server.ID,
+			"server_name": server.Name,
+			"host":        server.Host,
+			"user_id":     userID,
+		})
+	}
+
+	return event
+}
+
+// StartTerminalSession audits a terminal connection and starts recording it.
+// Without storage the session is still tracked, only the recording is skipped.
+func (s *Service) StartTerminalSession(projectID, userID uuid.UUID, server *DeployServer, cols, rows int) *TerminalSession {
+	event := s.RecordTerminalConnection(projectID, userID, server)
+
+	session := &TerminalSession{
+		ProjectID: projectID,
+		ServerID:  server.ID,
+		UserID:    userID,
+		Cols:      cols,
+		Rows:      rows,
+		StartedAt: time.Now(),
+	}
+	if event != nil {
+		session.AuditEventID = &event.ID
+	}
+
+	if s.recordings != nil && s.recordings.IsReady() {
+		title := fmt.Sprintf("%s (%s@%s)", server.Name, server.Username, server.Host)
+		recorder, err := newTerminalRecorder(session.StartedAt, cols, rows, title, s.recordInput)
+		if err != nil {
+			log.Printf("failed to start terminal recording: %v", err)
+		} else {
+			session.recorder = recorder
+			session.InputRecorded = s.recordInput
+		}
+	}
+
+	if err := s.repo.CreateTerminalSession(session); err != nil {
+		log.Printf("failed to create terminal session: %v", err)
+	}
+	return session
+}
+
+// FinishTerminalSession stores the recording and how the session ended
+func (s *Service) FinishTerminalSession(session *TerminalSession, exit TerminalEvent) {
+	now := time.Now()
+	duration := now.Sub(session.StartedAt).Milliseconds()
+	session.EndedAt = &now
+	session.DurationMs = &duration
+	session.ExitCode = exit.Code
+	if exit.Signal != "" {
+		session.ExitSignal = &exit.Signal
+	}
+
+	if recorder := session.recorder; recorder != nil {
+		defer recorder.discard()
+		key := fmt.Sprintf("deploy-recordings/%s/%s/%s.cast", session.ProjectID, session.ServerID, session.ID)
+		reader, size, err := recorder.finish()
+		if err == nil {
+			err = s.recordings.PutObject(context.Background(), key, reader, size, recordingContentType)
+		}
+		if err != nil {
+			log.Printf("failed to store terminal recording %s: %v", session.ID, err)
+		} else {
+			session.RecordingKey = &key
+			session.RecordingSize = size
+			session.Truncated = recorder.truncated
+		}
+	}
+
+	if session.ID != uuid.Nil {
+		if err := s.repo.UpdateTerminalSession(session); err != nil {
+			log.Printf("failed to update terminal session %s: %v", session.ID, err)
+		}
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: session.ProjectID,
+		ServerID:  &session.ServerID,
+		UserID:    session.UserID,
+		Action:    "terminal_disconnected",
+		Metadata: map[string]any{
+			"session_id":  session.ID,
+			"duration_ms": duration,
+			"exit_code":   session.ExitCode,
+			"exit_signal": session.ExitSignal,
+			"recorded":    session.RecordingKey != nil,
+		},
+		CreatedAt: now,
+	})
+}
+
+func (s *Service) ListTerminalSessions(projectID, serverID, userID uuid.UUID, limit int) ([]TerminalSession, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetServer(projectID, serverID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServerNotFound
+		}
+		return nil, err
+	}
+	return s.repo.ListTerminalSessions(serverID, limit)
+}
+
+// OpenRecording returns the asciicast recording of a session. Viewing is audited
+// as recordings show everything printed on the server.
+func (s *Service) OpenRecording(projectID, serverID, sessionID, userID uuid.UUID) (*TerminalSession, io.ReadCloser, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, nil, err
+	}
+	session, err := s.repo.GetTerminalSession(projectID, serverID, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrTerminalSessionNotFound
+		}
+		return nil, nil, err
+	}
+	if session.RecordingKey == nil {
+		return nil, nil, ErrRecordingNotFound
+	}
+	if s.recordings == nil || !s.recordings.IsReady() {
+		return nil, nil, ErrStorageUnavailable
+	}
+
+	recording, err := s.recordings.GetObject(context.Background(), *session.RecordingKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get recording: %w", err)
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  &serverID,
+		UserID:    userID,
+		Action:    "recording_viewed",
+		Metadata: map[string]any{
+			"session_id": session.ID,
+		},
+		CreatedAt: time.Now(),
+	})
+
+	return session, recording, nil
+}
+
+// memberName returns the display name of a project member for chat messages
+func (s *Service) memberName(projectID, userID uuid.UUID) string {
+	members, err := s.projectRepo.GetMembers(projectID)
+	if err == nil {
+		for _, member := range members {
+			if member.UserID == userID && member.User.Name != "" {
+				return member.User.Name
+			}
+		}
+	}
+	return "Someone"
+}
+
+// StartLiveTerminal registers an open shell so other admins can attach to it.
+// A shared terminal is announced in the project's deploy topic.
+func (s *Service) StartLiveTerminal(projectID, userID uuid.UUID, server *DeployServer, recording *TerminalSession, session *ssh.Session, stdin io.Writer, shared bool) *liveTerminal {
+	id := recording.ID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	live := &liveTerminal{
+		id:        id,
+		projectID: projectID,
+		server:    server,
+		ownerID:   userID,
+		shared:    shared,
+		startedAt: recording.StartedAt,
+		session:   session,
+		stdin:     stdin,
+		recorder:  recording.recorder,
+		writers:   make(map[uuid.UUID]bool),
+	}
+	s.terminals.add(live)
+	if !shared {
+		return live
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  &server.ID,
+		UserID:    userID,
+		Action:    "terminal_shared",
+		Metadata: map[string]any{
+			"session_id": live.id,
+		},
+		CreatedAt: time.Now(),
+	})
+
+	if s.systemMessageHook != nil {
+		content := fmt.Sprintf("%s started a shared terminal on %s", s.memberName(projectID, userID), server.Name)
+		s.systemMessageHook(projectID, "deploy", content, map[string]any{
+			"action":      "terminal_shared",
+			"session_id":  live.id,
+			"server_id":   server.ID,
+			"server_name": server.Name,
+			"attach_path": fmt.Sprintf("/api/projects/%s/deploy/sessions/%s/ws", projectID, live.id),
+		})
+	}
+	return live
+}
+
+// EndLiveTerminal unregisters a terminal once its shell has ended
+func (s *Service) EndLiveTerminal(live *liveTerminal) {
+	s.terminals.remove(live.id)
+	if !live.shared || s.systemMessageHook == nil {
+		return
+	}
+
+	duration := time.Since(live.startedAt).Round(time.Second)
+	content := fmt.Sprintf("Shared terminal on %s ended after %s", live.server.Name, duration)
+	s.systemMessageHook(live.projectID, "deploy", content, map[string]any{
+		"action":      "terminal_share_ended",
+		"session_id":  live.id,
+		"server_id":   live.server.ID,
+		"server_name": live.server.Name,
+		"duration_ms": duration.Milliseconds(),
+	})
+}
+
+// ListLiveTerminals returns the shared terminals admins of a project can attach to
+func (s *Service) ListLiveTerminals(projectID, userID uuid.UUID) ([]LiveTerminal, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	terminals := s.terminals.shared(projectID)
+	result := make([]LiveTerminal, 0, len(terminals))
+	for _, live := range terminals {
+		result = append(result, live.info())
+	}
+	return result, nil
+}
+
+// AttachTerminal checks that a user may watch a shared terminal and returns it.
+// Attaching is audited as viewers see everything printed on the server.
+func (s *Service) AttachTerminal(projectID, sessionID, userID uuid.UUID) (*liveTerminal, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireTwoFactor(projectID, userID); err != nil {
+		return nil, err
+	}
+	live, ok := s.terminals.get(sessionID)
+	if !ok || live.projectID != projectID || !live.shared {
+		return nil, ErrLiveTerminalNotFound
+	}
+
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  &live.server.ID,
+		UserID:    userID,
+		Action:    "terminal_attached",
+		Metadata: map[string]any{
+			"session_id": live.id,
+			"owner_id":   live.ownerID,
+		},
+		CreatedAt: time.Now(),
 	})
+	return live, nil
+}
+
+// SetTerminalWriter lets the owner of a shared terminal grant or revoke write access
+func (s *Service) SetTerminalWriter(live *liveTerminal, ownerID, userID uuid.UUID, write bool) error {
+	if ownerID != live.ownerID {
+		return ErrNotTerminalOwner
+	}
+	if err := live.setWriter(userID, write); err != nil {
+		return err
+	}
+
+	action := "terminal_write_granted"
+	if !write {
+		action = "terminal_write_revoked"
+	}
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: live.projectID,
+		ServerID:  &live.server.ID,
+		UserID:    ownerID,
+		Action:    action,
+		Metadata: map[string]any{
+			"session_id": live.id,
+			"target_id":  userID,
+		},
+		CreatedAt: time.Now(),
+	})
+	return nil
 }
 
 func (s *Service) DecryptPassword(server *DeployServer) (string, error) {
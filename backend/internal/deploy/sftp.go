@@ -0,0 +1,416 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxEditableFileSize is the largest file that can be opened in the editor
+const maxEditableFileSize = 1 << 20
+
+var (
+	ErrInvalidRemotePath      = errors.New("remote path must be absolute")
+	ErrRemoteFileNotFound     = errors.New("remote file not found")
+	ErrRemotePermissionDenied = errors.New("permission denied on the server")
+	ErrRemoteIsDirectory      = errors.New("remote path is a directory")
+	ErrRemoteNotDirectory     = errors.New("remote path is not a directory")
+	ErrRemoteFileTooLarge     = errors.New("remote file is too large to edit")
+	ErrRemoteFileNotText      = errors.New("remote file is not a text file")
+	ErrRemoteFileModified     = errors.New("remote file was changed since it was read")
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+)
+
+// AttachmentLookupFunc finds a file posted in a project's chat that userID can read
+type AttachmentLookupFunc func(projectID, messageID, userID uuid.UUID) (*Attachment, error)
+
+// SetAttachmentLookup lets chat attachments be copied to servers (called from main.go)
+func (s *Service) SetAttachmentLookup(lookup AttachmentLookupFunc) {
+	s.attachmentLookup = lookup
+}
+
+// sftpConn is an SFTP session over its own SSH connection
+type sftpConn struct {
+	*sftp.Client
+	ssh *ssh.Client
+}
+
+func (c *sftpConn) Close() error {
+	err := c.Client.Close()
+	_ = c.ssh.Close()
+	return err
+}
+
+// remoteFileReader closes the connection together with the file
+type remoteFileReader struct {
+	*sftp.File
+	conn *sftpConn
+}
+
+func (r *remoteFileReader) Close() error {
+	err := r.File.Close()
+	_ = r.conn.Close()
+	return err
+}
+
+// openSFTP connects to a server with the same credentials and host key pinning
+// as the terminal. Browsing files gives the same access as a shell, so it is
+// limited to the users who may open one.
+func (s *Service) openSFTP(projectID, serverID, userID uuid.UUID) (*DeployServer, *sftpConn, error) {
+	server, err := s.GetServerForTerminal(projectID, serverID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := s.dial(server, s.HostKeyCallback(projectID, userID, server))
+	if err != nil {
+		return nil, nil, err
+	}
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+	return server, &sftpConn{Client: sftpClient, ssh: client}, nil
+}
+
+// cleanRemotePath accepts absolute paths only, an empty path is the login directory
+func cleanRemotePath(conn *sftpConn, remotePath string) (string, error) {
+	if remotePath == "" {
+		return conn.Getwd()
+	}
+	if !strings.HasPrefix(remotePath, "/") || strings.ContainsRune(remotePath, 0) {
+		return "", ErrInvalidRemotePath
+	}
+	return path.Clean(remotePath), nil
+}
+
+// remoteError maps SFTP status errors to the errors of this package
+func remoteError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrRemoteFileNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrRemotePermissionDenied
+	default:
+		return err
+	}
+}
+
+func remoteFile(dir string, info os.FileInfo) RemoteFile {
+	return RemoteFile{
+		Name:    info.Name(),
+		Path:    path.Join(dir, info.Name()),
+		Size:    info.Size(),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+		IsLink:  info.Mode()&os.ModeSymlink != 0,
+	}
+}
+
+func (s *Service) auditFile(projectID, userID uuid.UUID, server *DeployServer, action string, metadata map[string]any) {
+	_ = s.repo.CreateAuditEvent(&DeployAuditEvent{
+		ProjectID: projectID,
+		ServerID:  &server.ID,
+		UserID:    userID,
+		Action:    action,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	})
+}
+
+// ListRemoteDir lists a directory on a server, directories first
+func (s *Service) ListRemoteDir(projectID, serverID, userID uuid.UUID, remotePath string) (string, []RemoteFile, error) {
+	server, conn, err := s.openSFTP(projectID, serverID, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer conn.Close()
+
+	dir, err := cleanRemotePath(conn, remotePath)
+	if err != nil {
+		return "", nil, err
+	}
+	infos, err := conn.ReadDir(dir)
+	if err != nil {
+		if info, statErr := conn.Stat(dir); statErr == nil && !info.IsDir() {
+			return "", nil, ErrRemoteNotDirectory
+		}
+		return "", nil, remoteError(err)
+	}
+
+	files := make([]RemoteFile, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, remoteFile(dir, info))
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	s.auditFile(projectID, userID, server, "file_listed", map[string]any{"path": dir})
+	return dir, files, nil
+}
+
+// OpenRemoteFile opens a file for download, closing the reader closes the connection
+func (s *Service) OpenRemoteFile(projectID, serverID, userID uuid.UUID, remotePath string) (*RemoteFile, io.ReadCloser, error) {
+	server, conn, err := s.openSFTP(projectID, serverID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filePath, err := cleanRemotePath(conn, remotePath)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	file, err := conn.Open(filePath)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, remoteError(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		_ = conn.Close()
+		return nil, nil, remoteError(err)
+	}
+	if info.IsDir() {
+		_ = file.Close()
+		_ = conn.Close()
+		return nil, nil, ErrRemoteIsDirectory
+	}
+
+	entry := remoteFile(path.Dir(filePath), info)
+	s.auditFile(projectID, userID, server, "file_downloaded", map[string]any{
+		"path": filePath,
+		"size": info.Size(),
+	})
+	return &entry, &remoteFileReader{File: file, conn: conn}, nil
+}
+
+// writeRemoteFile replaces the content of a file, or creates it. A path that is
+// a directory receives the file under filename.
+func writeRemoteFile(conn *sftpConn, filePath, filename string, content io.Reader) (*RemoteFile, error) {
+	if info, err := conn.Stat(filePath); err == nil && info.IsDir() {
+		if filename == "" || strings.ContainsAny(filename, "/\x00") || filename == "." || filename == ".." {
+			return nil, ErrRemoteIsDirectory
+		}
+		filePath = path.Join(filePath, filename)
+	}
+
+	file, err := conn.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+	if _, err := file.ReadFrom(content); err != nil {
+		_ = file.Close()
+		return nil, remoteError(err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, remoteError(err)
+	}
+
+	info, err := conn.Stat(filePath)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+	entry := remoteFile(path.Dir(filePath), info)
+	return &entry, nil
+}
+
+// UploadRemoteFile writes a file sent from the browser to a server
+func (s *Service) UploadRemoteFile(projectID, serverID, userID uuid.UUID, remotePath, filename string, content io.Reader) (*RemoteFile, error) {
+	server, conn, err := s.openSFTP(projectID, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filePath, err := cleanRemotePath(conn, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := writeRemoteFile(conn, filePath, filename, content)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditFile(projectID, userID, server, "file_uploaded", map[string]any{
+		"path":   entry.Path,
+		"size":   entry.Size,
+		"source": "browser",
+	})
+	return entry, nil
+}
+
+// TransferAttachment copies a file posted in the project's chat from storage to a server
+func (s *Service) TransferAttachment(projectID, serverID, userID uuid.UUID, req TransferAttachmentRequest) (*RemoteFile, error) {
+	if s.attachmentLookup == nil {
+		return nil, ErrAttachmentNotFound
+	}
+	if s.recordings == nil || !s.recordings.IsReady() {
+		return nil, ErrStorageUnavailable
+	}
+
+	server, conn, err := s.openSFTP(projectID, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filePath, err := cleanRemotePath(conn, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := s.attachmentLookup(projectID, req.MessageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	object, err := s.recordings.GetObject(context.Background(), attachment.StorageKey)
+	if err != nil {
+		return nil, ErrStorageUnavailable
+	}
+	defer object.Close()
+
+	entry, err := writeRemoteFile(conn, filePath, attachment.Filename, object)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditFile(projectID, userID, server, "file_uploaded", map[string]any{
+		"path":       entry.Path,
+		"size":       entry.Size,
+		"source":     "attachment",
+		"message_id": attachment.MessageID,
+		"filename":   attachment.Filename,
+	})
+	return entry, nil
+}
+
+// ReadRemoteTextFile opens a small text file for editing
+func (s *Service) ReadRemoteTextFile(projectID, serverID, userID uuid.UUID, remotePath string) (*RemoteFileContent, error) {
+	server, conn, err := s.openSFTP(projectID, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filePath, err := cleanRemotePath(conn, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := conn.Open(filePath)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, remoteError(err)
+	}
+	if info.IsDir() {
+		return nil, ErrRemoteIsDirectory
+	}
+	if info.Size() > maxEditableFileSize {
+		return nil, ErrRemoteFileTooLarge
+	}
+	// Read one byte past the limit in case the file grew after the stat
+	content, err := io.ReadAll(io.LimitReader(file, maxEditableFileSize+1))
+	if err != nil {
+		return nil, remoteError(err)
+	}
+	if len(content) > maxEditableFileSize {
+		return nil, ErrRemoteFileTooLarge
+	}
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return nil, ErrRemoteFileNotText
+	}
+
+	s.auditFile(projectID, userID, server, "file_opened", map[string]any{
+		"path": filePath,
+		"size": len(content),
+	})
+	return &RemoteFileContent{
+		Path:    filePath,
+		Content: string(content),
+		Size:    int64(len(content)),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// WriteRemoteTextFile saves an edited file. The save is refused when the file's
+// modification time no longer matches the one it had when it was read.
+func (s *Service) WriteRemoteTextFile(projectID, serverID, userID uuid.UUID, remotePath string, req WriteRemoteFileRequest) (*RemoteFileContent, error) {
+	if len(req.Content) > maxEditableFileSize {
+		return nil, ErrRemoteFileTooLarge
+	}
+	if !utf8.ValidString(req.Content) || strings.IndexByte(req.Content, 0) >= 0 {
+		return nil, ErrRemoteFileNotText
+	}
+
+	server, conn, err := s.openSFTP(projectID, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filePath, err := cleanRemotePath(conn, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := conn.Stat(filePath)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return nil, ErrRemoteIsDirectory
+		}
+		// SFTP reports modification times in whole seconds
+		if req.ModTime == nil || info.ModTime().Unix() != req.ModTime.Unix() {
+			return nil, ErrRemoteFileModified
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		if req.ModTime != nil {
+			return nil, ErrRemoteFileModified
+		}
+	default:
+		return nil, remoteError(err)
+	}
+
+	entry, err := writeRemoteFile(conn, filePath, "", strings.NewReader(req.Content))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]any{
+		"path": entry.Path,
+		"size": entry.Size,
+	}
+	if req.ModTime != nil {
+		metadata["previous_mod_time"] = req.ModTime.UTC()
+	}
+	s.auditFile(projectID, userID, server, "file_edited", metadata)
+	return &RemoteFileContent{
+		Path:    entry.Path,
+		Content: req.Content,
+		Size:    entry.Size,
+		ModTime: entry.ModTime,
+	}, nil
+}
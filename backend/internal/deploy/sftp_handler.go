@@ -0,0 +1,213 @@
+package deploy
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+func respondFileError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrNotProjectMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+	case errors.Is(err, ErrNotProjectAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	case errors.Is(err, ErrTwoFactorRequired):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication required"})
+	case errors.Is(err, ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Server not found"})
+	case errors.Is(err, ErrHostKeyMismatch), errors.Is(err, ErrHostKeyNotPinned):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error() + ", an admin must approve the new key"})
+	case errors.Is(err, ErrInvalidRemotePath):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Path must be absolute"})
+	case errors.Is(err, ErrRemoteFileNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "File not found on the server"})
+	case errors.Is(err, ErrRemotePermissionDenied):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Permission denied on the server"})
+	case errors.Is(err, ErrRemoteIsDirectory):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Path is a directory"})
+	case errors.Is(err, ErrRemoteNotDirectory):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Path is not a directory"})
+	case errors.Is(err, ErrRemoteFileTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "File is too large to edit, download it instead"})
+	case errors.Is(err, ErrRemoteFileNotText):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "File is not a text file"})
+	case errors.Is(err, ErrRemoteFileModified):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "File was changed on the server since it was opened, reload it"})
+	case errors.Is(err, ErrAttachmentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attachment not found"})
+	case errors.Is(err, ErrStorageUnavailable):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Storage is unavailable, please try again later"})
+	default:
+		log.Printf("deploy file operation failed: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "File operation on the server failed"})
+	}
+}
+
+// GET /api/projects/:projectId/deploy/servers/:serverId/files?path=
+func (h *Handler) ListRemoteFiles(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	dir, files, err := h.service.ListRemoteDir(projectID, serverID, userID, c.Query("path"))
+	if err != nil {
+		return respondFileError(c, err)
+	}
+	return c.JSON(fiber.Map{"path": dir, "files": files})
+}
+
+// GET /api/projects/:projectId/deploy/servers/:serverId/files/download?path=
+func (h *Handler) DownloadRemoteFile(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	file, content, err := h.service.OpenRemoteFile(projectID, serverID, userID, c.Query("path"))
+	if err != nil {
+		return respondFileError(c, err)
+	}
+
+	c.Attachment(file.Name)
+	c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	// The stream is closed, and the connection with it, once the body is sent
+	return c.SendStream(content, int(file.Size))
+}
+
+// Upload a file from the browser, path is the target file or a directory
+// POST /api/projects/:projectId/deploy/servers/:serverId/files/upload?path=
+func (h *Handler) UploadRemoteFile(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No file uploaded"})
+	}
+	content, err := header.Open()
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	defer content.Close()
+
+	file, err := h.service.UploadRemoteFile(projectID, serverID, userID, c.Query("path"), header.Filename, content)
+	if err != nil {
+		return respondFileError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(file)
+}
+
+// Copy a file posted in chat to the server, for files larger than a browser upload
+// POST /api/projects/:projectId/deploy/servers/:serverId/files/transfer
+func (h *Handler) TransferAttachment(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req TransferAttachmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	file, err := h.service.TransferAttachment(projectID, serverID, userID, req)
+	if err != nil {
+		return respondFileError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(file)
+}
+
+// GET /api/projects/:projectId/deploy/servers/:serverId/files/content?path=
+func (h *Handler) GetRemoteFileContent(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	content, err := h.service.ReadRemoteTextFile(projectID, serverID, userID, c.Query("path"))
+	if err != nil {
+		return respondFileError(c, err)
+	}
+	return c.JSON(content)
+}
+
+// Save an edited file, mod_time must match the one returned when it was read
+// PUT /api/projects/:projectId/deploy/servers/:serverId/files/content?path=
+func (h *Handler) UpdateRemoteFileContent(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Locals("userID").(string))
+	if err != nil {
+		return fiber.ErrUnauthorized
+	}
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+	serverID, err := uuid.Parse(c.Params("serverId"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var req WriteRemoteFileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrBadRequest
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+
+	content, err := h.service.WriteRemoteTextFile(projectID, serverID, userID, c.Query("path"), req)
+	if err != nil {
+		return respondFileError(c, err)
+	}
+	return c.JSON(content)
+}
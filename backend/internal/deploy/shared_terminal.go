@@ -0,0 +1,274 @@
+package deploy
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// Roles of the sockets attached to a live terminal
+const (
+	TerminalRoleOwner  = "owner"
+	TerminalRoleWriter = "writer"
+	TerminalRoleViewer = "viewer"
+)
+
+var (
+	ErrLiveTerminalNotFound = errors.New("live terminal not found")
+	ErrTerminalReadOnly     = errors.New("terminal is read-only for this user")
+	ErrNotTerminalOwner     = errors.New("only the owner can do this")
+	ErrNotAttached          = errors.New("user is not attached to the terminal")
+)
+
+// TerminalParticipant is a user attached to a live terminal
+type TerminalParticipant struct {
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	Role   string    `json:"role"`
+}
+
+// LiveTerminal describes a running shared terminal
+type LiveTerminal struct {
+	SessionID    uuid.UUID             `json:"session_id"`
+	ServerID     uuid.UUID             `json:"server_id"`
+	ServerName   string                `json:"server_name"`
+	OwnerID      uuid.UUID             `json:"owner_id"`
+	StartedAt    time.Time             `json:"started_at"`
+	Participants []TerminalParticipant `json:"participants"`
+}
+
+type terminalParticipant struct {
+	conn   *terminalConn
+	userID uuid.UUID
+	name   string
+}
+
+// liveTerminal is a shell and the sockets attached to it. The owner opened the
+// shell, when it is shared other project admins attach as viewers and may be
+// granted write access. Output is broadcast to every socket.
+type liveTerminal struct {
+	id        uuid.UUID
+	projectID uuid.UUID
+	server    *DeployServer
+	ownerID   uuid.UUID
+	shared    bool
+	startedAt time.Time
+	session   *ssh.Session
+	stdin     io.Writer
+	recorder  *terminalRecorder
+
+	mu           sync.Mutex
+	participants []*terminalParticipant
+	writers      map[uuid.UUID]bool
+	ended        bool
+}
+
+func (l *liveTerminal) roleLocked(userID uuid.UUID) string {
+	switch {
+	case userID == l.ownerID:
+		return TerminalRoleOwner
+	case l.writers[userID]:
+		return TerminalRoleWriter
+	default:
+		return TerminalRoleViewer
+	}
+}
+
+func (l *liveTerminal) canWrite(userID uuid.UUID) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.roleLocked(userID) != TerminalRoleViewer
+}
+
+func (l *liveTerminal) participantsLocked() []TerminalParticipant {
+	seen := make(map[uuid.UUID]bool, len(l.participants))
+	participants := make([]TerminalParticipant, 0, len(l.participants))
+	for _, p := range l.participants {
+		if seen[p.userID] {
+			continue
+		}
+		seen[p.userID] = true
+		participants = append(participants, TerminalParticipant{
+			UserID: p.userID,
+			Name:   p.name,
+			Role:   l.roleLocked(p.userID),
+		})
+	}
+	return participants
+}
+
+func (l *liveTerminal) info() LiveTerminal {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LiveTerminal{
+		SessionID:    l.id,
+		ServerID:     l.server.ID,
+		ServerName:   l.server.Name,
+		OwnerID:      l.ownerID,
+		StartedAt:    l.startedAt,
+		Participants: l.participantsLocked(),
+	}
+}
+
+// join attaches a socket, tells it its role and announces it to everyone
+func (l *liveTerminal) join(p *terminalParticipant) error {
+	l.mu.Lock()
+	if l.ended {
+		l.mu.Unlock()
+		return ErrLiveTerminalNotFound
+	}
+	l.participants = append(l.participants, p)
+	id := l.id
+	_ = p.conn.writeEvent(TerminalEvent{
+		Type:      TerminalMessageSession,
+		SessionID: &id,
+		Role:      l.roleLocked(p.userID),
+		Shared:    l.shared,
+	})
+	l.broadcastPresenceLocked()
+	l.mu.Unlock()
+	return nil
+}
+
+// leave detaches a socket. Once it returns nothing is written to the socket anymore.
+func (l *liveTerminal) leave(p *terminalParticipant) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, existing := range l.participants {
+		if existing == p {
+			l.participants = append(l.participants[:i], l.participants[i+1:]...)
+			break
+		}
+	}
+	if !l.ended {
+		l.broadcastPresenceLocked()
+	}
+}
+
+// setWriter grants or revokes write access of an attached user
+func (l *liveTerminal) setWriter(userID uuid.UUID, write bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if userID == l.ownerID {
+		return ErrNotTerminalOwner
+	}
+	attached := false
+	for _, p := range l.participants {
+		if p.userID == userID {
+			attached = true
+			break
+		}
+	}
+	if !attached {
+		return ErrNotAttached
+	}
+
+	if write {
+		l.writers[userID] = true
+	} else {
+		delete(l.writers, userID)
+	}
+	for _, p := range l.participants {
+		if p.userID == userID {
+			_ = p.conn.writeEvent(TerminalEvent{Type: TerminalMessageSession, SessionID: &l.id, Role: l.roleLocked(userID), Shared: l.shared})
+		}
+	}
+	l.broadcastPresenceLocked()
+	return nil
+}
+
+func (l *liveTerminal) broadcastPresenceLocked() {
+	l.broadcastLocked(TerminalEvent{Type: TerminalMessagePresence, Participants: l.participantsLocked()})
+}
+
+func (l *liveTerminal) broadcastLocked(event TerminalEvent) {
+	for _, p := range l.participants {
+		_ = p.conn.writeEvent(event)
+	}
+}
+
+func (l *liveTerminal) broadcast(event TerminalEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.broadcastLocked(event)
+}
+
+// output records shell output and sends it to every socket
+func (l *liveTerminal) output(data []byte) {
+	l.recorder.Output(data)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.participants {
+		_ = p.conn.writeOutput(data)
+	}
+}
+
+// input writes keystrokes of a user with write access to the shell
+func (l *liveTerminal) input(userID uuid.UUID, data []byte) error {
+	if !l.canWrite(userID) {
+		return ErrTerminalReadOnly
+	}
+	l.recorder.Input(data)
+	_, err := l.stdin.Write(data)
+	return err
+}
+
+// end reports the exit status to every socket and closes them
+func (l *liveTerminal) end(exit TerminalEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ended = true
+	for _, p := range l.participants {
+		_ = p.conn.writeEvent(exit)
+		p.conn.close(websocket.CloseNormalClosure, "session ended")
+		_ = p.conn.conn.SetReadDeadline(time.Now().Add(terminalCloseTimeout))
+	}
+}
+
+// terminalRegistry holds the live terminals of this API instance. A terminal
+// lives where its SSH connection is, viewers must reach the same instance.
+type terminalRegistry struct {
+	mu        sync.Mutex
+	terminals map[uuid.UUID]*liveTerminal
+}
+
+func newTerminalRegistry() *terminalRegistry {
+	return &terminalRegistry{terminals: make(map[uuid.UUID]*liveTerminal)}
+}
+
+func (r *terminalRegistry) add(live *liveTerminal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.terminals[live.id] = live
+}
+
+func (r *terminalRegistry) remove(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.terminals, id)
+}
+
+func (r *terminalRegistry) get(id uuid.UUID) (*liveTerminal, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	live, ok := r.terminals[id]
+	return live, ok
+}
+
+// shared returns the shared terminals of a project
+func (r *terminalRegistry) shared(projectID uuid.UUID) []*liveTerminal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	terminals := make([]*liveTerminal, 0)
+	for _, live := range r.terminals {
+		if live.projectID == projectID && live.shared {
+			terminals = append(terminals, live)
+		}
+	}
+	return terminals
+}
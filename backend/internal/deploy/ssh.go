@@ -0,0 +1,50 @@
+package deploy
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const sshDialTimeout = 10 * time.Second
+
+// authMethod builds the SSH credentials stored for a server
+func (s *Service) authMethod(server *DeployServer) (ssh.AuthMethod, error) {
+	switch server.AuthType {
+	case "password":
+		password, err := s.DecryptPassword(server)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.Password(password), nil
+	case "key":
+		key, err := s.DecryptPrivateKey(server)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		return ssh.PublicKeys(signer), nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type")
+	}
+}
+
+// dial connects to a server with its stored credentials
+func (s *Service) dial(server *DeployServer, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	auth, err := s.authMethod(server)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ClientConfig{
+		User:            server.Username,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}
+	return ssh.Dial("tcp", fmt.Sprintf("%s:%d", server.Host, server.Port), config)
+}
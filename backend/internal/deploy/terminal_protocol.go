@@ -0,0 +1,175 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// Deploy terminal protocol.
+//
+// Binary frames carry raw terminal bytes in both directions: keystrokes from
+// the browser, shell output from the server. Text frames carry JSON control
+// messages with a "type" field:
+//
+//	client -> server  {"type":"input","data":"ls\n"}   keystrokes, for clients that cannot send binary
+//	                  {"type":"resize","cols":120,"rows":40}
+//	                  {"type":"signal","signal":"INT"}
+//	                  {"type":"ping"}
+//	                  {"type":"grant","user_id":"..."}  owner of a shared terminal, lets a viewer type
+//	                  {"type":"revoke","user_id":"..."}
+//	server -> client  {"type":"session","session_id":"...","role":"viewer","shared":true}  sent on attach and role changes
+//	                  {"type":"presence","participants":[...]}
+//	                  {"type":"resize","cols":120,"rows":40}  the owner resized a shared terminal
+//	                  {"type":"pong"}
+//	                  {"type":"error","message":"..."}
+//	                  {"type":"exit","code":0}          the remote shell ended, the socket closes next
+//
+// The initial terminal size comes from the cols and rows query parameters.
+// Viewers of a shared terminal receive output and events but their input is rejected.
+const (
+	TerminalMessageInput  = "input"
+	TerminalMessageResize = "resize"
+	TerminalMessageSignal = "signal"
+	TerminalMessagePing   = "ping"
+	TerminalMessagePong   = "pong"
+	TerminalMessageError  = "error"
+	TerminalMessageExit   = "exit"
+
+	TerminalMessageSession  = "session"
+	TerminalMessagePresence = "presence"
+	TerminalMessageGrant    = "grant"
+	TerminalMessageRevoke   = "revoke"
+)
+
+const (
+	defaultTerminalCols = 120
+	defaultTerminalRows = 40
+	maxTerminalSize     = 1000
+	// terminalWriteTimeout keeps a stalled browser from blocking the shell output
+	terminalWriteTimeout = 10 * time.Second
+	// terminalCloseTimeout bounds the wait for the browser to answer a close frame
+	terminalCloseTimeout = 5 * time.Second
+)
+
+var ErrInvalidTerminalSize = errors.New("invalid terminal size")
+
+// terminalSignals are the signals a client may send to the remote shell
+var terminalSignals = map[string]ssh.Signal{
+	"INT":  ssh.SIGINT,
+	"TERM": ssh.SIGTERM,
+	"HUP":  ssh.SIGHUP,
+	"QUIT": ssh.SIGQUIT,
+	"KILL": ssh.SIGKILL,
+	"USR1": ssh.SIGUSR1,
+	"USR2": ssh.SIGUSR2,
+}
+
+// TerminalControl is a control message from the client
+type TerminalControl struct {
+	Type   string `json:"type"`
+	Data   string `json:"data,omitempty"`
+	Cols   int    `json:"cols,omitempty"`
+	Rows   int    `json:"rows,omitempty"`
+	Signal string `json:"signal,omitempty"`
+	// UserID is the participant a grant or revoke applies to
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// TerminalEvent is a control message to the client
+type TerminalEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+	// Code is the exit status of the shell, nil when it ended without one
+	Code *int `json:"code,omitempty"`
+	// Signal is set when the shell was killed by a signal
+	Signal string `json:"signal,omitempty"`
+
+	SessionID    *uuid.UUID            `json:"session_id,omitempty"`
+	Role         string                `json:"role,omitempty"`
+	Shared       bool                  `json:"shared,omitempty"`
+	Participants []TerminalParticipant `json:"participants,omitempty"`
+	Cols         int                   `json:"cols,omitempty"`
+	Rows         int                   `json:"rows,omitempty"`
+}
+
+func validTerminalSize(cols, rows int) bool {
+	return cols > 0 && rows > 0 && cols <= maxTerminalSize && rows <= maxTerminalSize
+}
+
+// parseTerminalSize reads the initial size from query values, falling back to the defaults
+func parseTerminalSize(colsStr, rowsStr string) (int, int) {
+	cols, err := strconv.Atoi(colsStr)
+	if err != nil {
+		return defaultTerminalCols, defaultTerminalRows
+	}
+	rows, err := strconv.Atoi(rowsStr)
+	if err != nil || !validTerminalSize(cols, rows) {
+		return defaultTerminalCols, defaultTerminalRows
+	}
+	return cols, rows
+}
+
+// exitEvent describes how the remote shell ended
+func exitEvent(err error) TerminalEvent {
+	event := TerminalEvent{Type: TerminalMessageExit}
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		code := 0
+		event.Code = &code
+	case errors.As(err, &exitErr):
+		if exitErr.Signal() != "" {
+			event.Signal = exitErr.Signal()
+		} else {
+			code := exitErr.ExitStatus()
+			event.Code = &code
+		}
+	default:
+		var missing *ssh.ExitMissingError
+		if !errors.As(err, &missing) {
+			event.Message = err.Error()
+		}
+	}
+	return event
+}
+
+// terminalConn serializes writes to the browser, output and control messages
+// are sent from several goroutines
+type terminalConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (t *terminalConn) write(messageType int, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_ = t.conn.SetWriteDeadline(time.Now().Add(terminalWriteTimeout))
+	return t.conn.WriteMessage(messageType, data)
+}
+
+func (t *terminalConn) writeOutput(data []byte) error {
+	return t.write(websocket.BinaryMessage, data)
+}
+
+func (t *terminalConn) writeEvent(event TerminalEvent) error {
+	return t.writeJSON(event)
+}
+
+func (t *terminalConn) writeJSON(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.write(websocket.TextMessage, payload)
+}
+
+func (t *terminalConn) close(code int, reason string) {
+	_ = t.write(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
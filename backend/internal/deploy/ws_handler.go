@@ -1,10 +1,11 @@
 package deploy
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
 	"io"
 	"log"
-	"time"
+	"sync"
 
 	"github.com/gofiber/websocket/v2"
 	"github.com/google/uuid"
@@ -45,74 +46,264 @@ func (h *WSHandler) HandleTerminal(c *websocket.Conn) {
 
 	server, err := h.service.GetServerForTerminal(projectID, serverID, userID)
 	if err != nil {
+		if errors.Is(err, ErrTwoFactorRequired) {
+			_ = c.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "two-factor authentication required"))
+		}
 		_ = c.Close()
 		return
 	}
 
-	client, session, stdin, stdout, stderr, err := h.openSession(server)
+	cols, rows := parseTerminalSize(c.Query("cols"), c.Query("rows"))
+	client, session, stdin, stdout, stderr, err := h.openSession(server, h.service.HostKeyCallback(projectID, userID, server), cols, rows)
 	if err != nil {
 		log.Printf("terminal connect failed: %v", err)
+		if errors.Is(err, ErrHostKeyMismatch) || errors.Is(err, ErrHostKeyNotPinned) {
+			_ = c.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()+", an admin must approve the new key"))
+		}
 		_ = c.Close()
 		return
 	}
+
+	recording := h.service.StartTerminalSession(projectID, userID, server, cols, rows)
+	live := h.service.StartLiveTerminal(projectID, userID, server, recording, session, stdin, c.Query("shared") == "true")
+	owner := &terminalParticipant{
+		conn:   &terminalConn{conn: c},
+		userID: userID,
+		name:   h.service.memberName(projectID, userID),
+	}
+	_ = live.join(owner)
+
+	var output sync.WaitGroup
+	output.Add(2)
+	go streamOutput(live, stdout, &output)
+	go streamOutput(live, stderr, &output)
+
+	// Report the exit status to every socket once the shell ends and its output
+	// is flushed. The connection goes back to Fiber's pool when this handler
+	// returns, so the handler waits for this goroutine.
+	exited := make(chan struct{})
+	var exit TerminalEvent
+	go func() {
+		defer close(exited)
+		output.Wait()
+		exit = exitEvent(session.Wait())
+		live.end(exit)
+	}()
+
+	// The shell belongs to the owner, it ends for everyone when they leave
 	defer func() {
+		live.leave(owner)
 		_ = session.Close()
 		_ = client.Close()
+		<-exited
 		_ = c.Close()
+		h.service.EndLiveTerminal(live)
+		h.service.FinishTerminalSession(recording, exit)
 	}()
 
-	h.service.RecordTerminalConnection(projectID, userID, server)
+	h.readTerminal(live, owner)
+}
 
-	go streamOutput(c, stdout)
-	go streamOutput(c, stderr)
+// GET /api/projects/:projectId/deploy/sessions/:sessionId/ws
+func (h *WSHandler) HandleAttach(c *websocket.Conn) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		_ = c.Close()
+		return
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+
+	live, err := h.service.AttachTerminal(projectID, sessionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTwoFactorRequired):
+			_ = c.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "two-factor authentication required"))
+		case errors.Is(err, ErrLiveTerminalNotFound):
+			_ = c.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"))
+		}
+		_ = c.Close()
+		return
+	}
+
+	viewer := &terminalParticipant{
+		conn:   &terminalConn{conn: c},
+		userID: userID,
+		name:   h.service.memberName(projectID, userID),
+	}
+	if err := live.join(viewer); err != nil {
+		_ = c.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"))
+		_ = c.Close()
+		return
+	}
+	defer func() {
+		live.leave(viewer)
+		_ = c.Close()
+	}()
+
+	h.readTerminal(live, viewer)
+}
+
+// GET /api/projects/:projectId/deploy/runs/:runId/ws
+func (h *WSHandler) HandleRun(c *websocket.Conn) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		_ = c.Close()
+		return
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+
+	projectID, err := uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+
+	live, err := h.service.WatchRun(projectID, runID, userID)
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+
+	watcher := &terminalConn{conn: c}
+	if live == nil || !live.watch(watcher) {
+		// The run is over, or runs on another instance: send what is stored
+		if run, err := h.service.GetScriptRun(projectID, runID, userID); err == nil {
+			response := run.ToResponse()
+			_ = watcher.writeJSON(ScriptRunEvent{Type: RunEventRun, Run: &response})
+		}
+		watcher.close(websocket.CloseNormalClosure, "run finished")
+		_ = c.Close()
+		return
+	}
+	defer func() {
+		live.unwatch(watcher)
+		_ = c.Close()
+	}()
 
 	for {
 		_, msg, err := c.ReadMessage()
 		if err != nil {
-			break
+			return
+		}
+		var control TerminalControl
+		if err := json.Unmarshal(msg, &control); err == nil && control.Type == TerminalMessagePing {
+			_ = watcher.writeEvent(TerminalEvent{Type: TerminalMessagePong})
 		}
-		if len(msg) == 0 {
+	}
+}
+
+// readTerminal handles the messages of one socket until it closes or the shell
+// stops accepting input
+func (h *WSHandler) readTerminal(live *liveTerminal, p *terminalParticipant) {
+	for {
+		messageType, msg, err := p.conn.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType == websocket.BinaryMessage {
+			if err := h.writeInput(live, p, msg); err != nil {
+				return
+			}
+			continue
+		}
+
+		var control TerminalControl
+		if err := json.Unmarshal(msg, &control); err != nil {
+			_ = p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: "invalid control message"})
 			continue
 		}
-		if _, err := stdin.Write(msg); err != nil {
-			break
+		if err := h.handleTerminalControl(live, p, control); err != nil {
+			return
 		}
 	}
 }
 
-func (h *WSHandler) openSession(server *DeployServer) (*ssh.Client, *ssh.Session, io.WriteCloser, io.Reader, io.Reader, error) {
-	address := fmt.Sprintf("%s:%d", server.Host, server.Port)
-	var auth ssh.AuthMethod
+// writeInput passes keystrokes to the shell. Input of viewers is rejected
+// with an error event, only a failed write to the shell is returned.
+func (h *WSHandler) writeInput(live *liveTerminal, p *terminalParticipant, data []byte) error {
+	err := live.input(p.userID, data)
+	if errors.Is(err, ErrTerminalReadOnly) {
+		return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: err.Error()})
+	}
+	return err
+}
 
-	switch server.AuthType {
-	case "password":
-		password, err := h.service.DecryptPassword(server)
-		if err != nil {
-			return nil, nil, nil, nil, nil, err
+// handleTerminalControl applies a control message. Only a failed write to the
+// shell or the socket is returned, other problems are reported to the client.
+func (h *WSHandler) handleTerminalControl(live *liveTerminal, p *terminalParticipant, control TerminalControl) error {
+	switch control.Type {
+	case TerminalMessageInput:
+		return h.writeInput(live, p, []byte(control.Data))
+	case TerminalMessageResize:
+		// The pty has one size, the owner's browser decides it
+		if p.userID != live.ownerID {
+			return nil
 		}
-		auth = ssh.Password(password)
-	case "key":
-		key, err := h.service.DecryptPrivateKey(server)
-		if err != nil {
-			return nil, nil, nil, nil, nil, err
+		if !validTerminalSize(control.Cols, control.Rows) {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: ErrInvalidTerminalSize.Error()})
 		}
-		signer, err := ssh.ParsePrivateKey([]byte(key))
-		if err != nil {
-			return nil, nil, nil, nil, nil, err
+		if err := live.session.WindowChange(control.Rows, control.Cols); err != nil {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: "failed to resize terminal"})
+		}
+		live.recorder.Resize(control.Cols, control.Rows)
+		live.broadcast(TerminalEvent{Type: TerminalMessageResize, Cols: control.Cols, Rows: control.Rows})
+	case TerminalMessageSignal:
+		if !live.canWrite(p.userID) {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: ErrTerminalReadOnly.Error()})
+		}
+		signal, ok := terminalSignals[control.Signal]
+		if !ok {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: "unsupported signal"})
 		}
-		auth = ssh.PublicKeys(signer)
+		if err := live.session.Signal(signal); err != nil {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: "failed to send signal"})
+		}
+	case TerminalMessageGrant, TerminalMessageRevoke:
+		if control.UserID == nil {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: "user_id is required"})
+		}
+		if err := h.service.SetTerminalWriter(live, p.userID, *control.UserID, control.Type == TerminalMessageGrant); err != nil {
+			return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: err.Error()})
+		}
+	case TerminalMessagePing:
+		return p.conn.writeEvent(TerminalEvent{Type: TerminalMessagePong})
 	default:
-		return nil, nil, nil, nil, nil, fmt.Errorf("unsupported auth type")
-	}
-
-	config := &ssh.ClientConfig{
-		User:            server.Username,
-		Auth:            []ssh.AuthMethod{auth},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
+		return p.conn.writeEvent(TerminalEvent{Type: TerminalMessageError, Message: "unknown message type"})
 	}
+	return nil
+}
 
-	client, err := ssh.Dial("tcp", address, config)
+func (h *WSHandler) openSession(server *DeployServer, hostKeyCallback ssh.HostKeyCallback, cols, rows int) (*ssh.Client, *ssh.Session, io.WriteCloser, io.Reader, io.Reader, error) {
+	client, err := h.service.dial(server, hostKeyCallback)
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
@@ -129,7 +320,7 @@ func (h *WSHandler) openSession(server *DeployServer) (*ssh.Client, *ssh.Session
 		ssh.TTY_OP_OSPEED: 14400,
 	}
 
-	if err := session.RequestPty("xterm-256color", 40, 120, modes); err != nil {
+	if err := session.RequestPty("xterm-256color", rows, cols, modes); err != nil {
 		_ = session.Close()
 		_ = client.Close()
 		return nil, nil, nil, nil, nil, err
@@ -163,12 +354,13 @@ func (h *WSHandler) openSession(server *DeployServer) (*ssh.Client, *ssh.Session
 	return client, session, stdin, stdout, stderr, nil
 }
 
-func streamOutput(c *websocket.Conn, reader io.Reader) {
+func streamOutput(live *liveTerminal, reader io.Reader, done *sync.WaitGroup) {
+	defer done.Done()
 	buf := make([]byte, 4096)
 	for {
 		n, err := reader.Read(buf)
 		if n > 0 {
-			_ = c.WriteMessage(websocket.TextMessage, buf[:n])
+			live.output(buf[:n])
 		}
 		if err != nil {
 			return
@@ -20,6 +20,9 @@ var (
 type Service struct {
 	repo        *Repository
 	projectRepo *project.Repository
+
+	// optional, delivers live events to a single user
+	eventHook project.UserEventFunc
 }
 
 func NewService(repo *Repository, projectRepo *project.Repository) *Service {
@@ -29,6 +32,11 @@ func NewService(repo *Repository, projectRepo *project.Repository) *Service {
 	}
 }
 
+// SetEventHook sets the callback used to announce new threads (called from main.go)
+func (s *Service) SetEventHook(hook project.UserEventFunc) {
+	s.eventHook = hook
+}
+
 func (s *Service) CreateOrGetThread(projectID, userID, otherUserID uuid.UUID) (*DirectMessageThread, error) {
 	if userID == otherUserID {
 		return nil, ErrInvalidThread
@@ -81,6 +89,9 @@ func (s *Service) CreateOrGetThread(projectID, userID, otherUserID uuid.UUID) (*
 		log.Printf("dm service: failed to fetch created thread for project %s users %s/%s: %v", projectID, userID, otherUserID, err)
 		return nil, fmt.Errorf("failed to fetch direct thread: %w", err)
 	}
+
+	s.notifyThreadCreated(projectID, userID, otherUserID, createdThread)
+
 	return createdThread, nil
 }
 
@@ -101,3 +112,24 @@ func (s *Service) ListThreads(projectID, userID uuid.UUID) ([]DirectMessageThrea
 	}
 	return threads, nil
 }
+
+// notifyThreadCreated pushes the new thread to both participants,
+// each seeing it from their own side
+func (s *Service) notifyThreadCreated(projectID, userID, otherUserID uuid.UUID, thread *DirectMessageThread) {
+	if s.eventHook == nil {
+		return
+	}
+
+	s.eventHook(userID, "dm_thread_created", map[string]interface{}{
+		"thread": thread,
+	})
+
+	otherThread, err := s.repo.GetThreadByUsers(projectID, otherUserID, userID)
+	if err != nil {
+		log.Printf("dm service: failed to load thread for user %s project %s: %v", otherUserID, projectID, err)
+		return
+	}
+	s.eventHook(otherUserID, "dm_thread_created", map[string]interface{}{
+		"thread": otherThread,
+	})
+}
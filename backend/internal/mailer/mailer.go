@@ -2,6 +2,7 @@ package mailer
 
 type Sender interface {
 	SendVerificationCode(email, code string) error
+	SendPasswordResetCode(email, code string) error
 }
 
 type NoopMailer struct{}
@@ -9,3 +10,7 @@ type NoopMailer struct{}
 func (NoopMailer) SendVerificationCode(email, code string) error {
 	return nil
 }
+
+func (NoopMailer) SendPasswordResetCode(email, code string) error {
+	return nil
+}
@@ -21,7 +21,16 @@ func NewResendClient(apiKey, from string) Sender {
 func (m *ResendMailer) SendVerificationCode(to, code string) error {
 	subject := "Your verification code"
 	text := fmt.Sprintf("Your verification code is: %s\n\nIf you didn’t request this, you can ignore this email.", code)
+	return m.send(to, subject, text)
+}
+
+func (m *ResendMailer) SendPasswordResetCode(to, code string) error {
+	subject := "Reset your password"
+	text := fmt.Sprintf("Your password reset code is: %s\n\nIf you didn’t request a password reset, you can ignore this email.", code)
+	return m.send(to, subject, text)
+}
 
+func (m *ResendMailer) send(to, subject, text string) error {
 	_, err := m.client.Emails.Send(&resend.SendEmailRequest{
 		From:    m.from,
 		To:      []string{to},
@@ -37,13 +37,22 @@ func NewSMTPClient(cfg SMTPConfig) *SMTPClient {
 }
 
 func (c *SMTPClient) SendVerificationCode(email, code string) error {
+	subject := "DevHub verification code"
+	body := fmt.Sprintf("Your verification code is: %s\n\nThis code expires in 10 minutes.", code)
+	return c.send(email, subject, body)
+}
+
+func (c *SMTPClient) SendPasswordResetCode(email, code string) error {
+	subject := "DevHub password reset"
+	body := fmt.Sprintf("Your password reset code is: %s\n\nThis code expires in 10 minutes. If you didn't request a password reset, you can ignore this email.", code)
+	return c.send(email, subject, body)
+}
+
+func (c *SMTPClient) send(email, subject, body string) error {
 	if c.from == "" {
 		return fmt.Errorf("mailer from address is not configured")
 	}
 
-	subject := "DevHub verification code"
-	body := fmt.Sprintf("Your verification code is: %s\n\nThis code expires in 10 minutes.", code)
-
 	message := strings.Join([]string{
 		fmt.Sprintf("From: %s", c.from),
 		fmt.Sprintf("To: %s", email),
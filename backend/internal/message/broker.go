@@ -0,0 +1,140 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Broker fans out topic broadcasts to every API replica.
+// Each replica subscribes once and delivers received messages to its local clients.
+type Broker interface {
+	Publish(ctx context.Context, msg *BroadcastMessage) error
+	Subscribe(ctx context.Context, handler func(*BroadcastMessage)) error
+	Close() error
+}
+
+// brokerEnvelope is the wire format shared by all replicas
+type brokerEnvelope struct {
+	TopicID    uuid.UUID          `json:"topic_id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	Data       json.RawMessage    `json:"data,omitempty"`
+	Revalidate *RevalidateRequest `json:"revalidate,omitempty"`
+}
+
+// MemoryBroker delivers broadcasts within a single process.
+// It is used for local development and tests.
+type MemoryBroker struct {
+	handlers []func(*BroadcastMessage)
+	mu       sync.RWMutex
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg *BroadcastMessage) error {
+	b.mu.RLock()
+	handlers := append([]func(*BroadcastMessage){}, b.handlers...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, handler func(*BroadcastMessage)) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	b.handlers = nil
+	b.mu.Unlock()
+	return nil
+}
+
+// RedisBroker fans out broadcasts through a Redis pub/sub channel
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+
+	pubsub *redis.PubSub
+	mu     sync.Mutex
+}
+
+func NewRedisBroker(client *redis.Client, channel string) *RedisBroker {
+	return &RedisBroker{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, msg *BroadcastMessage) error {
+	payload, err := json.Marshal(brokerEnvelope{
+		TopicID:    msg.TopicID,
+		UserID:     msg.UserID,
+		Data:       msg.Data,
+		Revalidate: msg.Revalidate,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode broadcast: %w", err)
+	}
+
+	if err := b.client.Publish(ctx, b.channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish broadcast: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, handler func(*BroadcastMessage)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	// Wait for the subscription to be confirmed so no broadcast is lost at startup
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", b.channel, err)
+	}
+
+	b.mu.Lock()
+	b.pubsub = pubsub
+	b.mu.Unlock()
+
+	go func() {
+		for received := range pubsub.Channel() {
+			var envelope brokerEnvelope
+			if err := json.Unmarshal([]byte(received.Payload), &envelope); err != nil {
+				log.Printf("ws broker: failed to decode broadcast: %v", err)
+				continue
+			}
+			handler(&BroadcastMessage{
+				TopicID:    envelope.TopicID,
+				UserID:     envelope.UserID,
+				Data:       envelope.Data,
+				Revalidate: envelope.Revalidate,
+			})
+		}
+	}()
+
+	return nil
+}
+
+func (b *RedisBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pubsub == nil {
+		return nil
+	}
+	err := b.pubsub.Close()
+	b.pubsub = nil
+	return err
+}
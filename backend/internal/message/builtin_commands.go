@@ -0,0 +1,351 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/deploy"
+	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/topic"
+)
+
+const (
+	maxReminderDelay = 30 * 24 * time.Hour
+	minPollOptions   = 2
+	maxPollOptions   = 10
+)
+
+func (s *Service) registerBuiltinCommands() {
+	s.commands.Register(CommandSpec{
+		Name: "help",
+		Help: "List the commands available in this topic",
+		Run:  s.runHelp,
+	})
+	s.commands.Register(CommandSpec{
+		Name: "shrug",
+		Args: []CommandArg{{Name: "text", Description: "Message to send before the shrug"}},
+		Help: `Append ¯\_(ツ)_/¯ to your message`,
+		Run:  s.runShrug,
+	})
+	s.commands.Register(CommandSpec{
+		Name: "topic",
+		Args: []CommandArg{{Name: "name", Description: "New topic name", Required: true}},
+		Help: "Rename the topic",
+		Run:  s.runRenameTopic,
+	})
+	s.commands.Register(CommandSpec{
+		Name:       "invite",
+		Args:       []CommandArg{{Name: "@handle", Description: "User to invite to the project", Required: true}},
+		Permission: CommandPermissionAdmin,
+		Help:       "Invite a user to the project",
+		Run:        s.runInvite,
+	})
+	s.commands.Register(CommandSpec{
+		Name: "remind",
+		Args: []CommandArg{
+			{Name: "in", Description: "Delay such as 30m, 2h or 1d", Required: true},
+			{Name: "text", Description: "What to remind you about", Required: true},
+		},
+		Help: "Get a notification about this topic later",
+		Run:  s.runRemind,
+	})
+	s.commands.Register(CommandSpec{
+		Name: "poll",
+		Args: []CommandArg{
+			{Name: "\"question\"", Description: "Poll question, quoted", Required: true},
+			{Name: "\"option\"...", Description: "Between 2 and 10 quoted options", Required: true},
+		},
+		Help: "Start a poll",
+		Run:  s.runPoll,
+	})
+	s.commands.Register(CommandSpec{
+		Name: "mute",
+		Args: []CommandArg{{Name: "for|off", Description: "Mute for a while (e.g. 8h), or off to unmute"}},
+		Help: "Mute new-message notifications of this topic, mentions still notify you",
+		Run:  s.runMute,
+	})
+	s.commands.Register(CommandSpec{
+		Name: "deploy",
+		Args: []CommandArg{
+			{Name: "server", Description: "Name of a deploy server of the project", Required: true},
+			{Name: "script", Description: "Deploy script to run, " + deploy.DefaultDeployScript + " by default"},
+		},
+		Permission: CommandPermissionAdmin,
+		Help:       "Run a deploy script on a server",
+		Run:        s.runDeploy,
+	})
+	s.commands.Register(CommandSpec{
+		Name:       "archive",
+		Permission: CommandPermissionAdmin,
+		Help:       "Archive the topic, it stays readable but accepts no new messages",
+		Run:        s.runArchive,
+	})
+}
+
+func (s *Service) runHelp(ctx *CommandContext) (*CommandResult, error) {
+	var help strings.Builder
+	help.WriteString("Available commands:")
+	for _, spec := range s.commands.List(ctx.Role) {
+		fmt.Fprintf(&help, "\n%s — %s", spec.Usage, spec.Help)
+	}
+	return &CommandResult{Content: help.String(), Ephemeral: true}, nil
+}
+
+func (s *Service) runShrug(ctx *CommandContext) (*CommandResult, error) {
+	content := strings.TrimSpace(ctx.Args)
+	if content == "" {
+		content = `¯\_(ツ)_/¯`
+	} else {
+		content = fmt.Sprintf("%s ¯\\_(ツ)_/¯", content)
+	}
+	return &CommandResult{Content: content, Type: "text"}, nil
+}
+
+func (s *Service) runRenameTopic(ctx *CommandContext) (*CommandResult, error) {
+	newName := strings.TrimSpace(ctx.Args)
+	if len(newName) < 2 || len(newName) > 100 {
+		return nil, commandErrorf("Topic name must be between 2 and 100 characters")
+	}
+
+	ctx.Topic.Name = newName
+	if err := s.topicRepo.Update(ctx.Topic); err != nil {
+		return nil, fmt.Errorf("failed to update topic: %w", err)
+	}
+
+	return &CommandResult{
+		Content: fmt.Sprintf("Topic renamed to \"%s\"", newName),
+		System:  true,
+	}, nil
+}
+
+func (s *Service) runInvite(ctx *CommandContext) (*CommandResult, error) {
+	if s.invitationService == nil {
+		return nil, commandErrorf("Invitations are not available")
+	}
+
+	handle := strings.TrimPrefix(ctx.Fields()[0], "@")
+	invitee, err := s.userRepo.GetByHandle(handle)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, commandErrorf("No user with handle @%s", handle)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	_, err = s.invitationService.Create(ctx.Topic.ProjectID, ctx.UserID, project.CreateProjectInvitationRequest{
+		UserID: invitee.ID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, project.ErrAlreadyMember):
+			return nil, commandErrorf("@%s is already a member of this project", handle)
+		case errors.Is(err, project.ErrInvitationAlreadyExists):
+			return nil, commandErrorf("@%s already has a pending invitation", handle)
+		case errors.Is(err, project.ErrNotProjectOwner):
+			return nil, ErrCommandForbidden
+		case errors.Is(err, project.ErrNotProjectMember):
+			return nil, ErrNotProjectMember
+		}
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return &CommandResult{
+		Content: fmt.Sprintf("%s invited @%s to the project", s.displayName(ctx.UserID), handle),
+		System:  true,
+	}, nil
+}
+
+func (s *Service) runRemind(ctx *CommandContext) (*CommandResult, error) {
+	fields := ctx.Fields()
+	delay, err := parseCommandDuration(fields[0])
+	if err != nil || delay < time.Minute || delay > maxReminderDelay {
+		return nil, commandErrorf("Reminder delay must be between 1m and 30d, e.g. 30m, 2h or 1d")
+	}
+	text := strings.Join(fields[1:], " ")
+
+	reminder := Reminder{
+		UserID:   ctx.UserID,
+		TopicID:  ctx.Topic.ID,
+		Content:  text,
+		RemindAt: time.Now().Add(delay),
+	}
+	if err := s.repo.CreateReminder(&reminder); err != nil {
+		return nil, fmt.Errorf("failed to create reminder: %w", err)
+	}
+
+	return &CommandResult{
+		Content:   fmt.Sprintf("I will remind you in %s: %s", fields[0], text),
+		Ephemeral: true,
+	}, nil
+}
+
+func (s *Service) runPoll(ctx *CommandContext) (*CommandResult, error) {
+	fields := ctx.Fields()
+	question := strings.TrimSpace(fields[0])
+
+	options := make([]PollOption, 0, len(fields)-1)
+	for _, field := range fields[1:] {
+		if text := strings.TrimSpace(field); text != "" {
+			options = append(options, PollOption{Text: text, Votes: []uuid.UUID{}})
+		}
+	}
+	if question == "" || len(options) < minPollOptions || len(options) > maxPollOptions {
+		return nil, commandErrorf(`Usage: /poll "question" "option 1" "option 2" (2 to 10 options)`)
+	}
+
+	metadata, err := json.Marshal(PollMetadata{Question: question, Options: options})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode poll: %w", err)
+	}
+	encoded := string(metadata)
+
+	return &CommandResult{
+		Content:  question,
+		Type:     "poll",
+		Metadata: &encoded,
+	}, nil
+}
+
+func (s *Service) runMute(ctx *CommandContext) (*CommandResult, error) {
+	fields := ctx.Fields()
+
+	if len(fields) > 0 && strings.EqualFold(fields[0], "off") {
+		if err := s.topicRepo.Unmute(ctx.Topic.ID, ctx.UserID); err != nil {
+			return nil, fmt.Errorf("failed to unmute topic: %w", err)
+		}
+		return &CommandResult{Content: "Notifications for this topic are back on", Ephemeral: true}, nil
+	}
+
+	mute := topic.Mute{
+		TopicID: ctx.Topic.ID,
+		UserID:  ctx.UserID,
+	}
+	content := "Notifications for this topic are muted, use /mute off to unmute"
+	if len(fields) > 0 {
+		delay, err := parseCommandDuration(fields[0])
+		if err != nil || delay < time.Minute {
+			return nil, commandErrorf("Mute duration must be at least 1m, e.g. 30m, 8h or 7d")
+		}
+		until := time.Now().Add(delay)
+		mute.MutedUntil = &until
+		content = fmt.Sprintf("Notifications for this topic are muted for %s", fields[0])
+	}
+
+	if err := s.topicRepo.Mute(&mute); err != nil {
+		return nil, fmt.Errorf("failed to mute topic: %w", err)
+	}
+
+	return &CommandResult{Content: content, Ephemeral: true}, nil
+}
+
+func (s *Service) runDeploy(ctx *CommandContext) (*CommandResult, error) {
+	if s.deployService == nil {
+		return nil, commandErrorf("Deploys are not available")
+	}
+
+	fields := strings.Fields(ctx.Args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, commandErrorf("Usage: /deploy <server> [script]")
+	}
+	serverName, scriptName := fields[0], deploy.DefaultDeployScript
+	if len(fields) == 2 {
+		scriptName = fields[1]
+	}
+
+	run, err := s.deployService.RequestDeploy(ctx.Topic.ProjectID, ctx.UserID, serverName, scriptName)
+	if err != nil {
+		switch {
+		case errors.Is(err, deploy.ErrServerNotFound):
+			return nil, commandErrorf("No deploy server named %q", serverName)
+		case errors.Is(err, deploy.ErrScriptNotFound):
+			return nil, commandErrorf("No deploy script named %q", scriptName)
+		case errors.Is(err, deploy.ErrTwoFactorRequired):
+			return nil, commandErrorf("This project requires two-factor authentication to deploy")
+		case errors.Is(err, deploy.ErrNotProjectAdmin):
+			return nil, ErrCommandForbidden
+		case errors.Is(err, deploy.ErrNotProjectMember):
+			return nil, ErrNotProjectMember
+		}
+		return nil, fmt.Errorf("failed to start deploy: %w", err)
+	}
+
+	metadata, err := json.Marshal(map[string]any{
+		"action":         "deploy",
+		"run_id":         run.ID,
+		"script_id":      run.ScriptID,
+		"script_name":    scriptName,
+		"script_version": run.ScriptVersion,
+		"server_name":    serverName,
+		"run_path": fmt.Sprintf(
+			"/api/projects/%s/deploy/runs/%s/ws",
+			ctx.Topic.ProjectID,
+			run.ID,
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deploy metadata: %w", err)
+	}
+	encoded := string(metadata)
+
+	return &CommandResult{
+		Content: fmt.Sprintf("%s started %s v%d on %s",
+			s.displayName(ctx.UserID), scriptName, run.ScriptVersion, serverName),
+		Metadata: &encoded,
+		System:   true,
+	}, nil
+}
+
+func (s *Service) runArchive(ctx *CommandContext) (*CommandResult, error) {
+	if s.topicService == nil {
+		return nil, commandErrorf("Archiving is not available")
+	}
+	if ctx.Topic.Type == "direct" {
+		return nil, commandErrorf("Direct conversations cannot be archived")
+	}
+	if ctx.Topic.Visibility == "archived" {
+		return nil, commandErrorf("This topic is already archived")
+	}
+
+	archived := "archived"
+	if _, err := s.topicService.Update(ctx.Topic.ID, ctx.UserID, topic.UpdateTopicRequest{
+		Visibility: &archived,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to archive topic: %w", err)
+	}
+
+	return &CommandResult{
+		Content: fmt.Sprintf("%s archived this topic", s.displayName(ctx.UserID)),
+		System:  true,
+	}, nil
+}
+
+// displayName returns the name shown for a user in system messages
+func (s *Service) displayName(userID uuid.UUID) string {
+	if s.userRepo == nil {
+		return "Someone"
+	}
+	found, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "Someone"
+	}
+	return found.Name
+}
+
+// parseCommandDuration accepts Go durations plus whole days, e.g. 45m, 1h30m or 7d
+func parseCommandDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -1,6 +1,22 @@
 package message
 
-import "strings"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/m0khm/devhub/backend/internal/topic"
+)
+
+// Command permissions, from least to most privileged
+const (
+	CommandPermissionMember = "member"
+	CommandPermissionAdmin  = "admin"
+	CommandPermissionOwner  = "owner"
+)
 
 type Command struct {
 	Name string
@@ -26,3 +42,190 @@ func ParseCommand(content string) (Command, bool) {
 		Args: args,
 	}, true
 }
+
+// CommandArg describes one positional argument of a command
+type CommandArg struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// CommandSpec declares a slash command
+type CommandSpec struct {
+	Name       string       `json:"name"`
+	Usage      string       `json:"usage"`
+	Args       []CommandArg `json:"args"`
+	Permission string       `json:"permission"`
+	Help       string       `json:"help"`
+	Run        CommandFunc  `json:"-"`
+}
+
+// CommandContext is what a command sees when it runs
+type CommandContext struct {
+	Topic  *topic.Topic
+	UserID uuid.UUID
+	Role   string
+	Args   string
+}
+
+// Fields splits the arguments on whitespace, keeping "quoted strings" together
+func (c *CommandContext) Fields() []string {
+	return splitArgs(c.Args)
+}
+
+type CommandFunc func(ctx *CommandContext) (*CommandResult, error)
+
+// CommandResult tells Create what to post once a command ran.
+// By default Content is posted as the caller's own message.
+type CommandResult struct {
+	Content  string
+	Type     string
+	Metadata *string
+	// System posts Content as a system message instead
+	System bool
+	// Ephemeral returns Content to the caller only, nothing is stored or broadcast
+	Ephemeral bool
+}
+
+// CommandError is a command failure that is shown to the caller as is
+type CommandError struct {
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+func commandErrorf(format string, args ...any) error {
+	return &CommandError{Message: fmt.Sprintf(format, args...)}
+}
+
+// CommandRegistry holds the slash commands available in topics
+type CommandRegistry struct {
+	mu       sync.RWMutex
+	commands map[string]CommandSpec
+}
+
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		commands: make(map[string]CommandSpec),
+	}
+}
+
+// Register adds a command, replacing any command with the same name
+func (r *CommandRegistry) Register(spec CommandSpec) {
+	spec.Name = strings.ToLower(spec.Name)
+	if spec.Permission == "" {
+		spec.Permission = CommandPermissionMember
+	}
+	if spec.Usage == "" {
+		spec.Usage = buildUsage(spec)
+	}
+	if spec.Args == nil {
+		spec.Args = []CommandArg{}
+	}
+
+	r.mu.Lock()
+	r.commands[spec.Name] = spec
+	r.mu.Unlock()
+}
+
+func (r *CommandRegistry) Get(name string) (CommandSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	spec, ok := r.commands[strings.ToLower(name)]
+	return spec, ok
+}
+
+// List returns the commands a role may run, sorted by name
+func (r *CommandRegistry) List(role string) []CommandSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	specs := make([]CommandSpec, 0, len(r.commands))
+	for _, spec := range r.commands {
+		if permissionAllows(spec.Permission, role) {
+			specs = append(specs, spec)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+func permissionRank(permission string) int {
+	switch permission {
+	case CommandPermissionOwner:
+		return 3
+	case CommandPermissionAdmin:
+		return 2
+	case CommandPermissionMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// permissionAllows reports whether a project role satisfies a command permission
+func permissionAllows(required, role string) bool {
+	rank := permissionRank(role)
+	return rank > 0 && rank >= permissionRank(required)
+}
+
+func requiredArgs(spec CommandSpec) int {
+	count := 0
+	for _, arg := range spec.Args {
+		if arg.Required {
+			count++
+		}
+	}
+	return count
+}
+
+func buildUsage(spec CommandSpec) string {
+	var usage strings.Builder
+	usage.WriteString("/")
+	usage.WriteString(spec.Name)
+	for _, arg := range spec.Args {
+		if arg.Required {
+			fmt.Fprintf(&usage, " <%s>", arg.Name)
+		} else {
+			fmt.Fprintf(&usage, " [%s]", arg.Name)
+		}
+	}
+	return usage.String()
+}
+
+// splitArgs splits on whitespace, double quotes group words into one argument
+func splitArgs(args string) []string {
+	var (
+		fields  []string
+		current strings.Builder
+		quoted  bool
+		pending bool
+	)
+
+	for _, r := range args {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			pending = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if pending {
+				fields = append(fields, current.String())
+				current.Reset()
+				pending = false
+			}
+		default:
+			current.WriteRune(r)
+			pending = true
+		}
+	}
+	if pending {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
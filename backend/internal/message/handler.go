@@ -84,36 +84,180 @@ func (h *Handler) Create(c *fiber.Ctx) error {
 				"error": "Invalid command usage",
 			})
 		}
+		if errors.Is(err, ErrCommandForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You are not allowed to run this command",
+			})
+		}
+		var commandErr *CommandError
+		if errors.As(err, &commandErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": commandErr.Message,
+			})
+		}
+		if errors.Is(err, ErrInvalidParent) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Parent message not found in this topic",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create message",
 		})
 	}
 
-	// Broadcast new message
-	if h.wsHandler != nil {
+	// Command responses meant for the caller only are not broadcast
+	if message.Ephemeral {
+		return c.Status(fiber.StatusOK).JSON(message)
+	}
+
+	// Replies are broadcast to the thread instead of the topic listing
+	if message.ParentID != nil {
+		h.handleThreadReply(message)
+	} else if h.wsHandler != nil {
 		h.wsHandler.BroadcastNewMessage(message)
 	}
 
-	if h.notificationService != nil {
-		notifications, err := h.notificationService.CreateMessageNotifications(
-			topicID,
-			userID,
-			message.Content,
-		)
+	h.notifyMembers(message, topicID, userID)
+
+	return c.Status(fiber.StatusCreated).JSON(message)
+}
+
+// notifyMembers creates the new-message notifications of a topic, for replies too
+func (h *Handler) notifyMembers(message *MessageWithUser, topicID, userID uuid.UUID) {
+	if h.notificationService == nil {
+		return
+	}
+	notifications, err := h.notificationService.CreateMessageNotifications(
+		topicID,
+		userID,
+		message.Content,
+	)
+	if err != nil {
+		log.Printf("failed to create notifications for message %s: %v", message.ID, err)
+		return
+	}
+	if h.wsHandler != nil {
+		for _, item := range notifications {
+			h.wsHandler.BroadcastNotificationCreated(item)
+		}
+	}
+}
+
+func (h *Handler) handleThreadReply(reply *MessageWithUser) {
+	if h.wsHandler != nil {
+		summary, err := h.service.GetThreadSummary(*reply.ParentID)
 		if err != nil {
-			log.Printf("failed to create notifications for message %s: %v", message.ID, err)
-		} else if h.wsHandler != nil {
-			for _, item := range notifications {
-				h.wsHandler.BroadcastNotificationCreated(topicID, item)
-			}
+			log.Printf("failed to get thread summary for message %s: %v", *reply.ParentID, err)
+		} else {
+			h.wsHandler.BroadcastThreadReply(reply, summary)
 		}
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(message)
+	notifications, err := h.service.NotifyThreadReply(reply)
+	if err != nil {
+		log.Printf("failed to create thread notifications for message %s: %v", reply.ID, err)
+		return
+	}
+	if h.wsHandler != nil {
+		for _, item := range notifications {
+			h.wsHandler.BroadcastNotificationCreated(item)
+		}
+	}
+}
+
+// Get thread of a message
+// GET /api/messages/:id/thread
+func (h *Handler) GetThread(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid message ID",
+		})
+	}
+
+	thread, err := h.service.GetThread(messageID, userID)
+	if err != nil {
+		if errors.Is(err, ErrMessageNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Message not found",
+			})
+		}
+		if errors.Is(err, ErrNotProjectMember) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Not a project member",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get thread",
+		})
+	}
+
+	return c.JSON(thread)
+}
+
+// Follow thread
+// POST /api/messages/:id/thread/follow
+func (h *Handler) FollowThread(c *fiber.Ctx) error {
+	return h.setThreadFollow(c, true)
+}
+
+// Unfollow thread
+// DELETE /api/messages/:id/thread/follow
+func (h *Handler) UnfollowThread(c *fiber.Ctx) error {
+	return h.setThreadFollow(c, false)
+}
+
+func (h *Handler) setThreadFollow(c *fiber.Ctx, follow bool) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid message ID",
+		})
+	}
+
+	if follow {
+		err = h.service.FollowThread(messageID, userID)
+	} else {
+		err = h.service.UnfollowThread(messageID, userID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrMessageNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Message not found",
+			})
+		}
+		if errors.Is(err, ErrNotProjectMember) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Not a project member",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update thread subscription",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success":   true,
+		"following": follow,
+	})
 }
 
 // Get messages by topic
-// GET /api/topics/:topicId/messages?limit=50&offset=0&before=2024-01-01T00:00:00Z&query=search
+// GET /api/topics/:topicId/messages?limit=50&before=<cursor>|after=<cursor>|around=<messageId>&query=search
 func (h *Handler) GetByTopicID(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -137,46 +281,98 @@ func (h *Handler) GetByTopicID(c *fiber.Ctx) error {
 		}
 	}
 
-	offset := 0
-	if offsetParam := c.Query("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
-	}
-
-	var before *time.Time
-	if beforeParam := c.Query("before"); beforeParam != "" {
-		if t, err := time.Parse(time.RFC3339, beforeParam); err == nil {
-			before = &t
-		}
-	}
-
 	query := strings.TrimSpace(c.Query("query"))
 	if query == "" {
 		query = strings.TrimSpace(c.Query("q"))
 	}
 
-	var (
-		messages []MessageWithUser
-	)
-
 	if query != "" {
-		messages, err = h.service.SearchByTopicID(topicID, userID, query, limit)
-	} else {
-		messages, err = h.service.GetByTopicID(topicID, userID, limit, offset, before)
+		messages, err := h.service.SearchByTopicID(topicID, userID, query, limit)
+		if err != nil {
+			if errors.Is(err, ErrNotProjectMember) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Not a project member",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get messages",
+			})
+		}
+		if messages == nil {
+			messages = []MessageWithUser{}
+		}
+		return c.JSON(MessagePage{Messages: messages})
+	}
+
+	pageReq, err := parsePageRequest(c, limit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+		})
 	}
+
+	page, err := h.service.GetByTopicID(topicID, userID, pageReq)
 	if err != nil {
 		if errors.Is(err, ErrNotProjectMember) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "Not a project member",
 			})
 		}
+		if errors.Is(err, ErrMessageNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Message not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get messages",
 		})
 	}
 
-	return c.JSON(messages)
+	return c.JSON(page)
+}
+
+// parsePageRequest reads one of before, after or around from the query
+func parsePageRequest(c *fiber.Ctx, limit int) (PageRequest, error) {
+	req := PageRequest{Limit: limit}
+
+	before, after, around := c.Query("before"), c.Query("after"), c.Query("around")
+	set := 0
+	for _, value := range []string{before, after, around} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return req, ErrInvalidCursor
+	}
+
+	switch {
+	case before != "":
+		cursor, err := DecodeCursor(before)
+		if err != nil {
+			// Older clients pass a plain timestamp
+			t, parseErr := time.Parse(time.RFC3339, before)
+			if parseErr != nil {
+				return req, err
+			}
+			cursor = &Cursor{CreatedAt: t}
+		}
+		req.Before = cursor
+	case after != "":
+		cursor, err := DecodeCursor(after)
+		if err != nil {
+			return req, err
+		}
+		req.After = cursor
+	case around != "":
+		messageID, err := uuid.Parse(around)
+		if err != nil {
+			return req, ErrInvalidCursor
+		}
+		req.Around = &messageID
+	}
+
+	return req, nil
 }
 
 // Get pinned messages by topic
@@ -439,6 +635,109 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
 
+// List slash commands available in a topic
+// GET /api/topics/:topicId/commands
+func (h *Handler) GetCommands(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	topicID, err := uuid.Parse(c.Params("topicId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid topic ID",
+		})
+	}
+
+	commands, err := h.service.ListCommands(topicID, userID)
+	if err != nil {
+		if errors.Is(err, ErrTopicNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Topic not found",
+			})
+		}
+		if errors.Is(err, ErrNotProjectMember) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Not a project member",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get commands",
+		})
+	}
+
+	return c.JSON(commands)
+}
+
+// Vote on a poll
+// POST /api/messages/:id/poll/vote
+// Body: {"option": 0}
+func (h *Handler) VotePoll(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid message ID",
+		})
+	}
+
+	var req PollVoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	message, err := h.service.VotePoll(messageID, userID, *req.Option)
+	if err != nil {
+		if errors.Is(err, ErrMessageNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Message not found",
+			})
+		}
+		if errors.Is(err, ErrNotProjectMember) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Not a project member",
+			})
+		}
+		if errors.Is(err, ErrNotPoll) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Message is not a poll",
+			})
+		}
+		if errors.Is(err, ErrInvalidPollOption) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid poll option",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record vote",
+		})
+	}
+
+	if h.wsHandler != nil {
+		h.wsHandler.BroadcastMessageUpdate(message)
+	}
+
+	return c.JSON(message)
+}
+
 // Toggle reaction
 // POST /api/messages/:id/reactions
 // Body: {"emoji": "👍"}
@@ -496,8 +795,7 @@ func (h *Handler) ToggleReaction(c *fiber.Ctx) error {
 
 	// Broadcast reaction update (best-effort)
 	if h.wsHandler != nil && topicID != uuid.Nil {
-		// NOTE: Если у тебя другая сигнатура — подгони под свою.
-		if reactions, err := h.service.repo.GetReactions(messageID, userID); err == nil {
+		if reactions, err := h.service.GetReactions(messageID, userID); err == nil {
 			h.wsHandler.BroadcastReactionUpdate(topicID, messageID, reactions)
 		}
 	}
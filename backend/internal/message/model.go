@@ -33,6 +33,18 @@ func (File) TableName() string {
 	return "files"
 }
 
+// ThreadSubscription marks a user as following the thread of a top-level message
+type ThreadSubscription struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	MessageID uuid.UUID `json:"message_id" gorm:"not null"`
+	UserID    uuid.UUID `json:"user_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ThreadSubscription) TableName() string {
+	return "thread_subscriptions"
+}
+
 type MessageReaction struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	MessageID uuid.UUID `json:"message_id" gorm:"not null"`
@@ -63,6 +75,75 @@ type MessageWithUser struct {
 		AvatarURL *string   `json:"avatar_url"`
 	} `json:"user,omitempty"`
 	Reactions []ReactionGroup `json:"reactions,omitempty"`
+
+	// Thread info, only set on top-level messages
+	ReplyCount      int        `json:"reply_count" gorm:"-"`
+	LastReplyAt     *time.Time `json:"last_reply_at,omitempty" gorm:"-"`
+	LastReplyUserID *uuid.UUID `json:"last_reply_user_id,omitempty" gorm:"-"`
+
+	// Set on command responses that are shown to the caller only
+	Ephemeral bool `json:"ephemeral,omitempty" gorm:"-"`
+}
+
+// ThreadSummary aggregates the replies of a top-level message
+type ThreadSummary struct {
+	ParentID        uuid.UUID
+	ReplyCount      int
+	LastReplyAt     time.Time
+	LastReplyUserID *uuid.UUID
+}
+
+// PageRequest selects a page of top-level messages.
+// At most one of Before, After and Around is set.
+type PageRequest struct {
+	Limit  int
+	Before *Cursor
+	After  *Cursor
+	Around *uuid.UUID
+}
+
+// MessagePage is a page of messages, newest first.
+// NextCursor continues towards older messages, PrevCursor towards newer ones.
+type MessagePage struct {
+	Messages   []MessageWithUser `json:"messages"`
+	NextCursor *string           `json:"next_cursor"`
+	PrevCursor *string           `json:"prev_cursor"`
+}
+
+type ThreadResponse struct {
+	Parent    MessageWithUser   `json:"parent"`
+	Replies   []MessageWithUser `json:"replies"`
+	Following bool              `json:"following"`
+}
+
+// Reminder is a /remind reminder, delivered as a notification at RemindAt
+type Reminder struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"not null"`
+	TopicID   uuid.UUID  `json:"topic_id" gorm:"not null"`
+	Content   string     `json:"content" gorm:"not null"`
+	RemindAt  time.Time  `json:"remind_at" gorm:"not null"`
+	SentAt    *time.Time `json:"sent_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (Reminder) TableName() string {
+	return "message_reminders"
+}
+
+// PollMetadata is the metadata of a "poll" message
+type PollMetadata struct {
+	Question string       `json:"question"`
+	Options  []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	Text  string      `json:"text"`
+	Votes []uuid.UUID `json:"votes"`
+}
+
+type PollVoteRequest struct {
+	Option *int `json:"option" validate:"required,min=0"`
 }
 
 type ReactionGroup struct {
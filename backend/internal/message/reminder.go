@@ -0,0 +1,92 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/notification"
+)
+
+const reminderBatchSize = 100
+
+// ReminderWorker delivers due /remind reminders as notifications
+type ReminderWorker struct {
+	repo             *Repository
+	notificationRepo *notification.Repository
+	interval         time.Duration
+	notify           func(item notification.Notification)
+}
+
+func NewReminderWorker(repo *Repository, notificationRepo *notification.Repository, interval time.Duration) *ReminderWorker {
+	return &ReminderWorker{
+		repo:             repo,
+		notificationRepo: notificationRepo,
+		interval:         interval,
+	}
+}
+
+// SetNotifier sets the callback used to push delivered reminders (called from main.go)
+func (w *ReminderWorker) SetNotifier(notify func(item notification.Notification)) {
+	w.notify = notify
+}
+
+// Run polls for due reminders until ctx is cancelled
+func (w *ReminderWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.deliverDue(); err != nil {
+				log.Printf("reminders: %v", err)
+			}
+		}
+	}
+}
+
+func (w *ReminderWorker) deliverDue() error {
+	for {
+		var notifications []notification.Notification
+		reminders, err := w.repo.ClaimDueReminders(time.Now(), reminderBatchSize, func(tx *gorm.DB, reminders []Reminder) error {
+			notifications = make([]notification.Notification, 0, len(reminders))
+			for _, reminder := range reminders {
+				link := fmt.Sprintf("/topics/%s", reminder.TopicID)
+				notifications = append(notifications, notification.Notification{
+					UserID: reminder.UserID,
+					Title:  "Reminder",
+					Body:   reminder.Content,
+					Link:   &link,
+					Type:   "reminder",
+				})
+			}
+			if err := w.notificationRepo.WithTx(tx).CreateMany(notifications); err != nil {
+				return fmt.Errorf("failed to create notifications: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to deliver reminders: %w", err)
+		}
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		// Pushed only once the reminders are committed as sent
+		if w.notify != nil {
+			for _, item := range notifications {
+				w.notify(item)
+			}
+		}
+
+		if len(reminders) < reminderBatchSize {
+			return nil
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -47,11 +48,8 @@ func (r *Repository) GetByIDWithUser(id uuid.UUID) (*MessageWithUser, error) {
 	return &message, err
 }
 
-// Get messages by topic with pagination
-func (r *Repository) GetByTopicID(topicID uuid.UUID, limit, offset int, before *time.Time) ([]MessageWithUser, error) {
-	var messages []MessageWithUser
-
-	query := r.db.Table("messages").
+func (r *Repository) topLevelQuery(topicID uuid.UUID) *gorm.DB {
+	return r.db.Table("messages").
 		Select(`
 			messages.*,
 			users.id as "user__id",
@@ -61,21 +59,124 @@ func (r *Repository) GetByTopicID(topicID uuid.UUID, limit, offset int, before *
 			users.avatar_url as "user__avatar_url"
 		`).
 		Joins("LEFT JOIN users ON users.id = messages.user_id").
-		Where("messages.topic_id = ?", topicID)
+		Where("messages.topic_id = ?", topicID).
+		Where("messages.parent_id IS NULL")
+}
+
+// Get top-level messages older than cursor, newest first.
+// A nil cursor starts at the newest message.
+func (r *Repository) GetBefore(topicID uuid.UUID, cursor *Cursor, limit int) ([]MessageWithUser, error) {
+	var messages []MessageWithUser
 
-	if before != nil {
-		query = query.Where("messages.created_at < ?", before)
+	query := r.topLevelQuery(topicID)
+	if cursor != nil {
+		query = query.Where("(messages.created_at, messages.id) < (?, ?)", cursor.CreatedAt, cursor.ID)
 	}
 
 	err := query.
-		Order("messages.created_at DESC").
+		Order("messages.created_at DESC, messages.id DESC").
 		Limit(limit).
-		Offset(offset).
 		Scan(&messages).Error
 
 	return messages, err
 }
 
+// Get top-level messages newer than cursor, oldest first
+func (r *Repository) GetAfter(topicID uuid.UUID, cursor Cursor, limit int) ([]MessageWithUser, error) {
+	var messages []MessageWithUser
+
+	err := r.topLevelQuery(topicID).
+		Where("(messages.created_at, messages.id) > (?, ?)", cursor.CreatedAt, cursor.ID).
+		Order("messages.created_at ASC, messages.id ASC").
+		Limit(limit).
+		Scan(&messages).Error
+
+	return messages, err
+}
+
+// Get replies of a thread, oldest first
+func (r *Repository) GetReplies(parentID uuid.UUID) ([]MessageWithUser, error) {
+	var messages []MessageWithUser
+
+	err := r.db.Table("messages").
+		Select(`
+			messages.*,
+			users.id as "user__id",
+			users.name as "user__name",
+			users.handle as "user__handle",
+			users.email as "user__email",
+			users.avatar_url as "user__avatar_url"
+		`).
+		Joins("LEFT JOIN users ON users.id = messages.user_id").
+		Where("messages.parent_id = ?", parentID).
+		Order("messages.created_at ASC").
+		Scan(&messages).Error
+
+	return messages, err
+}
+
+// Get reply count and last reply of each thread in one query
+func (r *Repository) GetThreadSummaries(parentIDs []uuid.UUID) (map[uuid.UUID]ThreadSummary, error) {
+	summaries := make(map[uuid.UUID]ThreadSummary)
+	if len(parentIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []ThreadSummary
+	err := r.db.Raw(`
+		SELECT DISTINCT ON (parent_id)
+			parent_id,
+			COUNT(*) OVER (PARTITION BY parent_id) AS reply_count,
+			created_at AS last_reply_at,
+			user_id AS last_reply_user_id
+		FROM messages
+		WHERE parent_id IN ?
+		ORDER BY parent_id, created_at DESC
+	`, parentIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		summaries[row.ParentID] = row
+	}
+	return summaries, nil
+}
+
+// Follow thread
+func (r *Repository) FollowThread(messageID, userID uuid.UUID) error {
+	return r.db.Exec(
+		"INSERT INTO thread_subscriptions (message_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		messageID,
+		userID,
+	).Error
+}
+
+// Unfollow thread
+func (r *Repository) UnfollowThread(messageID, userID uuid.UUID) error {
+	return r.db.
+		Where("message_id = ? AND user_id = ?", messageID, userID).
+		Delete(&ThreadSubscription{}).Error
+}
+
+// Check if user follows thread
+func (r *Repository) IsFollowingThread(messageID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&ThreadSubscription{}).
+		Where("message_id = ? AND user_id = ?", messageID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Get users following thread
+func (r *Repository) GetThreadFollowerIDs(messageID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.Model(&ThreadSubscription{}).
+		Where("message_id = ?", messageID).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
 // Update message
 func (r *Repository) Update(message *Message) error {
 	return r.db.Save(message).Error
@@ -85,6 +186,25 @@ func (r *Repository) UpdateMetadata(messageID uuid.UUID, metadata string) error
 	return r.db.Model(&Message{}).Where("id = ?", messageID).Update("metadata", metadata).Error
 }
 
+// UpdateMetadataLocked rewrites the metadata of a message while holding a row lock,
+// so concurrent read-modify-write updates (poll votes) do not overwrite each other
+func (r *Repository) UpdateMetadataLocked(messageID uuid.UUID, update func(metadata *string) (string, error)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var message Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&message, "id = ?", messageID).Error; err != nil {
+			return err
+		}
+
+		metadata, err := update(message.Metadata)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Message{}).Where("id = ?", messageID).Update("metadata", metadata).Error
+	})
+}
+
 // Delete message
 func (r *Repository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&Message{}, "id = ?", id).Error
@@ -104,44 +224,66 @@ func (r *Repository) RemoveReaction(messageID, userID uuid.UUID, emoji string) e
 
 // Get reactions for message
 func (r *Repository) GetReactions(messageID uuid.UUID, currentUserID uuid.UUID) ([]ReactionGroup, error) {
+	groups, err := r.GetReactionsBatch([]uuid.UUID{messageID}, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if groups[messageID] == nil {
+		return []ReactionGroup{}, nil
+	}
+	return groups[messageID], nil
+}
+
+// Get reactions for many messages in one query.
+// Groups keep the order in which each emoji was first used.
+func (r *Repository) GetReactionsBatch(messageIDs []uuid.UUID, currentUserID uuid.UUID) (map[uuid.UUID][]ReactionGroup, error) {
+	result := make(map[uuid.UUID][]ReactionGroup, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
 	var reactions []struct {
-		Emoji  string
-		UserID uuid.UUID
+		MessageID uuid.UUID
+		Emoji     string
+		UserID    uuid.UUID
 	}
 
 	err := r.db.Table("message_reactions").
-		Select("emoji, user_id").
-		Where("message_id = ?", messageID).
+		Select("message_id, emoji, user_id").
+		Where("message_id IN ?", messageIDs).
+		Order("message_id, created_at ASC, id ASC").
 		Scan(&reactions).Error
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Group by emoji
-	groups := make(map[string]*ReactionGroup)
+	// Group by emoji, per message
+	indexes := make(map[uuid.UUID]map[string]int)
 	for _, r := range reactions {
-		if _, exists := groups[r.Emoji]; !exists {
-			groups[r.Emoji] = &ReactionGroup{
+		if _, exists := indexes[r.MessageID]; !exists {
+			indexes[r.MessageID] = make(map[string]int)
+		}
+		idx, exists := indexes[r.MessageID][r.Emoji]
+		if !exists {
+			idx = len(result[r.MessageID])
+			indexes[r.MessageID][r.Emoji] = idx
+			result[r.MessageID] = append(result[r.MessageID], ReactionGroup{
 				Emoji:   r.Emoji,
 				Count:   0,
 				Users:   []uuid.UUID{},
 				HasSelf: false,
-			}
+			})
 		}
-		groups[r.Emoji].Count++
-		groups[r.Emoji].Users = append(groups[r.Emoji].Users, r.UserID)
+
+		group := &result[r.MessageID][idx]
+		group.Count++
+		group.Users = append(group.Users, r.UserID)
 		if r.UserID == currentUserID {
-			groups[r.Emoji].HasSelf = true
+			group.HasSelf = true
 		}
 	}
 
-	// Convert map to slice
-	result := make([]ReactionGroup, 0, len(groups))
-	for _, group := range groups {
-		result = append(result, *group)
-	}
-
 	return result, nil
 }
 
@@ -207,3 +349,38 @@ func (r *Repository) Search(topicID uuid.UUID, query string, limit int) ([]Messa
 
 	return messages, err
 }
+
+// Create reminder
+func (r *Repository) CreateReminder(reminder *Reminder) error {
+	return r.db.Create(reminder).Error
+}
+
+// ClaimDueReminders marks up to limit due reminders as sent and passes them to
+// deliver in the same transaction, so they are only marked when deliver succeeds.
+// SKIP LOCKED lets several replicas poll without delivering a reminder twice.
+func (r *Repository) ClaimDueReminders(now time.Time, limit int, deliver func(tx *gorm.DB, reminders []Reminder) error) ([]Reminder, error) {
+	var reminders []Reminder
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`
+			UPDATE message_reminders SET sent_at = ?
+			WHERE id IN (
+				SELECT id FROM message_reminders
+				WHERE sent_at IS NULL AND remind_at <= ?
+				ORDER BY remind_at
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *`, now, now, limit).
+			Scan(&reminders).Error; err != nil {
+			return err
+		}
+		if len(reminders) == 0 {
+			return nil
+		}
+		return deliver(tx, reminders)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/m0khm/devhub/backend/internal/deploy"
 	"github.com/m0khm/devhub/backend/internal/notification"
 	"github.com/m0khm/devhub/backend/internal/project"
 	"github.com/m0khm/devhub/backend/internal/topic"
@@ -18,11 +18,18 @@ import (
 )
 
 var (
-	ErrMessageNotFound  = errors.New("message not found")
-	ErrNotProjectMember = errors.New("not a project member")
-	ErrNotMessageAuthor = errors.New("not message author")
-	ErrUnknownCommand   = errors.New("unknown command")
-	ErrInvalidCommand   = errors.New("invalid command")
+	ErrMessageNotFound      = errors.New("message not found")
+	ErrNotProjectMember     = errors.New("not a project member")
+	ErrNotMessageAuthor     = errors.New("not message author")
+	ErrUnknownCommand       = errors.New("unknown command")
+	ErrInvalidCommand       = errors.New("invalid command")
+	ErrTopicNotFound        = errors.New("topic not found")
+	ErrTopicAccessDenied    = errors.New("topic access denied")
+	ErrTooManySubscriptions = errors.New("too many topic subscriptions")
+	ErrInvalidParent        = errors.New("invalid parent message")
+	ErrCommandForbidden     = errors.New("command not allowed")
+	ErrNotPoll              = errors.New("message is not a poll")
+	ErrInvalidPollOption    = errors.New("invalid poll option")
 )
 
 type Service struct {
@@ -31,6 +38,12 @@ type Service struct {
 	projectRepo      *project.Repository
 	notificationRepo *notification.Repository
 	userRepo         *user.Repository
+
+	commands          *CommandRegistry
+	invitationService *project.InvitationService // optional, used by /invite
+	topicService      *topic.Service             // optional, used by /archive
+	deployService     *deploy.Service            // optional, used by /deploy
+	projectEventHook  project.ProjectEventFunc
 }
 
 func NewService(
@@ -40,12 +53,108 @@ func NewService(
 	notificationRepo *notification.Repository,
 	userRepo *user.Repository,
 ) *Service {
-	return &Service{
+	s := &Service{
 		repo:             repo,
 		topicRepo:        topicRepo,
 		projectRepo:      projectRepo,
 		notificationRepo: notificationRepo,
 		userRepo:         userRepo,
+		commands:         NewCommandRegistry(),
+	}
+	s.registerBuiltinCommands()
+	return s
+}
+
+// SetInvitationService sets the invitation service (called from main.go)
+func (s *Service) SetInvitationService(invitationService *project.InvitationService) {
+	s.invitationService = invitationService
+}
+
+// SetTopicService sets the topic service (called from main.go)
+func (s *Service) SetTopicService(topicService *topic.Service) {
+	s.topicService = topicService
+}
+
+// SetDeployService sets the deploy service (called from main.go)
+func (s *Service) SetDeployService(deployService *deploy.Service) {
+	s.deployService = deployService
+}
+
+// SetProjectEventHook sets the callback used to publish project events (called from main.go)
+func (s *Service) SetProjectEventHook(hook project.ProjectEventFunc) {
+	s.projectEventHook = hook
+}
+
+// Commands returns the slash command registry
+func (s *Service) Commands() *CommandRegistry {
+	return s.commands
+}
+
+// ListCommands returns the commands the user may run in a topic
+func (s *Service) ListCommands(topicID, userID uuid.UUID) ([]CommandSpec, error) {
+	topicObj, err := s.topicRepo.GetByID(topicID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTopicNotFound
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	role, err := s.projectRepo.GetUserRole(topicObj.ProjectID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotProjectMember
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	return s.commands.List(role), nil
+}
+
+// runCommand checks permission and arguments, then runs the command
+func (s *Service) runCommand(topicObj *topic.Topic, userID uuid.UUID, command Command) (*CommandResult, error) {
+	spec, ok := s.commands.Get(command.Name)
+	if !ok {
+		return nil, ErrUnknownCommand
+	}
+
+	role, err := s.projectRepo.GetUserRole(topicObj.ProjectID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotProjectMember
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if !permissionAllows(spec.Permission, role) {
+		return nil, ErrCommandForbidden
+	}
+
+	ctx := &CommandContext{
+		Topic:  topicObj,
+		UserID: userID,
+		Role:   role,
+		Args:   command.Args,
+	}
+	if len(ctx.Fields()) < requiredArgs(spec) {
+		return nil, ErrInvalidCommand
+	}
+
+	return spec.Run(ctx)
+}
+
+// ephemeralMessage wraps a command response that is never stored
+func ephemeralMessage(topicID uuid.UUID, content string) *MessageWithUser {
+	now := time.Now()
+	return &MessageWithUser{
+		Message: Message{
+			ID:        uuid.New(),
+			TopicID:   topicID,
+			Content:   content,
+			Type:      "system",
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Ephemeral: true,
 	}
 }
 
@@ -70,56 +179,33 @@ func (s *Service) Create(topicID, userID uuid.UUID, req CreateMessageRequest) (*
 	}
 
 	if command, isCommand := ParseCommand(req.Content); isCommand {
-		switch command.Name {
-		case "shrug":
-			content := strings.TrimSpace(command.Args)
-			if content == "" {
-				req.Content = `¯\_(ツ)_/¯`
-			} else {
-				req.Content = fmt.Sprintf("%s ¯\\_(ツ)_/¯", content)
-			}
-			req.Type = "text"
-		case "topic":
-			newName := strings.TrimSpace(command.Args)
-			if newName == "" {
-				return nil, ErrInvalidCommand
-			}
-			topicObj.Name = newName
-			if err := s.topicRepo.Update(topicObj); err != nil {
-				return nil, fmt.Errorf("failed to update topic: %w", err)
-			}
-			systemMessage := Message{
-				TopicID:  topicID,
-				UserID:   nil,
-				Content:  fmt.Sprintf("Topic renamed to \"%s\"", newName),
-				Type:     "system",
-				Metadata: nil,
-				ParentID: nil,
-			}
-			if err := s.repo.Create(&systemMessage); err != nil {
-				return nil, fmt.Errorf("failed to create system message: %w", err)
-			}
-			return s.GetByID(systemMessage.ID, userID)
-		case "invite":
-			target := strings.TrimSpace(command.Args)
-			if target == "" {
-				return nil, ErrInvalidCommand
-			}
+		result, err := s.runCommand(topicObj, userID, command)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.Ephemeral {
+			return ephemeralMessage(topicID, result.Content), nil
+		}
+
+		if result.System {
 			systemMessage := Message{
 				TopicID:  topicID,
 				UserID:   nil,
-				Content:  fmt.Sprintf("Invitation sent to %s", target),
+				Content:  result.Content,
 				Type:     "system",
-				Metadata: nil,
+				Metadata: result.Metadata,
 				ParentID: nil,
 			}
 			if err := s.repo.Create(&systemMessage); err != nil {
 				return nil, fmt.Errorf("failed to create system message: %w", err)
 			}
-			return s.GetByID(systemMessage.ID, userID)
-		default:
-			return nil, ErrUnknownCommand
+			return s.loadCreated(topicObj, systemMessage.ID, userID)
 		}
+
+		req.Content = result.Content
+		req.Type = result.Type
+		req.Metadata = result.Metadata
 	}
 
 	messageType := req.Type
@@ -127,6 +213,15 @@ func (s *Service) Create(topicID, userID uuid.UUID, req CreateMessageRequest) (*
 		messageType = "text"
 	}
 
+	var parent *Message
+	if req.ParentID != nil {
+		parent, err = s.resolveThreadRoot(topicID, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		req.ParentID = &parent.ID
+	}
+
 	message := Message{
 		TopicID:  topicID,
 		UserID:   &userID,
@@ -144,8 +239,211 @@ func (s *Service) Create(topicID, userID uuid.UUID, req CreateMessageRequest) (*
 		log.Printf("failed to create mention notifications: %v", err)
 	}
 
+	// Thread participants follow the thread automatically
+	if parent != nil {
+		if err := s.repo.FollowThread(parent.ID, userID); err != nil {
+			log.Printf("failed to follow thread %s: %v", parent.ID, err)
+		}
+		if parent.UserID != nil {
+			if err := s.repo.FollowThread(parent.ID, *parent.UserID); err != nil {
+				log.Printf("failed to follow thread %s: %v", parent.ID, err)
+			}
+		}
+	}
+
 	// Return message with user info
-	return s.GetByID(message.ID, userID)
+	return s.loadCreated(topicObj, message.ID, userID)
+}
+
+// CreateIntegration stores a message posted by an external integration.
+// Integration messages have no author, UserID stays nil.
+func (s *Service) CreateIntegration(topicID uuid.UUID, content string, metadata *string) (*MessageWithUser, error) {
+	topicObj, err := s.topicRepo.GetByID(topicID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTopicNotFound
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	message := Message{
+		TopicID:  topicID,
+		UserID:   nil,
+		Content:  content,
+		Type:     "integration",
+		Metadata: metadata,
+	}
+	if err := s.repo.Create(&message); err != nil {
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	return s.loadCreated(topicObj, message.ID, uuid.Nil)
+}
+
+// CreateProjectSystem posts a system message to the first topic of topicType in a project
+func (s *Service) CreateProjectSystem(projectID uuid.UUID, topicType, content string, metadata *string) (*MessageWithUser, error) {
+	topicObj, err := s.topicRepo.GetFirstByType(projectID, topicType)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTopicNotFound
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	message := Message{
+		TopicID:  topicObj.ID,
+		UserID:   nil,
+		Content:  content,
+		Type:     "system",
+		Metadata: metadata,
+	}
+	if err := s.repo.Create(&message); err != nil {
+		return nil, fmt.Errorf("failed to create system message: %w", err)
+	}
+
+	return s.loadCreated(topicObj, message.ID, uuid.Nil)
+}
+
+// ProjectAttachment finds a file message of a project so it can be copied to a
+// deploy server. The user must be able to read the topic it was posted in.
+func (s *Service) ProjectAttachment(projectID, messageID, userID uuid.UUID) (*deploy.Attachment, error) {
+	message, err := s.repo.GetByID(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, deploy.ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if message.Type != "file" || message.Metadata == nil {
+		return nil, deploy.ErrAttachmentNotFound
+	}
+
+	topicObj, err := s.topicRepo.GetByID(message.TopicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+	if topicObj.ProjectID != projectID {
+		return nil, deploy.ErrAttachmentNotFound
+	}
+	// Direct and admins-only topics are hidden like missing attachments
+	if _, err := s.GetTopicAccess(message.TopicID, userID); err != nil {
+		if errors.Is(err, ErrNotProjectMember) || errors.Is(err, ErrTopicAccessDenied) || errors.Is(err, ErrTopicNotFound) {
+			return nil, deploy.ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	var metadata fileMetadata
+	if err := json.Unmarshal([]byte(*message.Metadata), &metadata); err != nil || metadata.StorageKey == "" {
+		return nil, deploy.ErrAttachmentNotFound
+	}
+	return &deploy.Attachment{
+		MessageID:  message.ID,
+		Filename:   metadata.Filename,
+		MimeType:   metadata.MimeType,
+		Size:       metadata.Size,
+		StorageKey: metadata.StorageKey,
+	}, nil
+}
+
+// loadCreated loads a new message and publishes it as a project event. The
+// caller checked access when creating it, userID is uuid.Nil for messages
+// without an author.
+func (s *Service) loadCreated(topicObj *topic.Topic, messageID, userID uuid.UUID) (*MessageWithUser, error) {
+	message, err := s.repo.GetByIDWithUser(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if err := s.attachReactions([]*MessageWithUser{message}, userID); err != nil {
+		return nil, err
+	}
+	if err := s.attachThreadSummaries([]*MessageWithUser{message}); err != nil {
+		return nil, err
+	}
+	// Webhooks reach integrations outside the project, they only get messages
+	// every member can read
+	if s.projectEventHook != nil && topicObj.Type != "direct" && topicObj.AccessLevel != "admins" {
+		s.projectEventHook(topicObj.ProjectID, project.EventMessageCreated, message)
+	}
+	return message, nil
+}
+
+// resolveThreadRoot returns the top-level message a reply belongs to.
+// Replies to replies are attached to the root, threads are one level deep.
+func (s *Service) resolveThreadRoot(topicID, parentID uuid.UUID) (*Message, error) {
+	parent, err := s.repo.GetByID(parentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidParent
+		}
+		return nil, fmt.Errorf("failed to get parent message: %w", err)
+	}
+
+	if parent.ParentID != nil {
+		parent, err = s.repo.GetByID(*parent.ParentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrInvalidParent
+			}
+			return nil, fmt.Errorf("failed to get parent message: %w", err)
+		}
+	}
+
+	if parent.TopicID != topicID {
+		return nil, ErrInvalidParent
+	}
+
+	return parent, nil
+}
+
+// TopicAccess is the result of a topic access check
+type TopicAccess struct {
+	ProjectID uuid.UUID
+	topic.Access
+}
+
+// GetTopicAccess checks whether a user may follow a topic's live events
+func (s *Service) GetTopicAccess(topicID, userID uuid.UUID) (*TopicAccess, error) {
+	topicObj, err := s.topicRepo.GetByID(topicID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTopicNotFound
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	role, err := s.projectRepo.GetUserRole(topicObj.ProjectID, userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get user role: %w", err)
+		}
+		role = ""
+	}
+
+	access := topic.ResolveAccess(topicObj, role)
+
+	// Direct threads are only visible to their participants
+	if topicObj.Type == "direct" {
+		isParticipant, err := s.topicRepo.IsDirectParticipant(topicID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check participants: %w", err)
+		}
+		if !isParticipant {
+			access = topic.Access{}
+		}
+	}
+
+	if !access.CanRead {
+		if role == "" {
+			return nil, ErrNotProjectMember
+		}
+		return nil, ErrTopicAccessDenied
+	}
+
+	return &TopicAccess{
+		ProjectID: topicObj.ProjectID,
+		Access:    access,
+	}, nil
 }
 
 type mentionPayload struct {
@@ -217,19 +515,19 @@ func (s *Service) GetByID(messageID, currentUserID uuid.UUID) (*MessageWithUser,
 		return nil, ErrNotProjectMember
 	}
 
-	// Get reactions
-	reactions, err := s.repo.GetReactions(messageID, currentUserID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	if err := s.attachReactions([]*MessageWithUser{message}, currentUserID); err != nil {
+		return nil, err
 	}
 
-	message.Reactions = reactions
+	if err := s.attachThreadSummaries([]*MessageWithUser{message}); err != nil {
+		return nil, err
+	}
 
 	return message, nil
 }
 
-// Get messages by topic
-func (s *Service) GetByTopicID(topicID, userID uuid.UUID, limit, offset int, before *time.Time) ([]MessageWithUser, error) {
+// Get a page of top-level messages by topic
+func (s *Service) GetByTopicID(topicID, userID uuid.UUID, req PageRequest) (*MessagePage, error) {
 	// Check access
 	topicObj, err := s.topicRepo.GetByID(topicID)
 	if err != nil {
@@ -247,21 +545,136 @@ func (s *Service) GetByTopicID(topicID, userID uuid.UUID, limit, offset int, bef
 		return nil, ErrNotProjectMember
 	}
 
-	messages, err := s.repo.GetByTopicID(topicID, limit, offset, before)
+	var (
+		messages []MessageWithUser
+		hasOlder bool
+		hasNewer bool
+	)
+	switch {
+	case req.Around != nil:
+		messages, hasOlder, hasNewer, err = s.getAround(topicID, *req.Around, req.Limit)
+	case req.After != nil:
+		messages, hasNewer, err = s.getAfter(topicID, *req.After, req.Limit)
+		// We came from older messages
+		hasOlder = true
+	default:
+		messages, hasOlder, err = s.getBefore(topicID, req.Before, req.Limit)
+		hasNewer = req.Before != nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
+		return nil, err
 	}
 
-	// Get reactions for each message
-	for i := range messages {
-		reactions, err := s.repo.GetReactions(messages[i].ID, userID)
+	if err := s.attachReactions(messagePointers(messages), userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachThreadSummaries(messagePointers(messages)); err != nil {
+		return nil, err
+	}
+
+	page := &MessagePage{Messages: messages}
+	if len(messages) == 0 {
+		// Nothing past the cursor, hand it back so the client can turn around
+		page.Messages = []MessageWithUser{}
+		if req.Before != nil {
+			cursor := req.Before.Encode()
+			page.PrevCursor = &cursor
+		}
+		if req.After != nil {
+			cursor := req.After.Encode()
+			page.NextCursor = &cursor
+		}
+		return page, nil
+	}
+	if hasOlder {
+		cursor := cursorOf(&messages[len(messages)-1]).Encode()
+		page.NextCursor = &cursor
+	}
+	if hasNewer {
+		cursor := cursorOf(&messages[0]).Encode()
+		page.PrevCursor = &cursor
+	}
+
+	return page, nil
+}
+
+// getBefore returns messages older than cursor, newest first,
+// and whether even older messages exist
+func (s *Service) getBefore(topicID uuid.UUID, cursor *Cursor, limit int) ([]MessageWithUser, bool, error) {
+	messages, err := s.repo.GetBefore(topicID, cursor, limit+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	return messages, hasMore, nil
+}
+
+// getAfter returns messages newer than cursor, newest first,
+// and whether even newer messages exist
+func (s *Service) getAfter(topicID uuid.UUID, cursor Cursor, limit int) ([]MessageWithUser, bool, error) {
+	messages, err := s.repo.GetAfter(topicID, cursor, limit+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Pages are always returned newest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, hasMore, nil
+}
+
+// getAround returns the page centered on a message.
+// Replies are located through their thread's top-level message.
+func (s *Service) getAround(topicID, messageID uuid.UUID, limit int) ([]MessageWithUser, bool, bool, error) {
+	anchor, err := s.repo.GetByIDWithUser(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, false, ErrMessageNotFound
+		}
+		return nil, false, false, fmt.Errorf("failed to get message: %w", err)
+	}
+	if anchor.ParentID != nil {
+		anchor, err = s.repo.GetByIDWithUser(*anchor.ParentID)
 		if err != nil {
-			continue // Skip on error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, false, false, ErrMessageNotFound
+			}
+			return nil, false, false, fmt.Errorf("failed to get message: %w", err)
 		}
-		messages[i].Reactions = reactions
+	}
+	if anchor.TopicID != topicID {
+		return nil, false, false, ErrMessageNotFound
 	}
 
-	return messages, nil
+	cursor := cursorOf(anchor)
+	olderLimit := (limit - 1) / 2
+	newerLimit := limit - 1 - olderLimit
+
+	newer, hasNewer, err := s.getAfter(topicID, cursor, newerLimit)
+	if err != nil {
+		return nil, false, false, err
+	}
+	older, hasOlder, err := s.getBefore(topicID, &cursor, olderLimit)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	messages := make([]MessageWithUser, 0, len(newer)+1+len(older))
+	messages = append(messages, newer...)
+	messages = append(messages, *anchor)
+	messages = append(messages, older...)
+	return messages, hasOlder, hasNewer, nil
 }
 
 // Search messages by topic
@@ -288,12 +701,12 @@ func (s *Service) SearchByTopicID(topicID, userID uuid.UUID, query string, limit
 		return nil, fmt.Errorf("failed to search messages: %w", err)
 	}
 
-	for i := range messages {
-		reactions, err := s.repo.GetReactions(messages[i].ID, userID)
-		if err != nil {
-			continue
-		}
-		messages[i].Reactions = reactions
+	if err := s.attachReactions(messagePointers(messages), userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachThreadSummaries(messagePointers(messages)); err != nil {
+		return nil, err
 	}
 
 	return messages, nil
@@ -410,6 +823,77 @@ func (s *Service) ToggleReaction(messageID, userID uuid.UUID, emoji string) erro
 	}
 }
 
+// VotePoll records the user's choice on a poll message.
+// Polls are single choice, voting for the current choice again withdraws the vote.
+func (s *Service) VotePoll(messageID, userID uuid.UUID, option int) (*MessageWithUser, error) {
+	message, err := s.repo.GetByID(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if message.Type != "poll" {
+		return nil, ErrNotPoll
+	}
+
+	topicObj, err := s.topicRepo.GetByID(message.TopicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	isMember, err := s.projectRepo.IsUserMember(topicObj.ProjectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotProjectMember
+	}
+
+	err = s.repo.UpdateMetadataLocked(messageID, func(metadata *string) (string, error) {
+		if metadata == nil {
+			return "", ErrNotPoll
+		}
+		var poll PollMetadata
+		if err := json.Unmarshal([]byte(*metadata), &poll); err != nil {
+			return "", ErrNotPoll
+		}
+		if option < 0 || option >= len(poll.Options) {
+			return "", ErrInvalidPollOption
+		}
+
+		withdraw := false
+		for i := range poll.Options {
+			votes := poll.Options[i].Votes[:0]
+			for _, voterID := range poll.Options[i].Votes {
+				if voterID == userID {
+					withdraw = withdraw || i == option
+					continue
+				}
+				votes = append(votes, voterID)
+			}
+			poll.Options[i].Votes = votes
+		}
+		if !withdraw {
+			poll.Options[option].Votes = append(poll.Options[option].Votes, userID)
+		}
+
+		encoded, err := json.Marshal(poll)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotPoll) || errors.Is(err, ErrInvalidPollOption) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to record vote: %w", err)
+	}
+
+	return s.GetByID(messageID, userID)
+}
+
 // Pin message
 func (s *Service) PinMessage(messageID, userID uuid.UUID) (*MessageWithUser, error) {
 	message, err := s.repo.GetByID(messageID)
@@ -510,13 +994,191 @@ func (s *Service) GetPinnedByTopicID(topicID, userID uuid.UUID) ([]MessageWithUs
 		return nil, fmt.Errorf("failed to get pinned messages: %w", err)
 	}
 
+	if err := s.attachReactions(messagePointers(messages), userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachThreadSummaries(messagePointers(messages)); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// attachReactions loads the reactions of all messages in one query
+func (s *Service) attachReactions(messages []*MessageWithUser, currentUserID uuid.UUID) error {
+	messageIDs := make([]uuid.UUID, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.ID
+	}
+
+	reactions, err := s.repo.GetReactionsBatch(messageIDs, currentUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	for _, message := range messages {
+		message.Reactions = reactions[message.ID]
+	}
+	return nil
+}
+
+// GetReactions returns the reaction groups of a message
+func (s *Service) GetReactions(messageID, currentUserID uuid.UUID) ([]ReactionGroup, error) {
+	return s.repo.GetReactions(messageID, currentUserID)
+}
+
+// attachThreadSummaries fills reply counts and last reply info of top-level messages
+func (s *Service) attachThreadSummaries(messages []*MessageWithUser) error {
+	parentIDs := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		if message.ParentID == nil {
+			parentIDs = append(parentIDs, message.ID)
+		}
+	}
+
+	summaries, err := s.repo.GetThreadSummaries(parentIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get thread summaries: %w", err)
+	}
+
+	for _, message := range messages {
+		summary, ok := summaries[message.ID]
+		if !ok {
+			continue
+		}
+		lastReplyAt := summary.LastReplyAt
+		message.ReplyCount = summary.ReplyCount
+		message.LastReplyAt = &lastReplyAt
+		message.LastReplyUserID = summary.LastReplyUserID
+	}
+
+	return nil
+}
+
+func messagePointers(messages []MessageWithUser) []*MessageWithUser {
+	pointers := make([]*MessageWithUser, len(messages))
 	for i := range messages {
-		reactions, err := s.repo.GetReactions(messages[i].ID, userID)
+		pointers[i] = &messages[i]
+	}
+	return pointers
+}
+
+// Get thread of a message
+func (s *Service) GetThread(messageID, userID uuid.UUID) (*ThreadResponse, error) {
+	parent, err := s.GetByID(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.ParentID != nil {
+		parent, err = s.GetByID(*parent.ParentID, userID)
 		if err != nil {
+			return nil, err
+		}
+	}
+
+	replies, err := s.repo.GetReplies(parent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replies: %w", err)
+	}
+
+	if err := s.attachReactions(messagePointers(replies), userID); err != nil {
+		return nil, err
+	}
+
+	following, err := s.repo.IsFollowingThread(parent.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check thread subscription: %w", err)
+	}
+
+	return &ThreadResponse{
+		Parent:    *parent,
+		Replies:   replies,
+		Following: following,
+	}, nil
+}
+
+// Get reply count and last reply of a thread
+func (s *Service) GetThreadSummary(parentID uuid.UUID) (*ThreadSummary, error) {
+	summaries, err := s.repo.GetThreadSummaries([]uuid.UUID{parentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread summary: %w", err)
+	}
+
+	summary, ok := summaries[parentID]
+	if !ok {
+		return &ThreadSummary{ParentID: parentID}, nil
+	}
+	return &summary, nil
+}
+
+// Follow thread
+func (s *Service) FollowThread(messageID, userID uuid.UUID) error {
+	rootID, err := s.threadRootForUser(messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.FollowThread(rootID, userID); err != nil {
+		return fmt.Errorf("failed to follow thread: %w", err)
+	}
+	return nil
+}
+
+// Unfollow thread
+func (s *Service) UnfollowThread(messageID, userID uuid.UUID) error {
+	rootID, err := s.threadRootForUser(messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UnfollowThread(rootID, userID); err != nil {
+		return fmt.Errorf("failed to unfollow thread: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) threadRootForUser(messageID, userID uuid.UUID) (uuid.UUID, error) {
+	message, err := s.GetByID(messageID, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if message.ParentID != nil {
+		return *message.ParentID, nil
+	}
+	return message.ID, nil
+}
+
+// NotifyThreadReply creates notifications for the followers of a reply's thread
+func (s *Service) NotifyThreadReply(reply *MessageWithUser) ([]notification.Notification, error) {
+	if reply.ParentID == nil || s.notificationRepo == nil {
+		return nil, nil
+	}
+
+	followerIDs, err := s.repo.GetThreadFollowerIDs(*reply.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread followers: %w", err)
+	}
+
+	link := fmt.Sprintf("/topics/%s?message=%s", reply.TopicID, *reply.ParentID)
+	notifications := make([]notification.Notification, 0, len(followerIDs))
+	for _, followerID := range followerIDs {
+		if reply.UserID != nil && followerID == *reply.UserID {
 			continue
 		}
-		messages[i].Reactions = reactions
+		l := link
+		notifications = append(notifications, notification.Notification{
+			UserID: followerID,
+			Title:  "Thread reply",
+			Body:   "New reply in a thread you follow.",
+			Link:   &l,
+			Type:   "thread_reply",
+		})
 	}
 
-	return messages, nil
+	if err := s.notificationRepo.CreateMany(notifications); err != nil {
+		return nil, fmt.Errorf("failed to create notifications: %w", err)
+	}
+
+	return notifications, nil
 }
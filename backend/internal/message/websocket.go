@@ -1,7 +1,9 @@
 package message
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
 	"time"
@@ -12,31 +14,63 @@ import (
 	"github.com/m0khm/devhub/backend/internal/metrics"
 )
 
-// Client represents a WebSocket client
+// Client represents a WebSocket connection of a single user.
+// A client receives events of the topics it subscribed to and
+// every event addressed to its user.
 type Client struct {
-	ID      string
-	UserID  uuid.UUID
-	TopicID uuid.UUID
-	Conn    *websocket.Conn
-	Hub     *Hub
-	Send    chan []byte
-	mu      sync.Mutex
+	ID     string
+	UserID uuid.UUID
+	Conn   *websocket.Conn
+	Hub    *Hub
+	Send   chan []byte
+	mu     sync.Mutex
+
+	// Clients of the legacy per-topic endpoint are disconnected
+	// instead of unsubscribed when they lose access to their topic
+	topicScoped bool
+
+	// guarded by mu
+	subscriptions map[uuid.UUID]*subscription
+	closed        bool
+}
+
+type subscription struct {
+	projectID uuid.UUID
+	readOnly  bool
 }
 
+// Upper bound of topics a single connection may follow
+const maxSubscriptionsPerClient = 200
+
+// AccessChecker re-checks a user's access to a topic for live connections
+type AccessChecker func(topicID, userID uuid.UUID) (*TopicAccess, error)
+
 // Hub maintains active clients and broadcasts messages
 type Hub struct {
-	// Registered clients per topic
+	// All registered clients
+	clients map[*Client]bool
+
+	// Subscribed clients per topic
 	topics map[uuid.UUID]map[*Client]bool
 
+	// Registered clients per user
+	users map[uuid.UUID]map[*Client]bool
+
 	// Register requests from clients
 	register chan *Client
 
 	// Unregister requests from clients
 	unregister chan *Client
 
-	// Broadcast message to all clients in a topic
+	// Broadcast message to all clients in a topic or of a user
 	broadcast chan *BroadcastMessage
 
+	// Fans out broadcasts to other API replicas (nil means local only)
+	broker Broker
+
+	// Used to check access of subscribing and connected clients
+	accessChecker AccessChecker
+
 	// Active users across WebSocket connections.
 	activeUsers           map[string]activeUserState
 	activeUserConnections int
@@ -44,9 +78,23 @@ type Hub struct {
 	mu sync.RWMutex
 }
 
+// BroadcastMessage is addressed either to a topic or, when UserID is set,
+// to every connection of that user.
 type BroadcastMessage struct {
 	TopicID uuid.UUID
+	UserID  uuid.UUID
 	Data    []byte
+
+	// Revalidate asks every replica to re-check access of matching clients
+	Revalidate *RevalidateRequest
+}
+
+// RevalidateRequest selects connected clients whose access must be re-checked.
+// Zero fields match everything.
+type RevalidateRequest struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	TopicID   uuid.UUID `json:"topic_id"`
+	UserID    uuid.UUID `json:"user_id"`
 }
 
 type activeUserState struct {
@@ -64,83 +112,292 @@ type WSMessagePayload struct {
 	Message MessageWithUser `json:"message"`
 }
 
+type WSThreadReplyPayload struct {
+	TopicID     uuid.UUID       `json:"topic_id"`
+	ParentID    uuid.UUID       `json:"parent_id"`
+	Message     MessageWithUser `json:"message"`
+	ReplyCount  int             `json:"reply_count"`
+	LastReplyAt *time.Time      `json:"last_reply_at,omitempty"`
+}
+
 type WSTypingPayload struct {
+	TopicID  uuid.UUID `json:"topic_id"`
 	UserID   uuid.UUID `json:"user_id"`
 	Name     string    `json:"name"`
 	IsTyping bool      `json:"is_typing"`
 }
 
 type WSReactionPayload struct {
+	TopicID   uuid.UUID       `json:"topic_id"`
 	MessageID uuid.UUID       `json:"message_id"`
 	Reactions []ReactionGroup `json:"reactions"`
 }
 
-// NewHub creates a new Hub
-func NewHub() *Hub {
+// WSSubscriptionPayload is sent by clients to (un)subscribe from a topic
+// and echoed back once the request is processed.
+type WSSubscriptionPayload struct {
+	TopicID  uuid.UUID `json:"topic_id"`
+	ReadOnly bool      `json:"read_only,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// NewHub creates a new Hub. Broadcasts are published through broker
+// so that clients connected to other replicas receive them too.
+func NewHub(broker Broker) *Hub {
 	return &Hub{
+		clients:               make(map[*Client]bool),
 		topics:                make(map[uuid.UUID]map[*Client]bool),
+		users:                 make(map[uuid.UUID]map[*Client]bool),
 		register:              make(chan *Client),
 		unregister:            make(chan *Client),
 		broadcast:             make(chan *BroadcastMessage, 256),
+		broker:                broker,
 		activeUsers:           make(map[string]activeUserState),
 		activeUserConnections: 0,
 	}
 }
 
+// NewClient creates a client for a user connection
+func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID) *Client {
+	return &Client{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Conn:          conn,
+		Hub:           hub,
+		Send:          make(chan []byte, 256),
+		subscriptions: make(map[uuid.UUID]*subscription),
+	}
+}
+
 // Run starts the hub
 func (h *Hub) Run() {
+	if broker := h.getBroker(); broker != nil {
+		if err := broker.Subscribe(context.Background(), h.deliver); err != nil {
+			log.Printf("WebSocket broker unavailable, falling back to local delivery: %v", err)
+			h.mu.Lock()
+			h.broker = nil
+			h.mu.Unlock()
+		}
+	}
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			if _, ok := h.topics[client.TopicID]; !ok {
-				h.topics[client.TopicID] = make(map[*Client]bool)
+			h.clients[client] = true
+			if _, ok := h.users[client.UserID]; !ok {
+				h.users[client.UserID] = make(map[*Client]bool)
+			}
+			h.users[client.UserID][client] = true
+			for _, topicID := range client.subscribedTopics() {
+				h.addToTopic(client, topicID)
 			}
-			h.topics[client.TopicID][client] = true
 			h.trackActiveUser(client.UserID.String(), true)
 			activeUsersTotal := len(h.activeUsers)
 			activeConnectionsTotal := h.activeUserConnections
 			h.mu.Unlock()
 			metrics.WebsocketConnected()
 			metrics.UpdateActiveUsers(activeUsersTotal, activeConnectionsTotal)
-			log.Printf("Client %s registered to topic %s", client.ID, client.TopicID)
+			log.Printf("Client %s registered for user %s", client.ID, client.UserID)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if clients, ok := h.topics[client.TopicID]; ok {
-				if _, ok := clients[client]; ok {
-					delete(clients, client)
-					close(client.Send)
-					if len(clients) == 0 {
-						delete(h.topics, client.TopicID)
-					}
-				}
+			if h.removeClient(client) {
+				log.Printf("Client %s unregistered for user %s", client.ID, client.UserID)
 			}
-			h.trackActiveUser(client.UserID.String(), false)
-			activeUsersTotal := len(h.activeUsers)
-			activeConnectionsTotal := h.activeUserConnections
-			h.mu.Unlock()
-			metrics.WebsocketDisconnected()
-			metrics.UpdateActiveUsers(activeUsersTotal, activeConnectionsTotal)
-			log.Printf("Client %s unregistered from topic %s", client.ID, client.TopicID)
 
 		case message := <-h.broadcast:
+			if message.Revalidate != nil {
+				h.revalidate(*message.Revalidate)
+				continue
+			}
+
 			h.mu.RLock()
-			clients := h.topics[message.TopicID]
+			targets := h.topics[message.TopicID]
+			if message.UserID != uuid.Nil {
+				targets = h.users[message.UserID]
+			}
+			clients := make([]*Client, 0, len(targets))
+			for client := range targets {
+				clients = append(clients, client)
+			}
 			h.mu.RUnlock()
 
-			for client := range clients {
-				select {
-				case client.Send <- message.Data:
-				default:
-					close(client.Send)
-					h.mu.Lock()
-					delete(h.topics[message.TopicID], client)
-					h.mu.Unlock()
+			for _, client := range clients {
+				if !client.send(message.Data) {
+					// Slow consumer, drop the connection
+					h.removeClient(client)
+				}
+			}
+		}
+	}
+}
+
+// addToTopic must be called with h.mu held
+func (h *Hub) addToTopic(client *Client, topicID uuid.UUID) {
+	if _, ok := h.topics[topicID]; !ok {
+		h.topics[topicID] = make(map[*Client]bool)
+	}
+	h.topics[topicID][client] = true
+}
+
+// removeFromTopic must be called with h.mu held
+func (h *Hub) removeFromTopic(client *Client, topicID uuid.UUID) {
+	clients, ok := h.topics[topicID]
+	if !ok {
+		return
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.topics, topicID)
+	}
+}
+
+// removeClient drops a client from the hub and closes its send channel.
+// It reports whether the client was still registered.
+func (h *Hub) removeClient(client *Client) bool {
+	h.mu.Lock()
+	if !h.clients[client] {
+		h.mu.Unlock()
+		return false
+	}
+
+	delete(h.clients, client)
+	if clients, ok := h.users[client.UserID]; ok {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.users, client.UserID)
+		}
+	}
+	for _, topicID := range client.subscribedTopics() {
+		h.removeFromTopic(client, topicID)
+	}
+	client.close()
+	h.trackActiveUser(client.UserID.String(), false)
+	activeUsersTotal := len(h.activeUsers)
+	activeConnectionsTotal := h.activeUserConnections
+	h.mu.Unlock()
+
+	metrics.WebsocketDisconnected()
+	metrics.UpdateActiveUsers(activeUsersTotal, activeConnectionsTotal)
+	return true
+}
+
+// SetAccessChecker sets the function used to check access of clients
+func (h *Hub) SetAccessChecker(checker AccessChecker) {
+	h.mu.Lock()
+	h.accessChecker = checker
+	h.mu.Unlock()
+}
+
+func (h *Hub) getAccessChecker() AccessChecker {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.accessChecker
+}
+
+// Subscribe checks the client's access to a topic and starts
+// delivering the topic's events to it.
+func (h *Hub) Subscribe(client *Client, topicID uuid.UUID) (*TopicAccess, error) {
+	checker := h.getAccessChecker()
+	if checker == nil {
+		return nil, ErrTopicAccessDenied
+	}
+
+	access, err := checker(topicID, client.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[client] {
+		return nil, ErrTopicAccessDenied
+	}
+	if !client.setSubscription(topicID, &subscription{
+		projectID: access.ProjectID,
+		readOnly:  !access.CanWrite,
+	}) {
+		return nil, ErrTooManySubscriptions
+	}
+	h.addToTopic(client, topicID)
+
+	return access, nil
+}
+
+// Unsubscribe stops delivering a topic's events to the client
+func (h *Hub) Unsubscribe(client *Client, topicID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.removeSubscription(topicID)
+	h.removeFromTopic(client, topicID)
+}
+
+// Revalidate re-checks access of matching clients on every replica
+// and drops the subscriptions that lost it.
+func (h *Hub) Revalidate(req RevalidateRequest) {
+	h.publish(&BroadcastMessage{Revalidate: &req})
+}
+
+type revalidateTarget struct {
+	client  *Client
+	topicID uuid.UUID
+}
+
+func (h *Hub) revalidate(req RevalidateRequest) {
+	h.mu.RLock()
+	checker := h.accessChecker
+	var targets []revalidateTarget
+	for topicID, clients := range h.topics {
+		if req.TopicID != uuid.Nil && topicID != req.TopicID {
+			continue
+		}
+		for client := range clients {
+			if req.UserID != uuid.Nil && client.UserID != req.UserID {
+				continue
+			}
+			if req.ProjectID != uuid.Nil && client.subscriptionProject(topicID) != req.ProjectID {
+				continue
+			}
+			targets = append(targets, revalidateTarget{client: client, topicID: topicID})
+		}
+	}
+	h.mu.RUnlock()
+
+	if checker == nil || len(targets) == 0 {
+		return
+	}
+
+	// Access checks hit the database, keep them off the hub loop
+	go func() {
+		for _, target := range targets {
+			access, err := checker(target.topicID, target.client.UserID)
+			if err != nil {
+				if errors.Is(err, ErrNotProjectMember) || errors.Is(err, ErrTopicAccessDenied) || errors.Is(err, ErrTopicNotFound) {
+					h.revoke(target.client, target.topicID)
+				} else {
+					log.Printf("Failed to re-check access for client %s: %v", target.client.ID, err)
 				}
+				continue
 			}
+			target.client.setReadOnly(target.topicID, !access.CanWrite)
 		}
+	}()
+}
+
+// revoke tells the client its access to a topic is gone and stops
+// delivering the topic's events to it
+func (h *Hub) revoke(client *Client, topicID uuid.UUID) {
+	client.sendEvent("access_revoked", map[string]string{
+		"topic_id": topicID.String(),
+	})
+	if client.topicScoped {
+		h.unregister <- client
+	} else {
+		h.Unsubscribe(client, topicID)
 	}
+	log.Printf("Client %s lost access to topic %s", client.ID, topicID)
 }
 
 func (h *Hub) trackActiveUser(userID string, connected bool) {
@@ -171,29 +428,183 @@ func (h *Hub) trackActiveUser(userID string, connected bool) {
 	}
 }
 
-// BroadcastToTopic sends a message to all clients in a topic
+func (h *Hub) getBroker() Broker {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.broker
+}
+
+// deliver queues a broadcast for the clients connected to this replica
+func (h *Hub) deliver(message *BroadcastMessage) {
+	h.broadcast <- message
+}
+
+// BroadcastToTopic sends a message to all clients in a topic on every replica
 func (h *Hub) BroadcastToTopic(topicID uuid.UUID, msgType string, payload interface{}) error {
-	payloadJSON, err := json.Marshal(payload)
+	messageJSON, err := encodeWSMessage(msgType, payload)
+	if err != nil {
+		return err
+	}
+
+	h.publish(&BroadcastMessage{
+		TopicID: topicID,
+		Data:    messageJSON,
+	})
+
+	return nil
+}
+
+// SendToUser sends a message to every connection of a user on every replica
+func (h *Hub) SendToUser(userID uuid.UUID, msgType string, payload interface{}) error {
+	messageJSON, err := encodeWSMessage(msgType, payload)
 	if err != nil {
 		return err
 	}
 
-	message := WSMessage{
+	h.publish(&BroadcastMessage{
+		UserID: userID,
+		Data:   messageJSON,
+	})
+
+	return nil
+}
+
+func encodeWSMessage(msgType string, payload interface{}) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(WSMessage{
 		Type:    msgType,
 		Payload: payloadJSON,
+	})
+}
+
+func (h *Hub) publish(message *BroadcastMessage) {
+	broker := h.getBroker()
+	if broker == nil {
+		h.deliver(message)
+		return
+	}
+
+	if err := broker.Publish(context.Background(), message); err != nil {
+		// Keep local clients working even if the broker is down
+		log.Printf("Failed to publish broadcast for topic %s: %v", message.TopicID, err)
+		h.deliver(message)
+	}
+}
+
+// send queues data for the client without blocking.
+// It reports false if the client is closed or its buffer is full.
+func (c *Client) send(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	select {
+	case c.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.Send)
 	}
+}
 
-	messageJSON, err := json.Marshal(message)
+// sendEvent queues a message for this client only
+func (c *Client) sendEvent(msgType string, payload interface{}) {
+	data, err := encodeWSMessage(msgType, payload)
 	if err != nil {
-		return err
+		log.Printf("Failed to encode WebSocket message: %v", err)
+		return
 	}
+	c.send(data)
+}
 
-	h.broadcast <- &BroadcastMessage{
-		TopicID: topicID,
-		Data:    messageJSON,
+func (c *Client) subscribedTopics() []uuid.UUID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	topics := make([]uuid.UUID, 0, len(c.subscriptions))
+	for topicID := range c.subscriptions {
+		topics = append(topics, topicID)
 	}
+	return topics
+}
 
-	return nil
+// setSubscription reports false if the subscription limit is reached
+func (c *Client) setSubscription(topicID uuid.UUID, sub *subscription) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subscriptions[topicID]; !ok && len(c.subscriptions) >= maxSubscriptionsPerClient {
+		return false
+	}
+	c.subscriptions[topicID] = sub
+	return true
+}
+
+func (c *Client) removeSubscription(topicID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.subscriptions, topicID)
+	c.mu.Unlock()
+}
+
+func (c *Client) subscriptionProject(topicID uuid.UUID) uuid.UUID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sub, ok := c.subscriptions[topicID]; ok {
+		return sub.projectID
+	}
+	return uuid.Nil
+}
+
+// canWrite reports whether the client may send activity to a subscribed topic
+func (c *Client) canWrite(topicID uuid.UUID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub, ok := c.subscriptions[topicID]
+	return ok && !sub.readOnly
+}
+
+func (c *Client) setReadOnly(topicID uuid.UUID, readOnly bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sub, ok := c.subscriptions[topicID]; ok {
+		sub.readOnly = readOnly
+	}
+}
+
+// resolveTopic falls back to the only subscribed topic for clients
+// that do not name one (the legacy per-topic endpoint)
+func (c *Client) resolveTopic(topicID uuid.UUID) uuid.UUID {
+	if topicID != uuid.Nil {
+		return topicID
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.subscriptions) == 1 {
+		for id := range c.subscriptions {
+			return id
+		}
+	}
+	return uuid.Nil
 }
 
 // ReadPump pumps messages from the websocket connection to the hub
@@ -227,22 +638,72 @@ func (c *Client) ReadPump() {
 
 		// Handle different message types
 		switch wsMsg.Type {
+		case "subscribe":
+			var subPayload WSSubscriptionPayload
+			if err := json.Unmarshal(wsMsg.Payload, &subPayload); err != nil || subPayload.TopicID == uuid.Nil {
+				c.sendEvent("error", map[string]string{"error": "Invalid topic ID"})
+				continue
+			}
+			access, err := c.Hub.Subscribe(c, subPayload.TopicID)
+			if err != nil {
+				c.sendEvent("subscribe_failed", WSSubscriptionPayload{
+					TopicID: subPayload.TopicID,
+					Error:   subscribeErrorMessage(err),
+				})
+				continue
+			}
+			c.sendEvent("subscribed", WSSubscriptionPayload{
+				TopicID:  subPayload.TopicID,
+				ReadOnly: !access.CanWrite,
+			})
+
+		case "unsubscribe":
+			var subPayload WSSubscriptionPayload
+			if err := json.Unmarshal(wsMsg.Payload, &subPayload); err != nil || subPayload.TopicID == uuid.Nil {
+				c.sendEvent("error", map[string]string{"error": "Invalid topic ID"})
+				continue
+			}
+			c.Hub.Unsubscribe(c, subPayload.TopicID)
+			c.sendEvent("unsubscribed", WSSubscriptionPayload{
+				TopicID: subPayload.TopicID,
+			})
+
 		case "typing":
 			// Broadcast typing indicator to other clients
 			var typingPayload WSTypingPayload
 			if err := json.Unmarshal(wsMsg.Payload, &typingPayload); err != nil {
 				continue
 			}
+			typingPayload.TopicID = c.resolveTopic(typingPayload.TopicID)
+
+			// Read-only and unsubscribed clients can not signal activity
+			if !c.canWrite(typingPayload.TopicID) {
+				continue
+			}
 			typingPayload.UserID = c.UserID
-			c.Hub.BroadcastToTopic(c.TopicID, "typing", typingPayload)
+			c.Hub.BroadcastToTopic(typingPayload.TopicID, "typing", typingPayload)
 
 		case "ping":
 			// Respond with pong
-			c.Send <- []byte(`{"type":"pong"}`)
+			c.send([]byte(`{"type":"pong"}`))
 		}
 	}
 }
 
+func subscribeErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrTopicNotFound):
+		return "Topic not found"
+	case errors.Is(err, ErrNotProjectMember), errors.Is(err, ErrTopicAccessDenied):
+		return "Access denied"
+	case errors.Is(err, ErrTooManySubscriptions):
+		return "Too many subscriptions"
+	default:
+		log.Printf("Failed to subscribe to topic: %v", err)
+		return "Failed to subscribe"
+	}
+}
+
 // WritePump pumps messages from the hub to the websocket connection
 func (c *Client) WritePump() {
 	ticker := time.NewTicker(54 * time.Second)
@@ -1,11 +1,15 @@
 package message
 
 import (
+	"encoding/json"
+	"errors"
 	"log"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 	"github.com/google/uuid"
 	"github.com/m0khm/devhub/backend/internal/notification"
+	"github.com/m0khm/devhub/backend/internal/topic"
 )
 
 type WSHandler struct {
@@ -14,17 +18,83 @@ type WSHandler struct {
 }
 
 func NewWSHandler(hub *Hub, service *Service) *WSHandler {
+	hub.SetAccessChecker(service.GetTopicAccess)
 	return &WSHandler{
 		hub:     hub,
 		service: service,
 	}
 }
 
-// HandleWebSocket handles WebSocket connections
+// AuthorizeTopic checks topic access before the WebSocket upgrade.
+// Must run after the middleware that sets userID.
+func (h *WSHandler) AuthorizeTopic(c *fiber.Ctx) error {
+	userIDStr, _ := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	topicID, err := uuid.Parse(c.Params("topicId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid topic ID",
+		})
+	}
+
+	access, err := h.service.GetTopicAccess(topicID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTopicNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Topic not found",
+			})
+		case errors.Is(err, ErrNotProjectMember), errors.Is(err, ErrTopicAccessDenied):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Access denied",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check topic access",
+			})
+		}
+	}
+
+	c.Locals("projectID", access.ProjectID.String())
+	c.Locals("topicReadOnly", !access.CanWrite)
+	return c.Next()
+}
+
+// HandleUserWebSocket handles the multiplexed user connection.
+// Topics are followed with subscribe/unsubscribe frames and
+// user-scoped events are delivered without any subscription.
+// GET /api/ws
+func (h *WSHandler) HandleUserWebSocket(c *websocket.Conn) {
+	userIDStr, _ := c.Locals("userID").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("Invalid user ID: %v", err)
+		c.Close()
+		return
+	}
+
+	client := NewClient(h.hub, c, userID)
+
+	// Register client
+	h.hub.register <- client
+
+	// Start pumps
+	go client.WritePump()
+	client.ReadPump() // This blocks until connection closes
+}
+
+// HandleWebSocket handles WebSocket connections bound to a single topic.
+// Kept for older clients, new clients use /api/ws.
 // GET /api/topics/:topicId/ws
 func (h *WSHandler) HandleWebSocket(c *websocket.Conn) {
 	// Get user ID from query params (set by middleware)
-	userIDStr := c.Locals("userID").(string)
+	userIDStr, _ := c.Locals("userID").(string)
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		log.Printf("Invalid user ID: %v", err)
@@ -41,17 +111,22 @@ func (h *WSHandler) HandleWebSocket(c *websocket.Conn) {
 		return
 	}
 
-	// TODO: Check if user has access to topic
-	// For now, we'll skip this check
+	// Access was checked by AuthorizeTopic before the upgrade
+	projectIDStr, _ := c.Locals("projectID").(string)
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		log.Printf("Missing topic access for client of topic %s", topicID)
+		c.Close()
+		return
+	}
+	readOnly, _ := c.Locals("topicReadOnly").(bool)
 
 	// Create client
-	client := &Client{
-		ID:      uuid.New().String(),
-		UserID:  userID,
-		TopicID: topicID,
-		Conn:    c,
-		Hub:     h.hub,
-		Send:    make(chan []byte, 256),
+	client := NewClient(h.hub, c, userID)
+	client.topicScoped = true
+	client.subscriptions[topicID] = &subscription{
+		projectID: projectID,
+		readOnly:  readOnly,
 	}
 
 	// Register client
@@ -70,6 +145,49 @@ func (h *WSHandler) BroadcastNewMessage(message *MessageWithUser) {
 	h.hub.BroadcastToTopic(message.TopicID, "new_message", payload)
 }
 
+// PostSystemMessage posts and broadcasts a system message to a project topic of
+// topicType. Projects without such a topic are skipped.
+func (h *WSHandler) PostSystemMessage(projectID uuid.UUID, topicType, content string, metadata map[string]any) {
+	var encoded *string
+	if metadata != nil {
+		raw, err := json.Marshal(metadata)
+		if err != nil {
+			log.Printf("failed to encode system message metadata: %v", err)
+			return
+		}
+		value := string(raw)
+		encoded = &value
+	}
+
+	created, err := h.service.CreateProjectSystem(projectID, topicType, content, encoded)
+	if err != nil {
+		if !errors.Is(err, ErrTopicNotFound) {
+			log.Printf("failed to post system message to project %s: %v", projectID, err)
+		}
+		return
+	}
+	h.BroadcastNewMessage(created)
+}
+
+// BroadcastThreadReply broadcasts a new reply with the updated thread info
+func (h *WSHandler) BroadcastThreadReply(reply *MessageWithUser, summary *ThreadSummary) {
+	payload := WSThreadReplyPayload{
+		TopicID:    reply.TopicID,
+		ParentID:   summary.ParentID,
+		Message:    *reply,
+		ReplyCount: summary.ReplyCount,
+	}
+	if !summary.LastReplyAt.IsZero() {
+		payload.LastReplyAt = &summary.LastReplyAt
+	}
+	h.hub.BroadcastToTopic(reply.TopicID, "thread_reply", payload)
+}
+
+// BroadcastReadReceipt broadcasts how far a user has read a topic
+func (h *WSHandler) BroadcastReadReceipt(cursor *topic.ReadCursor) {
+	h.hub.BroadcastToTopic(cursor.TopicID, "read_receipt", cursor)
+}
+
 // BroadcastMessageUpdate broadcasts message update
 func (h *WSHandler) BroadcastMessageUpdate(message *MessageWithUser) {
 	payload := WSMessagePayload{
@@ -81,6 +199,7 @@ func (h *WSHandler) BroadcastMessageUpdate(message *MessageWithUser) {
 // BroadcastMessageDelete broadcasts message deletion
 func (h *WSHandler) BroadcastMessageDelete(topicID, messageID uuid.UUID) {
 	payload := map[string]string{
+		"topic_id":   topicID.String(),
 		"message_id": messageID.String(),
 	}
 	h.hub.BroadcastToTopic(topicID, "message_deleted", payload)
@@ -89,16 +208,40 @@ func (h *WSHandler) BroadcastMessageDelete(topicID, messageID uuid.UUID) {
 // BroadcastReactionUpdate broadcasts reaction update
 func (h *WSHandler) BroadcastReactionUpdate(topicID, messageID uuid.UUID, reactions []ReactionGroup) {
 	payload := WSReactionPayload{
+		TopicID:   topicID,
 		MessageID: messageID,
 		Reactions: reactions,
 	}
 	h.hub.BroadcastToTopic(topicID, "reaction_updated", payload)
 }
 
-// BroadcastNotificationCreated broadcasts a new notification event
-func (h *WSHandler) BroadcastNotificationCreated(topicID uuid.UUID, item notification.Notification) {
+// BroadcastNotificationCreated sends a new notification to its recipient only
+func (h *WSHandler) BroadcastNotificationCreated(item notification.Notification) {
 	payload := map[string]interface{}{
 		"notification": item,
 	}
-	h.hub.BroadcastToTopic(topicID, "notification_created", payload)
+	h.hub.SendToUser(item.UserID, "notification_created", payload)
+}
+
+// SendToUser sends a user-scoped event to every connection of the user
+func (h *WSHandler) SendToUser(userID uuid.UUID, eventType string, payload interface{}) {
+	if err := h.hub.SendToUser(userID, eventType, payload); err != nil {
+		log.Printf("Failed to send %s event to user %s: %v", eventType, userID, err)
+	}
+}
+
+// RevalidateProjectMember re-checks access of a user's connections to a project.
+// A nil userID re-checks every connection to the project.
+func (h *WSHandler) RevalidateProjectMember(projectID, userID uuid.UUID) {
+	h.hub.Revalidate(RevalidateRequest{
+		ProjectID: projectID,
+		UserID:    userID,
+	})
+}
+
+// RevalidateTopic re-checks access of every connection to a topic
+func (h *WSHandler) RevalidateTopic(topicID uuid.UUID) {
+	h.hub.Revalidate(RevalidateRequest{
+		TopicID: topicID,
+	})
 }
@@ -0,0 +1,161 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/m0khm/devhub/backend/internal/notification"
+	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/topic"
+	"github.com/m0khm/devhub/backend/internal/user"
+)
+
+// recordingBroker keeps the broadcasts published by the hub
+type recordingBroker struct {
+	mu       sync.Mutex
+	messages []*BroadcastMessage
+}
+
+func (b *recordingBroker) Publish(ctx context.Context, msg *BroadcastMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+func (b *recordingBroker) Subscribe(ctx context.Context, handler func(*BroadcastMessage)) error {
+	return nil
+}
+
+func (b *recordingBroker) Close() error {
+	return nil
+}
+
+func (b *recordingBroker) events() []WSMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := make([]WSMessage, 0, len(b.messages))
+	for _, msg := range b.messages {
+		var event WSMessage
+		if err := json.Unmarshal(msg.Data, &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func newMockService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(NewRepository(db), topic.NewRepository(db), project.NewRepository(db),
+		notification.NewRepository(db), user.NewRepository(db))
+	return service, mock
+}
+
+func TestPostSystemMessageBroadcasts(t *testing.T) {
+	service, mock := newMockService(t)
+	topicID, projectID, messageID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT \* FROM "topics" WHERE project_id = \$1 AND type = \$2`).
+		WithArgs(projectID, "deploy", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "type", "access_level", "visibility"}).
+			AddRow(topicID, projectID, "deploy", "deploy", "members", "visible"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "messages"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(messageID, "system"))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM "messages" LEFT JOIN users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic_id", "content", "type", "created_at", "updated_at"}).
+			AddRow(messageID, topicID, "Shared terminal started", "system", now, now))
+	mock.ExpectQuery(`SELECT message_id, emoji, user_id FROM "message_reactions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "user_id"}))
+	mock.ExpectQuery(`SELECT DISTINCT ON \(parent_id\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id", "reply_count", "last_reply_at", "last_reply_user_id"}))
+
+	broker := &recordingBroker{}
+	handler := NewWSHandler(NewHub(broker), service)
+	handler.PostSystemMessage(projectID, "deploy", "Shared terminal started", map[string]any{"action": "terminal_started"})
+
+	events := broker.events()
+	if len(events) != 1 || events[0].Type != "new_message" {
+		t.Fatalf("broadcasts = %+v, want one new_message", events)
+	}
+	var payload WSMessagePayload
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Message.ID != messageID || payload.Message.TopicID != topicID {
+		t.Fatalf("broadcast message %s in topic %s, want %s in %s", payload.Message.ID, payload.Message.TopicID, messageID, topicID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateIntegrationDispatchesOnlyMemberTopics(t *testing.T) {
+	cases := []struct {
+		name        string
+		topicType   string
+		accessLevel string
+		want        bool
+	}{
+		{"members", "chat", "members", true},
+		{"public", "chat", "public", true},
+		{"admins only", "chat", "admins", false},
+		{"direct", "direct", "members", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mock := newMockService(t)
+			topicID, projectID, messageID := uuid.New(), uuid.New(), uuid.New()
+			now := time.Now()
+
+			mock.ExpectQuery(`SELECT \* FROM "topics" WHERE id = \$1`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "type", "access_level", "visibility"}).
+					AddRow(topicID, projectID, "topic", tc.topicType, tc.accessLevel, "visible"))
+			mock.ExpectBegin()
+			mock.ExpectQuery(`INSERT INTO "messages"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(messageID, "integration"))
+			mock.ExpectCommit()
+			mock.ExpectQuery(`SELECT .* FROM "messages" LEFT JOIN users`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "topic_id", "content", "type", "created_at", "updated_at"}).
+					AddRow(messageID, topicID, "Build passed", "integration", now, now))
+			mock.ExpectQuery(`SELECT message_id, emoji, user_id FROM "message_reactions"`).
+				WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "user_id"}))
+			mock.ExpectQuery(`SELECT DISTINCT ON \(parent_id\)`).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id", "reply_count", "last_reply_at", "last_reply_user_id"}))
+
+			var events []string
+			service.SetProjectEventHook(func(projectID uuid.UUID, event string, data interface{}) {
+				events = append(events, event)
+			})
+			if _, err := service.CreateIntegration(topicID, "Build passed", nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(events) == 1 && events[0] == project.EventMessageCreated; got != tc.want {
+				t.Fatalf("events = %v, want dispatched %v", events, tc.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
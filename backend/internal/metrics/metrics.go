@@ -29,6 +29,8 @@ type store struct {
 	durations          map[labelKey]durationSummary
 	registrationsTotal uint64
 	registrationsByDay map[string]uint64
+	rateLimited        map[string]uint64
+	loginLockouts      uint64
 }
 
 var metricsStore = store{
@@ -36,6 +38,7 @@ var metricsStore = store{
 	errors:             make(map[labelKey]uint64),
 	durations:          make(map[labelKey]durationSummary),
 	registrationsByDay: make(map[string]uint64),
+	rateLimited:        make(map[string]uint64),
 }
 
 var wsConnections int64
@@ -96,6 +99,20 @@ func RecordRegistration(at time.Time) {
 	metricsStore.mu.Unlock()
 }
 
+// RecordRateLimited counts a request rejected by a rate limit policy
+func RecordRateLimited(policy string) {
+	metricsStore.mu.Lock()
+	metricsStore.rateLimited[policy]++
+	metricsStore.mu.Unlock()
+}
+
+// RecordLoginLockout counts an account locked after repeated failed logins
+func RecordLoginLockout() {
+	metricsStore.mu.Lock()
+	metricsStore.loginLockouts++
+	metricsStore.mu.Unlock()
+}
+
 func Handler(c *fiber.Ctx) error {
 	c.Set("Content-Type", "text/plain; version=0.0.4")
 
@@ -130,10 +147,21 @@ func Handler(c *fiber.Ctx) error {
 
 	builder.WriteString("# HELP devhub_user_registrations_daily_total Total number of user registrations per day.\n")
 	builder.WriteString("# TYPE devhub_user_registrations_daily_total counter\n")
-	dayKeys := sortedDayKeys(metricsStore.registrationsByDay)
+	dayKeys := sortedStringKeys(metricsStore.registrationsByDay)
 	for _, day := range dayKeys {
 		builder.WriteString(fmt.Sprintf("devhub_user_registrations_daily_total{day=%q} %d\n", escapeLabelValue(day), metricsStore.registrationsByDay[day]))
 	}
+
+	builder.WriteString("# HELP devhub_rate_limited_requests_total Total number of requests rejected by a rate limit policy.\n")
+	builder.WriteString("# TYPE devhub_rate_limited_requests_total counter\n")
+	policies := sortedStringKeys(metricsStore.rateLimited)
+	for _, policy := range policies {
+		builder.WriteString(fmt.Sprintf("devhub_rate_limited_requests_total{policy=%q} %d\n", escapeLabelValue(policy), metricsStore.rateLimited[policy]))
+	}
+
+	builder.WriteString("# HELP devhub_login_lockouts_total Total number of accounts locked after repeated failed logins.\n")
+	builder.WriteString("# TYPE devhub_login_lockouts_total counter\n")
+	builder.WriteString(fmt.Sprintf("devhub_login_lockouts_total %d\n", metricsStore.loginLockouts))
 	metricsStore.mu.Unlock()
 
 	builder.WriteString("# HELP devhub_ws_connections Number of active WebSocket connections.\n")
@@ -168,7 +196,7 @@ func sortedKeys[T any](input map[labelKey]T) []labelKey {
 	return keys
 }
 
-func sortedDayKeys(input map[string]uint64) []string {
+func sortedStringKeys(input map[string]uint64) []string {
 	keys := make([]string, 0, len(input))
 	for key := range input {
 		keys = append(keys, key)
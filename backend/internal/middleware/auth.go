@@ -4,10 +4,13 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 	"github.com/m0khm/devhub/backend/internal/auth"
 )
 
-func Auth(jwtManager *auth.JWTManager) fiber.Handler {
+// Auth accepts session JWTs and personal access tokens.
+// Personal access tokens are limited to the scopes of the requested route.
+func Auth(authenticator *auth.Authenticator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Extract token from Authorization header
 		authHeader := c.Get("Authorization")
@@ -28,17 +31,67 @@ func Auth(jwtManager *auth.JWTManager) fiber.Handler {
 		tokenString := parts[1]
 
 		// Verify token
-		claims, err := jwtManager.Verify(tokenString)
+		identity, err := authenticator.Authenticate(tokenString)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid or expired token",
 			})
 		}
 
-		// Set user info in context
-		c.Locals("userID", claims.UserID.String())
-		c.Locals("email", claims.Email)
+		if !allowedByScope(identity, c.Method(), c.Path()) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Token scope does not allow this request",
+			})
+		}
+
+		setIdentity(c, identity)
+		return c.Next()
+	}
+}
+
+// WSAuth authenticates WebSocket upgrades. Browsers cannot set headers on
+// WebSocket requests, so the token may also come from the "token" query parameter.
+func WSAuth(authenticator *auth.Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+
+		// token from query or header
+		token := c.Query("token")
+		if token == "" {
+			authHeader := c.Get("Authorization")
+			if strings.HasPrefix(authHeader, "Bearer ") {
+				token = strings.TrimPrefix(authHeader, "Bearer ")
+			}
+		}
 
+		if token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing token"})
+		}
+
+		identity, err := authenticator.Authenticate(token)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+		}
+
+		if !allowedByScope(identity, c.Method(), c.Path()) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Token scope does not allow this request"})
+		}
+
+		setIdentity(c, identity)
 		return c.Next()
 	}
 }
+
+func setIdentity(c *fiber.Ctx, identity *auth.Identity) {
+	c.Locals("userID", identity.UserID.String())
+	c.Locals("email", identity.Email)
+	if identity.SessionID != nil {
+		c.Locals("sessionID", identity.SessionID.String())
+	}
+	if identity.IsScoped() {
+		c.Locals("tokenID", identity.TokenID.String())
+		c.Locals("scopes", identity.Scopes)
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/m0khm/devhub/backend/internal/config"
+	"github.com/m0khm/devhub/backend/internal/metrics"
+	"github.com/m0khm/devhub/backend/internal/ratelimit"
+)
+
+// RateLimitKey picks the subject a request is counted against
+type RateLimitKey func(c *fiber.Ctx) string
+
+// ByIP counts requests per client IP
+func ByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// ByUser counts requests per authenticated user and falls back to the client IP.
+// It must run after Auth.
+func ByUser(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return ByIP(c)
+}
+
+// ByEmail counts requests per address in the JSON "email" field, so spreading
+// them over many IPs does not send more codes to one inbox. Requests without
+// an email fall back to the client IP.
+func ByEmail(c *fiber.Ctx) string {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err == nil {
+		if email := strings.ToLower(strings.TrimSpace(body.Email)); email != "" {
+			return "email:" + email
+		}
+	}
+	return ByIP(c)
+}
+
+// RateLimit rejects requests over the policy with 429 and reports the state of
+// the window in X-RateLimit-* headers. A nil limiter or a zero limit disables it.
+// Requests are let through when Redis is unavailable.
+func RateLimit(limiter *ratelimit.Limiter, policy string, rate config.RateLimitPolicy, key RateLimitKey) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if limiter == nil || rate.Limit <= 0 {
+			return c.Next()
+		}
+
+		result, err := limiter.Allow(c.UserContext(), policy, key(c), rate.Limit, rate.Window)
+		if err != nil {
+			log.Printf("rate limit %s: %v", policy, err)
+			return c.Next()
+		}
+
+		resetSeconds := strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds())))
+		c.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("X-RateLimit-Reset", resetSeconds)
+
+		if !result.Allowed {
+			metrics.RecordRateLimited(policy)
+			c.Set(fiber.HeaderRetryAfter, resetSeconds)
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests, try again later",
+			})
+		}
+
+		return c.Next()
+	}
+}
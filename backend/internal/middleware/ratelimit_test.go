@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestByEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"normalized email", `{"email": "  Alice@Example.COM "}`, "email:alice@example.com"},
+		{"missing email", `{"name": "alice"}`, "ip:0.0.0.0"},
+		{"invalid body", `email=alice@example.com`, "ip:0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			var got string
+			app.Post("/", func(c *fiber.Ctx) error {
+				got = ByEmail(c)
+				return nil
+			})
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("ByEmail = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
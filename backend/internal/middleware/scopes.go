@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/m0khm/devhub/backend/internal/auth"
+)
+
+// scopeRule maps a route prefix to the scope a personal access token needs.
+// read applies to GET and HEAD, write to every other method.
+// An empty scope means the route only accepts session tokens.
+type scopeRule struct {
+	pattern *regexp.Regexp
+	read    string
+	write   string
+}
+
+func rule(pattern, read, write string) scopeRule {
+	return scopeRule{pattern: regexp.MustCompile(pattern), read: read, write: write}
+}
+
+// scopeRules are matched in order, the first match wins.
+// Routes without a rule are closed to personal access tokens.
+var scopeRules = []scopeRule{
+	// Credentials are only managed from a session
+	rule(`^/api/users/me/(tokens|password)`, "", ""),
+	rule(`^/api/auth/(sessions|logout|mfa|github)`, "", ""),
+	rule(`^/api/projects/[^/]+/bots`, "", ""),
+
+	rule(`^/api/auth/me$`, auth.ScopeUserRead, ""),
+	rule(`^/api/ws$`, auth.ScopeMessagesRead, auth.ScopeMessagesRead),
+	rule(`^/api/topics/[^/]+/ws$`, auth.ScopeMessagesRead, auth.ScopeMessagesRead),
+
+	rule(`^/api/projects/[^/]+/deploy/(servers/[^/]+/(terminal|sessions|files)|sessions|runs|scripts/[^/]+/runs|pipelines/[^/]+/runs|pipeline-runs)`, auth.ScopeDeployTerminal, auth.ScopeDeployTerminal),
+	rule(`^/api/projects/[^/]+/deploy`, auth.ScopeDeployRead, auth.ScopeDeployWrite),
+	rule(`^/api/projects/[^/]+/webhooks`, auth.ScopeWebhooksManage, auth.ScopeWebhooksManage),
+	rule(`^/api/projects/[^/]+/repos`, auth.ScopeCodeRead, auth.ScopeCodeWrite),
+	rule(`^/api/projects/[^/]+/topics`, auth.ScopeTopicsRead, auth.ScopeTopicsWrite),
+	rule(`^/api/projects/[^/]+/dm`, auth.ScopeMessagesRead, auth.ScopeMessagesWrite),
+	rule(`^/api/projects`, auth.ScopeProjectsRead, auth.ScopeProjectsWrite),
+	rule(`^/api/project-invitations`, auth.ScopeProjectsWrite, auth.ScopeProjectsWrite),
+
+	rule(`^/api/topics/[^/]+/incoming-webhooks`, auth.ScopeWebhooksManage, auth.ScopeWebhooksManage),
+	rule(`^/api/topics/[^/]+/(messages|pins|search|upload|commands|video)`, auth.ScopeMessagesRead, auth.ScopeMessagesWrite),
+	rule(`^/api/topics`, auth.ScopeTopicsRead, auth.ScopeTopicsWrite),
+	rule(`^/api/messages`, auth.ScopeMessagesRead, auth.ScopeMessagesWrite),
+	rule(`^/api/files`, auth.ScopeMessagesRead, ""),
+	rule(`^/api/dm`, auth.ScopeMessagesRead, auth.ScopeMessagesWrite),
+	rule(`^/api/search$`, auth.ScopeMessagesRead, ""),
+	rule(`^/api/notifications`, auth.ScopeNotificationsRead, auth.ScopeNotificationsRead),
+
+	rule(`^/api/users/?$`, auth.ScopeUserRead, ""),
+	rule(`^/api/(groups|communities)/?$`, auth.ScopeUserRead, ""),
+}
+
+func allowedByScope(identity *auth.Identity, method, path string) bool {
+	if !identity.IsScoped() {
+		return true
+	}
+
+	// Routes are case sensitive, so other casings never reach a handler. The
+	// rules still compare lower case paths in case that setting is dropped.
+	path = strings.ToLower(path)
+	for _, r := range scopeRules {
+		if !r.pattern.MatchString(path) {
+			continue
+		}
+		scope := r.write
+		if method == "GET" || method == "HEAD" {
+			scope = r.read
+		}
+		return scope != "" && identity.HasScope(scope)
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/m0khm/devhub/backend/internal/auth"
+)
+
+func TestAllowedByScope(t *testing.T) {
+	tokenID := uuid.New()
+	token := func(scopes ...string) *auth.Identity {
+		return &auth.Identity{UserID: uuid.New(), Scopes: scopes, TokenID: &tokenID}
+	}
+	project := "/api/projects/" + uuid.NewString()
+	server := project + "/deploy/servers/" + uuid.NewString()
+
+	tests := []struct {
+		name     string
+		identity *auth.Identity
+		method   string
+		path     string
+		want     bool
+	}{
+		{"session token is not scoped", &auth.Identity{UserID: uuid.New()}, "POST", project + "/bots/x/tokens", true},
+		{"projects write reaches projects", token(auth.ScopeProjectsWrite), "PUT", project, true},
+		{"bots are session only", token(auth.ScopeProjectsWrite), "POST", project + "/bots/x/tokens", false},
+		{"bots are session only in any case", token(auth.ScopeProjectsWrite), "POST", project + "/BOTS/x/tokens", false},
+		{"bots are session only in mixed case", token(auth.ScopeProjectsWrite), "POST", "/API/Projects/x/Bots/y/tokens", false},
+		{"files need the terminal scope", token(auth.ScopeProjectsWrite, auth.ScopeDeployRead), "GET", server + "/files", false},
+		{"files need the terminal scope in any case", token(auth.ScopeProjectsWrite, auth.ScopeDeployRead), "GET", project + "/Deploy/servers/x/FILES", false},
+		{"terminal scope reaches files", token(auth.ScopeDeployTerminal), "GET", server + "/files", true},
+		{"terminal scope reaches files in any case", token(auth.ScopeDeployTerminal), "GET", project + "/Deploy/Servers/x/Files", true},
+		{"deploy write is not enough for pipelines", token(auth.ScopeDeployWrite), "POST", project + "/deploy/pipelines/x/runs", false},
+		{"deploy write changes servers", token(auth.ScopeDeployWrite), "POST", project + "/deploy/servers", true},
+		{"deploy read cannot write", token(auth.ScopeDeployRead), "POST", project + "/DEPLOY/servers", false},
+		{"webhooks need their scope", token(auth.ScopeProjectsWrite), "POST", project + "/WebHooks", false},
+		{"password is session only", token(auth.ScopeUserRead), "PUT", "/api/users/me/Password", false},
+		{"unknown routes are closed", token(auth.ScopeProjectsRead), "GET", "/api/admin", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedByScope(tt.identity, tt.method, tt.path); got != tt.want {
+				t.Errorf("allowedByScope(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
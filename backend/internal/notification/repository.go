@@ -15,6 +15,11 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// WithTx returns a repository that writes inside the transaction tx
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
 func (r *Repository) Create(notification *Notification) error {
 	return r.db.Create(notification).Error
 }
@@ -52,6 +52,15 @@ func (s *Service) CreateMessageNotifications(topicID, authorID uuid.UUID, conten
 		return nil, fmt.Errorf("failed to get members: %w", err)
 	}
 
+	mutedIDs, err := s.topicRepo.GetMutedUserIDs(topicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get muted users: %w", err)
+	}
+	muted := make(map[uuid.UUID]struct{}, len(mutedIDs))
+	for _, mutedID := range mutedIDs {
+		muted[mutedID] = struct{}{}
+	}
+
 	title := fmt.Sprintf("New message in #%s", topicObj.Name)
 	body := truncateContent(content, 140)
 	link := fmt.Sprintf("/projects/%s", topicObj.ProjectID)
@@ -60,6 +69,9 @@ func (s *Service) CreateMessageNotifications(topicID, authorID uuid.UUID, conten
 		if memberID == authorID {
 			continue
 		}
+		if _, isMuted := muted[memberID]; isMuted {
+			continue
+		}
 		notifications = append(notifications, Notification{
 			UserID: memberID,
 			Title:  title,
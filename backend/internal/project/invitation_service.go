@@ -22,8 +22,15 @@ var (
 type InvitationService struct {
 	repo     *Repository
 	userRepo *user.Repository
+
+	// optional, delivers live events to a single user
+	eventHook        UserEventFunc
+	projectEventHook ProjectEventFunc
 }
 
+// UserEventFunc delivers a live event to every connection of a user
+type UserEventFunc func(userID uuid.UUID, eventType string, payload interface{})
+
 func NewInvitationService(repo *Repository, userRepo *user.Repository) *InvitationService {
 	return &InvitationService{
 		repo:     repo,
@@ -31,6 +38,30 @@ func NewInvitationService(repo *Repository, userRepo *user.Repository) *Invitati
 	}
 }
 
+// SetEventHook sets the callback used to push invitation updates (called from main.go)
+func (s *InvitationService) SetEventHook(hook UserEventFunc) {
+	s.eventHook = hook
+}
+
+// SetProjectEventHook sets the callback used to publish project events (called from main.go)
+func (s *InvitationService) SetProjectEventHook(hook ProjectEventFunc) {
+	s.projectEventHook = hook
+}
+
+func (s *InvitationService) emit(projectID uuid.UUID, event string, data interface{}) {
+	if s.projectEventHook != nil {
+		s.projectEventHook(projectID, event, data)
+	}
+}
+
+func (s *InvitationService) notify(userID uuid.UUID, eventType string, invitation *ProjectInvitation) {
+	if s.eventHook != nil {
+		s.eventHook(userID, eventType, map[string]interface{}{
+			"invitation": invitation,
+		})
+	}
+}
+
 func (s *InvitationService) Create(projectID, inviterID uuid.UUID, req CreateProjectInvitationRequest) (*ProjectInvitation, error) {
 	role, err := s.repo.GetUserRole(projectID, inviterID)
 	if err != nil {
@@ -107,6 +138,8 @@ func (s *InvitationService) Create(projectID, inviterID uuid.UUID, req CreatePro
 		return nil, fmt.Errorf("failed to create invitation: %w", err)
 	}
 
+	s.notify(invitation.InviteeID, "invitation_created", invitation)
+
 	return invitation, nil
 }
 
@@ -155,6 +188,18 @@ func (s *InvitationService) Accept(invitationID, userID uuid.UUID) (*ProjectInvi
 		return nil, fmt.Errorf("failed to accept invitation: %w", err)
 	}
 
+	s.notify(invitation.InviterID, "invitation_updated", invitation)
+	s.notify(invitation.InviteeID, "invitation_updated", invitation)
+
+	s.emit(invitation.ProjectID, EventInvitationAccepted, map[string]interface{}{
+		"invitation": invitation,
+	})
+	s.emit(invitation.ProjectID, EventMemberAdded, map[string]interface{}{
+		"user_id":  userID,
+		"role":     "member",
+		"added_by": invitation.InviterID,
+	})
+
 	return invitation, nil
 }
 
@@ -180,5 +225,8 @@ func (s *InvitationService) Decline(invitationID, userID uuid.UUID) (*ProjectInv
 		return nil, fmt.Errorf("failed to decline invitation: %w", err)
 	}
 
+	s.notify(invitation.InviterID, "invitation_updated", invitation)
+	s.notify(invitation.InviteeID, "invitation_updated", invitation)
+
 	return invitation, nil
 }
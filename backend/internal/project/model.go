@@ -14,9 +14,11 @@ type Project struct {
 	AccessLevel        string    `json:"access_level" gorm:"not null;default:'private'"`
 	Visibility         string    `json:"visibility" gorm:"not null;default:'visible'"`
 	NotificationsMuted bool      `json:"notifications_muted" gorm:"not null;default:false"`
-	OwnerID            uuid.UUID `json:"owner_id" gorm:"not null"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	// RequireTwoFactor blocks deploy terminals for members without 2FA
+	RequireTwoFactor bool      `json:"require_two_factor" gorm:"not null;default:false"`
+	OwnerID          uuid.UUID `json:"owner_id" gorm:"not null"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 type ProjectMember struct {
@@ -43,6 +45,8 @@ type UpdateProjectRequest struct {
 	AccessLevel        *string `json:"access_level" validate:"omitempty,oneof=private members public"`
 	Visibility         *string `json:"visibility" validate:"omitempty,oneof=visible hidden archived"`
 	NotificationsMuted *bool   `json:"notifications_muted"`
+	// Only the owner may change the 2FA policy
+	RequireTwoFactor *bool `json:"require_two_factor"`
 }
 
 type AddProjectMemberRequest struct {
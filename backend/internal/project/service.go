@@ -20,12 +20,55 @@ var (
 
 type Service struct {
 	repo *Repository
+
+	// optional, notified when a member's access to a project changes
+	accessChangeHook AccessChangeFunc
+	projectEventHook ProjectEventFunc
 }
 
+// AccessChangeFunc is called after project access changes.
+// userID is uuid.Nil when every member of the project is affected.
+type AccessChangeFunc func(projectID, userID uuid.UUID)
+
+// ProjectEventFunc publishes a project event to integrations such as outgoing webhooks
+type ProjectEventFunc func(projectID uuid.UUID, event string, data interface{})
+
+// Project events published through ProjectEventFunc. The webhook package
+// exposes them to subscribers, it cannot be imported from here.
+const (
+	EventMessageCreated          = "message.created"
+	EventMemberAdded             = "member.added"
+	EventInvitationAccepted      = "invitation.accepted"
+	EventDeployTerminalOpened    = "deploy.terminal_opened"
+	EventDeployScriptRunFinished = "deploy.script_run_finished"
+)
+
 func NewService(repo *Repository) *Service {
 	return &Service{repo: repo}
 }
 
+// SetAccessChangeHook sets the callback used to re-check live connections (called from main.go)
+func (s *Service) SetAccessChangeHook(hook AccessChangeFunc) {
+	s.accessChangeHook = hook
+}
+
+// SetProjectEventHook sets the callback used to publish project events (called from main.go)
+func (s *Service) SetProjectEventHook(hook ProjectEventFunc) {
+	s.projectEventHook = hook
+}
+
+func (s *Service) emit(projectID uuid.UUID, event string, data interface{}) {
+	if s.projectEventHook != nil {
+		s.projectEventHook(projectID, event, data)
+	}
+}
+
+func (s *Service) notifyAccessChange(projectID, userID uuid.UUID) {
+	if s.accessChangeHook != nil {
+		s.accessChangeHook(projectID, userID)
+	}
+}
+
 // Create project
 func (s *Service) Create(userID uuid.UUID, req CreateProjectRequest) (*Project, error) {
 	accessLevel := "private"
@@ -150,6 +193,9 @@ func (s *Service) Update(projectID, userID uuid.UUID, req UpdateProjectRequest)
 	if role != "owner" && role != "admin" {
 		return nil, ErrNotProjectOwner
 	}
+	if req.RequireTwoFactor != nil && role != "owner" {
+		return nil, ErrNotProjectOwner
+	}
 
 	// Get project
 	project, err := s.repo.GetByID(projectID)
@@ -179,6 +225,9 @@ func (s *Service) Update(projectID, userID uuid.UUID, req UpdateProjectRequest)
 	if req.NotificationsMuted != nil {
 		project.NotificationsMuted = *req.NotificationsMuted
 	}
+	if req.RequireTwoFactor != nil {
+		project.RequireTwoFactor = *req.RequireTwoFactor
+	}
 
 	// Save
 	if err := s.repo.Update(project); err != nil {
@@ -221,6 +270,12 @@ func (s *Service) AddMember(projectID, userID uuid.UUID, req AddProjectMemberReq
 		return fmt.Errorf("failed to add member: %w", err)
 	}
 
+	s.emit(projectID, EventMemberAdded, map[string]interface{}{
+		"user_id":  req.UserID,
+		"role":     newRole,
+		"added_by": userID,
+	})
+
 	return nil
 }
 
@@ -244,6 +299,8 @@ func (s *Service) Delete(projectID, userID uuid.UUID) error {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
 
+	s.notifyAccessChange(projectID, uuid.Nil)
+
 	return nil
 }
 
@@ -275,6 +332,8 @@ func (s *Service) RemoveMember(projectID, userID, memberID uuid.UUID) error {
 		return fmt.Errorf("failed to remove member: %w", err)
 	}
 
+	s.notifyAccessChange(projectID, memberID)
+
 	return nil
 }
 
@@ -310,5 +369,7 @@ func (s *Service) UpdateMemberRole(projectID, userID, memberID uuid.UUID, role s
 		return fmt.Errorf("failed to update member role: %w", err)
 	}
 
+	s.notifyAccessChange(projectID, memberID)
+
 	return nil
 }
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "devhub:ratelimit:"
+
+// hit counts a request in the current window, which starts with its first request
+var hit = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// Result describes the state of a window after a request was counted
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the window ends
+	ResetAfter time.Duration
+}
+
+// Limiter counts requests in fixed windows in Redis, so limits hold across API replicas
+type Limiter struct {
+	client *redis.Client
+}
+
+func NewLimiter(client *redis.Client) *Limiter {
+	return &Limiter{client: client}
+}
+
+// Allow counts a request of subject against a policy allowing limit requests per window
+func (l *Limiter) Allow(ctx context.Context, policy, subject string, limit int, window time.Duration) (Result, error) {
+	values, err := hit.Run(ctx, l.client, []string{keyPrefix + policy + ":" + subject}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count request: %w", err)
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit result: %v", values)
+	}
+
+	count := int(values[0])
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:    count <= limit,
+		Limit:      limit,
+		Remaining:  remaining,
+		ResetAfter: time.Duration(values[1]) * time.Millisecond,
+	}, nil
+}
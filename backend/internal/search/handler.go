@@ -0,0 +1,101 @@
+package search
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Search messages, topics, repo files, users and projects
+// GET /api/search?q=deploy from:@alice in:#ops has:file before:2024-02-01&types=messages,files&projectId=...&lang=english&limit=10
+func (h *Handler) Search(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		query = strings.TrimSpace(c.Query("query"))
+	}
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Query is required",
+		})
+	}
+
+	req := Request{
+		Query:    query,
+		Language: strings.TrimSpace(c.Query("lang")),
+		Limit:    10,
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 50 {
+			req.Limit = l
+		}
+	}
+
+	if typesParam := c.Query("types"); typesParam != "" {
+		for _, kind := range strings.Split(typesParam, ",") {
+			kind = strings.TrimSpace(kind)
+			if !containsString(allKinds, kind) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid result type",
+				})
+			}
+			req.Types = append(req.Types, kind)
+		}
+	}
+
+	if projectParam := c.Query("projectId"); projectParam != "" {
+		projectID, err := uuid.Parse(projectParam)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid project ID",
+			})
+		}
+		req.ProjectID = &projectID
+	}
+
+	results, err := h.service.Search(userID, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFilter) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid search filter",
+			})
+		}
+		if errors.Is(err, ErrEmptyQuery) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Query is required",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search",
+		})
+	}
+
+	return c.JSON(results)
+}
+
+// Helper
+func getUserIDFromContext(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+	return uuid.Parse(userIDStr)
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Result kinds, also accepted by the "types" query parameter
+const (
+	KindMessages = "messages"
+	KindTopics   = "topics"
+	KindFiles    = "files"
+	KindUsers    = "users"
+	KindProjects = "projects"
+)
+
+var allKinds = []string{KindMessages, KindTopics, KindFiles, KindUsers, KindProjects}
+
+// Snippets are HTML-escaped, matches are wrapped in <mark></mark>
+
+type MessageResult struct {
+	ID         uuid.UUID  `json:"id"`
+	TopicID    uuid.UUID  `json:"topic_id"`
+	TopicName  string     `json:"topic_name"`
+	ProjectID  uuid.UUID  `json:"project_id"`
+	ParentID   *uuid.UUID `json:"parent_id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	UserName   *string    `json:"user_name"`
+	UserHandle *string    `json:"user_handle"`
+	Type       string     `json:"type"`
+	Snippet    string     `json:"snippet"`
+	Rank       float64    `json:"rank"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type TopicResult struct {
+	ID        uuid.UUID `json:"id"`
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+}
+
+type FileResult struct {
+	ID        uuid.UUID `json:"id"`
+	RepoID    uuid.UUID `json:"repo_id"`
+	RepoName  string    `json:"repo_name"`
+	ProjectID uuid.UUID `json:"project_id"`
+	Path      string    `json:"path"`
+	Language  *string   `json:"language"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+}
+
+type UserResult struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Handle    *string   `json:"handle"`
+	AvatarURL *string   `json:"avatar_url"`
+	Rank      float64   `json:"rank"`
+}
+
+type ProjectResult struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	AvatarURL *string   `json:"avatar_url"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+}
+
+type Response struct {
+	Query    string          `json:"query"`
+	Language string          `json:"language"`
+	Messages []MessageResult `json:"messages"`
+	Topics   []TopicResult   `json:"topics"`
+	Files    []FileResult    `json:"files"`
+	Users    []UserResult    `json:"users"`
+	Projects []ProjectResult `json:"projects"`
+}
+
+type Request struct {
+	Query     string
+	Types     []string
+	ProjectID *uuid.UUID
+	Language  string
+	Limit     int
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrInvalidFilter = errors.New("invalid search filter")
+
+const filterDateLayout = "2006-01-02"
+
+// Query is a search string split into free text and filters.
+//
+//	deploy failed from:@alice in:#ops has:file after:2024-01-01
+type Query struct {
+	Text       string
+	FromHandle string
+	InTopic    string
+	HasFile    bool
+	HasCode    bool
+	Before     *time.Time
+	After      *time.Time
+}
+
+// HasFilters reports whether any message filter is set
+func (q Query) HasFilters() bool {
+	return q.FromHandle != "" || q.InTopic != "" || q.HasFile || q.HasCode || q.Before != nil || q.After != nil
+}
+
+// ParseQuery extracts known filters, everything else is free text.
+// Unknown "key:value" words stay in the text.
+func ParseQuery(raw string) (Query, error) {
+	var (
+		q    Query
+		text []string
+	)
+
+	for _, word := range strings.Fields(raw) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			text = append(text, word)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "from":
+			q.FromHandle = strings.TrimPrefix(value, "@")
+		case "in":
+			q.InTopic = strings.TrimPrefix(value, "#")
+		case "has":
+			switch strings.ToLower(value) {
+			case "file":
+				q.HasFile = true
+			case "code":
+				q.HasCode = true
+			default:
+				return q, ErrInvalidFilter
+			}
+		case "before":
+			t, err := time.Parse(filterDateLayout, value)
+			if err != nil {
+				return q, ErrInvalidFilter
+			}
+			q.Before = &t
+		case "after":
+			t, err := time.Parse(filterDateLayout, value)
+			if err != nil {
+				return q, ErrInvalidFilter
+			}
+			// after:2024-01-01 starts with the next day
+			t = t.AddDate(0, 0, 1)
+			q.After = &t
+		default:
+			text = append(text, word)
+		}
+	}
+
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// detectLanguage picks a text search config from the script of the text
+func detectLanguage(text string, languages []string, fallback string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) && containsString(languages, "russian") {
+			return "russian"
+		}
+	}
+	return fallback
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
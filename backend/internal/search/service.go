@@ -0,0 +1,324 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/config"
+)
+
+var ErrEmptyQuery = errors.New("empty search query")
+
+// Text search config names are inlined into SQL, only plain identifiers are allowed
+var languagePattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// Code is not natural language, stemming would only get in the way
+const codeLanguage = "simple"
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2"
+
+type Service struct {
+	db              *gorm.DB
+	languages       []string
+	defaultLanguage string
+}
+
+func NewService(db *gorm.DB, cfg config.SearchConfig) *Service {
+	languages := make([]string, 0, len(cfg.Languages))
+	for _, language := range cfg.Languages {
+		if languagePattern.MatchString(language) {
+			languages = append(languages, language)
+		}
+	}
+
+	defaultLanguage := cfg.DefaultLanguage
+	if !languagePattern.MatchString(defaultLanguage) {
+		defaultLanguage = "english"
+	}
+	if !containsString(languages, defaultLanguage) {
+		languages = append(languages, defaultLanguage)
+	}
+
+	return &Service{
+		db:              db,
+		languages:       languages,
+		defaultLanguage: defaultLanguage,
+	}
+}
+
+// Search runs the query against every project the user belongs to
+func (s *Service) Search(userID uuid.UUID, req Request) (*Response, error) {
+	q, err := ParseQuery(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	if q.Text == "" && !q.HasFilters() {
+		return nil, ErrEmptyQuery
+	}
+
+	var language string
+	if req.Language != "" && containsString(s.languages, req.Language) {
+		language = req.Language
+	} else {
+		language = detectLanguage(q.Text, s.languages, s.defaultLanguage)
+	}
+
+	types := req.Types
+	if len(types) == 0 {
+		types = allKinds
+	}
+
+	resp := &Response{
+		Query:    req.Query,
+		Language: language,
+		Messages: []MessageResult{},
+		Topics:   []TopicResult{},
+		Files:    []FileResult{},
+		Users:    []UserResult{},
+		Projects: []ProjectResult{},
+	}
+
+	if containsString(types, KindMessages) {
+		if resp.Messages, err = s.searchMessages(userID, q, req, language); err != nil {
+			return nil, fmt.Errorf("failed to search messages: %w", err)
+		}
+	}
+
+	// Filters only apply to messages, other kinds need free text
+	if q.Text == "" {
+		return resp, nil
+	}
+
+	if containsString(types, KindTopics) {
+		if resp.Topics, err = s.searchTopics(userID, q, req, language); err != nil {
+			return nil, fmt.Errorf("failed to search topics: %w", err)
+		}
+	}
+	if containsString(types, KindFiles) {
+		if resp.Files, err = s.searchFiles(userID, q, req); err != nil {
+			return nil, fmt.Errorf("failed to search files: %w", err)
+		}
+	}
+	if containsString(types, KindUsers) {
+		if resp.Users, err = s.searchUsers(userID, q, req); err != nil {
+			return nil, fmt.Errorf("failed to search users: %w", err)
+		}
+	}
+	if containsString(types, KindProjects) {
+		if resp.Projects, err = s.searchProjects(userID, q, req, language); err != nil {
+			return nil, fmt.Errorf("failed to search projects: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// topicAccess limits topics to what the joined project member may read
+const topicAccess = `
+	(topics.access_level <> 'admins' OR project_members.role IN ('owner', 'admin'))
+	AND (topics.type <> 'direct' OR EXISTS (
+		SELECT 1 FROM direct_participants
+		WHERE direct_participants.topic_id = topics.id AND direct_participants.user_id = ?
+	))`
+
+func (s *Service) searchMessages(userID uuid.UUID, q Query, req Request, language string) ([]MessageResult, error) {
+	query := s.db.Table("messages").
+		Joins("JOIN topics ON topics.id = messages.topic_id").
+		Joins("JOIN project_members ON project_members.project_id = topics.project_id AND project_members.user_id = ?", userID).
+		Joins("LEFT JOIN users ON users.id = messages.user_id").
+		Where(topicAccess, userID)
+
+	columns := `
+		messages.id, messages.topic_id, topics.name AS topic_name, topics.project_id,
+		messages.parent_id, messages.user_id, users.name AS user_name, users.handle AS user_handle,
+		messages.type, messages.created_at`
+
+	if q.Text != "" {
+		tsquery := fmt.Sprintf("websearch_to_tsquery('%s', ?)", language)
+		vector := fmt.Sprintf("to_tsvector('%s', messages.content)", language)
+		query = query.
+			Select(columns+`,
+				ts_headline('`+language+`', `+escapeHTML("messages.content")+`, `+tsquery+`, '`+headlineOptions+`') AS snippet,
+				ts_rank_cd(`+vector+`, `+tsquery+`, 32) AS rank`, q.Text, q.Text).
+			Where(vector+" @@ "+tsquery, q.Text).
+			Order("rank DESC, messages.created_at DESC")
+	} else {
+		query = query.
+			Select(columns + `,
+				` + escapeHTML("left(messages.content, 200)") + ` AS snippet,
+				0 AS rank`).
+			Order("messages.created_at DESC")
+	}
+
+	if req.ProjectID != nil {
+		query = query.Where("topics.project_id = ?", *req.ProjectID)
+	}
+	if q.FromHandle != "" {
+		query = query.Where("users.handle ILIKE ?", escapeLike(q.FromHandle))
+	}
+	if q.InTopic != "" {
+		query = query.Where("topics.name ILIKE ?", escapeLike(q.InTopic))
+	}
+	if q.HasFile {
+		query = query.Where("messages.type = 'file'")
+	}
+	if q.HasCode {
+		query = query.Where("messages.type = 'code'")
+	}
+	if q.Before != nil {
+		query = query.Where("messages.created_at < ?", *q.Before)
+	}
+	if q.After != nil {
+		query = query.Where("messages.created_at >= ?", *q.After)
+	}
+
+	var results []MessageResult
+	err := query.Limit(req.Limit).Scan(&results).Error
+	if results == nil {
+		results = []MessageResult{}
+	}
+	return results, err
+}
+
+func (s *Service) searchTopics(userID uuid.UUID, q Query, req Request, language string) ([]TopicResult, error) {
+	document := "topics.name || ' ' || coalesce(topics.description, '')"
+	tsquery := fmt.Sprintf("websearch_to_tsquery('%s', ?)", language)
+	vector := fmt.Sprintf("to_tsvector('%s', %s)", language, document)
+	like := "%" + escapeLike(q.Text) + "%"
+
+	query := s.db.Table("topics").
+		Select(`
+			topics.id, topics.project_id, topics.name, topics.type,
+			ts_headline('`+language+`', `+escapeHTML(document)+`, `+tsquery+`, '`+headlineOptions+`') AS snippet,
+			ts_rank_cd(`+vector+`, `+tsquery+`, 32) + CASE WHEN topics.name ILIKE ? THEN 1 ELSE 0 END AS rank`,
+			q.Text, q.Text, escapeLike(q.Text)).
+		Joins("JOIN project_members ON project_members.project_id = topics.project_id AND project_members.user_id = ?", userID).
+		Where(topicAccess, userID).
+		Where("("+vector+" @@ "+tsquery+" OR topics.name ILIKE ?)", q.Text, like)
+
+	if req.ProjectID != nil {
+		query = query.Where("topics.project_id = ?", *req.ProjectID)
+	}
+
+	var results []TopicResult
+	err := query.
+		Order("rank DESC, topics.name ASC").
+		Limit(req.Limit).
+		Scan(&results).Error
+	if results == nil {
+		results = []TopicResult{}
+	}
+	return results, err
+}
+
+func (s *Service) searchFiles(userID uuid.UUID, q Query, req Request) ([]FileResult, error) {
+	tsquery := fmt.Sprintf("websearch_to_tsquery('%s', ?)", codeLanguage)
+	vector := fmt.Sprintf("to_tsvector('%s', project_repo_files.content)", codeLanguage)
+	like := "%" + escapeLike(q.Text) + "%"
+
+	query := s.db.Table("project_repo_files").
+		Select(`
+			project_repo_files.id, project_repo_files.repo_id, project_repositories.name AS repo_name,
+			project_repositories.project_id, project_repo_files.path, project_repo_files.language,
+			ts_headline('`+codeLanguage+`', `+escapeHTML("project_repo_files.content")+`, `+tsquery+`, '`+headlineOptions+`') AS snippet,
+			ts_rank_cd(`+vector+`, `+tsquery+`, 32) + CASE WHEN project_repo_files.path ILIKE ? THEN 1 ELSE 0 END AS rank`,
+			q.Text, q.Text, like).
+		Joins("JOIN project_repositories ON project_repositories.id = project_repo_files.repo_id").
+		Joins("JOIN project_members ON project_members.project_id = project_repositories.project_id AND project_members.user_id = ?", userID).
+		Where("("+vector+" @@ "+tsquery+" OR project_repo_files.path ILIKE ?)", q.Text, like)
+
+	if req.ProjectID != nil {
+		query = query.Where("project_repositories.project_id = ?", *req.ProjectID)
+	}
+
+	var results []FileResult
+	err := query.
+		Order("rank DESC, project_repo_files.path ASC").
+		Limit(req.Limit).
+		Scan(&results).Error
+	if results == nil {
+		results = []FileResult{}
+	}
+	return results, err
+}
+
+func (s *Service) searchUsers(userID uuid.UUID, q Query, req Request) ([]UserResult, error) {
+	text := strings.TrimPrefix(q.Text, "@")
+	exact := escapeLike(text)
+	prefix := exact + "%"
+	like := "%" + exact + "%"
+
+	// Only people sharing a project with the caller
+	members := s.db.Table("project_members AS others").
+		Select("others.user_id").
+		Joins("JOIN project_members AS me ON me.project_id = others.project_id AND me.user_id = ?", userID)
+	if req.ProjectID != nil {
+		members = members.Where("others.project_id = ?", *req.ProjectID)
+	}
+
+	var results []UserResult
+	err := s.db.Table("users").
+		Select(`
+			users.id, users.name, users.handle, users.avatar_url,
+			CASE
+				WHEN users.handle ILIKE ? THEN 3
+				WHEN users.handle ILIKE ? OR users.name ILIKE ? THEN 2
+				ELSE 1
+			END AS rank`, exact, prefix, prefix).
+		Where("users.is_deleted = false").
+		Where("users.id IN (?)", members).
+		Where("users.name ILIKE ? OR users.handle ILIKE ?", like, like).
+		Order("rank DESC, users.name ASC").
+		Limit(req.Limit).
+		Scan(&results).Error
+	if results == nil {
+		results = []UserResult{}
+	}
+	return results, err
+}
+
+func (s *Service) searchProjects(userID uuid.UUID, q Query, req Request, language string) ([]ProjectResult, error) {
+	document := "projects.name || ' ' || coalesce(projects.description, '')"
+	tsquery := fmt.Sprintf("websearch_to_tsquery('%s', ?)", language)
+	vector := fmt.Sprintf("to_tsvector('%s', %s)", language, document)
+	like := "%" + escapeLike(q.Text) + "%"
+
+	query := s.db.Table("projects").
+		Select(`
+			projects.id, projects.name, projects.avatar_url,
+			ts_headline('`+language+`', `+escapeHTML(document)+`, `+tsquery+`, '`+headlineOptions+`') AS snippet,
+			ts_rank_cd(`+vector+`, `+tsquery+`, 32) + CASE WHEN projects.name ILIKE ? THEN 1 ELSE 0 END AS rank`,
+			q.Text, q.Text, escapeLike(q.Text)).
+		Joins("JOIN project_members ON project_members.project_id = projects.id AND project_members.user_id = ?", userID).
+		Where("("+vector+" @@ "+tsquery+" OR projects.name ILIKE ?)", q.Text, like)
+
+	if req.ProjectID != nil {
+		query = query.Where("projects.id = ?", *req.ProjectID)
+	}
+
+	var results []ProjectResult
+	err := query.
+		Order("rank DESC, projects.name ASC").
+		Limit(req.Limit).
+		Scan(&results).Error
+	if results == nil {
+		results = []ProjectResult{}
+	}
+	return results, err
+}
+
+// escapeHTML wraps a SQL text expression so that highlighted snippets are safe to render
+func escapeHTML(expr string) string {
+	return fmt.Sprintf("replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", expr)
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
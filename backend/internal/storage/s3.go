@@ -93,6 +93,17 @@ func (s *S3Client) Upload(ctx context.Context, reader io.Reader, filename string
 	}, nil
 }
 
+// PutObject stores an object under a key chosen by the caller, e.g. outside the public uploads/ prefix
+func (s *S3Client) PutObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload object: %w", err)
+	}
+	return nil
+}
+
 func (s *S3Client) Delete(ctx context.Context, key string) error {
 	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
 }
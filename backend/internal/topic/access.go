@@ -0,0 +1,31 @@
+package topic
+
+// Access describes what a user may do in a topic
+type Access struct {
+	CanRead  bool
+	CanWrite bool
+}
+
+// ResolveAccess applies the topic access level and visibility to a project role.
+// role is empty when the user is not a member of the project. Topics are only
+// readable by project members, like the REST message endpoints require.
+func ResolveAccess(topic *Topic, role string) Access {
+	isMember := role != ""
+	isAdmin := role == "owner" || role == "admin"
+
+	var canRead bool
+	switch topic.AccessLevel {
+	case "admins":
+		canRead = isAdmin
+	default: // members, public
+		canRead = isMember
+	}
+
+	// Archived topics stay readable but no longer accept new activity
+	canWrite := canRead && isMember && topic.Visibility != "archived"
+
+	return Access{
+		CanRead:  canRead,
+		CanWrite: canWrite,
+	}
+}
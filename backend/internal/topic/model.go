@@ -45,10 +45,44 @@ type UpdateTopicRequest struct {
 
 type TopicWithStats struct {
 	Topic
-	MessageCount  int        `json:"message_count"`
-	LastMessageAt *time.Time `json:"last_message_at"`
+	MessageCount       int        `json:"message_count"`
+	LastMessageAt      *time.Time `json:"last_message_at"`
+	UnreadCount        int        `json:"unread_count"`
+	UnreadMentionCount int        `json:"unread_mention_count"`
+}
+
+// ReadCursor marks how far a user has read a topic
+type ReadCursor struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TopicID           uuid.UUID  `json:"topic_id" gorm:"not null"`
+	UserID            uuid.UUID  `json:"user_id" gorm:"not null"`
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id"`
+	LastReadAt        time.Time  `json:"last_read_at" gorm:"not null"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Mute silences new-message notifications of a topic for one user.
+// MutedUntil is nil for a mute without expiry.
+type Mute struct {
+	TopicID    uuid.UUID  `json:"topic_id" gorm:"primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"primaryKey"`
+	MutedUntil *time.Time `json:"muted_until"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type MarkReadRequest struct {
+	// Defaults to the latest message of the topic
+	MessageID *uuid.UUID `json:"message_id"`
 }
 
 func (Topic) TableName() string {
 	return "topics"
 }
+
+func (ReadCursor) TableName() string {
+	return "topic_read_cursors"
+}
+
+func (Mute) TableName() string {
+	return "topic_mutes"
+}
@@ -1,8 +1,12 @@
 package topic
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -35,23 +39,114 @@ func (r *Repository) GetByProjectID(projectID uuid.UUID) ([]Topic, error) {
 	return topics, err
 }
 
-// Get topics with stats
-func (r *Repository) GetByProjectIDWithStats(projectID uuid.UUID) ([]TopicWithStats, error) {
+// GetFirstByType returns the first topic of a type in a project, e.g. its deploy topic
+func (r *Repository) GetFirstByType(projectID uuid.UUID, topicType string) (*Topic, error) {
+	var topic Topic
+	err := r.db.
+		Where("project_id = ? AND type = ?", projectID, topicType).
+		Order("position ASC, created_at ASC").
+		First(&topic).Error
+	return &topic, err
+}
+
+// Get topics with stats.
+// Unread counts start at the user's read cursor, or at the time they joined the project.
+func (r *Repository) GetByProjectIDWithStats(projectID, userID uuid.UUID) ([]TopicWithStats, error) {
+	mention, err := json.Marshal([]map[string]uuid.UUID{{"id": userID}})
+	if err != nil {
+		return nil, err
+	}
+
 	var topics []TopicWithStats
-	err := r.db.Table("topics").
+	err = r.db.Table("topics").
 		Select(`
 			topics.*,
 			COUNT(messages.id) as message_count,
-			MAX(messages.created_at) as last_message_at
-		`).
+			MAX(messages.created_at) as last_message_at,
+			COUNT(messages.id) FILTER (
+				WHERE messages.parent_id IS NULL
+				AND messages.created_at > COALESCE(topic_read_cursors.last_read_at, project_members.joined_at)
+				AND messages.user_id IS DISTINCT FROM ?
+			) as unread_count,
+			COUNT(messages.id) FILTER (
+				WHERE messages.created_at > COALESCE(topic_read_cursors.last_read_at, project_members.joined_at)
+				AND messages.metadata->'mentions' @> ?::jsonb
+			) as unread_mention_count
+		`, userID, string(mention)).
 		Joins("LEFT JOIN messages ON messages.topic_id = topics.id").
+		Joins("LEFT JOIN topic_read_cursors ON topic_read_cursors.topic_id = topics.id AND topic_read_cursors.user_id = ?", userID).
+		Joins("LEFT JOIN project_members ON project_members.project_id = topics.project_id AND project_members.user_id = ?", userID).
 		Where("topics.project_id = ?", projectID).
-		Group("topics.id").
+		Group("topics.id, topic_read_cursors.last_read_at, project_members.joined_at").
 		Order("topics.position ASC, topics.created_at ASC").
 		Scan(&topics).Error
 	return topics, err
 }
 
+// Get the latest top-level message of a topic
+func (r *Repository) GetLatestMessage(topicID uuid.UUID) (uuid.UUID, time.Time, error) {
+	var row struct {
+		ID        uuid.UUID
+		CreatedAt time.Time
+	}
+	err := r.db.Table("messages").
+		Select("id, created_at").
+		Where("topic_id = ? AND parent_id IS NULL", topicID).
+		Order("created_at DESC").
+		Take(&row).Error
+	return row.ID, row.CreatedAt, err
+}
+
+// Get creation time of a topic message
+func (r *Repository) GetMessageTime(topicID, messageID uuid.UUID) (time.Time, error) {
+	var row struct {
+		CreatedAt time.Time
+	}
+	err := r.db.Table("messages").
+		Select("created_at").
+		Where("id = ? AND topic_id = ?", messageID, topicID).
+		Take(&row).Error
+	return row.CreatedAt, err
+}
+
+// Advance read cursor. Cursors never move backwards.
+func (r *Repository) AdvanceReadCursor(cursor *ReadCursor) error {
+	return r.db.Exec(`
+		INSERT INTO topic_read_cursors (topic_id, user_id, last_read_message_id, last_read_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (topic_id, user_id) DO UPDATE
+		SET last_read_message_id = EXCLUDED.last_read_message_id,
+			last_read_at = EXCLUDED.last_read_at
+		WHERE topic_read_cursors.last_read_at < EXCLUDED.last_read_at
+	`, cursor.TopicID, cursor.UserID, cursor.LastReadMessageID, cursor.LastReadAt).Error
+}
+
+// Get read cursor of a user
+func (r *Repository) GetReadCursor(topicID, userID uuid.UUID) (*ReadCursor, error) {
+	var cursor ReadCursor
+	err := r.db.First(&cursor, "topic_id = ? AND user_id = ?", topicID, userID).Error
+	return &cursor, err
+}
+
+// Get read cursors of all users in a topic
+func (r *Repository) GetReadCursors(topicID uuid.UUID) ([]ReadCursor, error) {
+	var cursors []ReadCursor
+	err := r.db.
+		Where("topic_id = ?", topicID).
+		Order("last_read_at DESC").
+		Find(&cursors).Error
+	return cursors, err
+}
+
+// Check if user participates in a direct topic
+func (r *Repository) IsDirectParticipant(topicID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Table("direct_participants").
+		Where("topic_id = ? AND user_id = ?", topicID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
 // Update topic
 func (r *Repository) Update(topic *Topic) error {
 	return r.db.Save(topic).Error
@@ -78,3 +173,25 @@ func (r *Repository) UpdatePositions(updates []struct {
 		return nil
 	})
 }
+
+// Mute topic notifications for a user, replacing an existing mute
+func (r *Repository) Mute(mute *Mute) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "topic_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"muted_until"}),
+	}).Create(mute).Error
+}
+
+// Unmute topic notifications for a user
+func (r *Repository) Unmute(topicID, userID uuid.UUID) error {
+	return r.db.Delete(&Mute{}, "topic_id = ? AND user_id = ?", topicID, userID).Error
+}
+
+// Get users with an active mute on the topic
+func (r *Repository) GetMutedUserIDs(topicID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.Model(&Mute{}).
+		Where("topic_id = ? AND (muted_until IS NULL OR muted_until > ?)", topicID, time.Now()).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
@@ -3,6 +3,7 @@ package topic
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -13,11 +14,18 @@ import (
 var (
 	ErrTopicNotFound    = errors.New("topic not found")
 	ErrNotProjectMember = errors.New("not a project member")
+	ErrMessageNotFound  = errors.New("message not found")
 )
 
 type Service struct {
 	repo        *Repository
 	projectRepo *project.Repository
+
+	// optional, notified when topic access settings change
+	accessChangeHook func(topicID uuid.UUID)
+
+	// optional, notified when a user's read cursor moves
+	readHook func(cursor *ReadCursor)
 }
 
 func NewService(repo *Repository, projectRepo *project.Repository) *Service {
@@ -27,6 +35,22 @@ func NewService(repo *Repository, projectRepo *project.Repository) *Service {
 	}
 }
 
+// SetAccessChangeHook sets the callback used to re-check live connections (called from main.go)
+func (s *Service) SetAccessChangeHook(hook func(topicID uuid.UUID)) {
+	s.accessChangeHook = hook
+}
+
+func (s *Service) notifyAccessChange(topicID uuid.UUID) {
+	if s.accessChangeHook != nil {
+		s.accessChangeHook(topicID)
+	}
+}
+
+// SetReadHook sets the callback used to publish read receipts (called from main.go)
+func (s *Service) SetReadHook(hook func(cursor *ReadCursor)) {
+	s.readHook = hook
+}
+
 // Create topic
 func (s *Service) Create(projectID, userID uuid.UUID, req CreateTopicRequest) (*Topic, error) {
 	// Check if user is member of project
@@ -104,7 +128,7 @@ func (s *Service) GetByProjectID(projectID, userID uuid.UUID, withStats bool) (i
 	}
 
 	if withStats {
-		topics, err := s.repo.GetByProjectIDWithStats(projectID)
+		topics, err := s.repo.GetByProjectIDWithStats(projectID, userID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get topics with stats: %w", err)
 		}
@@ -164,6 +188,10 @@ func (s *Service) Update(topicID, userID uuid.UUID, req UpdateTopicRequest) (*To
 		return nil, fmt.Errorf("failed to update topic: %w", err)
 	}
 
+	if req.AccessLevel != nil || req.Visibility != nil {
+		s.notifyAccessChange(topicID)
+	}
+
 	return topic, nil
 }
 
@@ -190,5 +218,66 @@ func (s *Service) Delete(topicID, userID uuid.UUID) error {
 		return fmt.Errorf("failed to delete topic: %w", err)
 	}
 
+	s.notifyAccessChange(topicID)
+
 	return nil
 }
+
+// Mark topic read up to a message, or up to the latest message
+func (s *Service) MarkRead(topicID, userID uuid.UUID, req MarkReadRequest) (*ReadCursor, error) {
+	if _, err := s.GetByID(topicID, userID); err != nil {
+		return nil, err
+	}
+
+	var (
+		messageID uuid.UUID
+		readAt    time.Time
+		err       error
+	)
+	if req.MessageID != nil {
+		messageID = *req.MessageID
+		readAt, err = s.repo.GetMessageTime(topicID, messageID)
+	} else {
+		messageID, readAt, err = s.repo.GetLatestMessage(topicID)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	if err := s.repo.AdvanceReadCursor(&ReadCursor{
+		TopicID:           topicID,
+		UserID:            userID,
+		LastReadMessageID: &messageID,
+		LastReadAt:        readAt,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update read cursor: %w", err)
+	}
+
+	// Reload, an older position does not move the cursor back
+	cursor, err := s.repo.GetReadCursor(topicID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read cursor: %w", err)
+	}
+
+	if s.readHook != nil {
+		s.readHook(cursor)
+	}
+
+	return cursor, nil
+}
+
+// Get read cursors of topic members
+func (s *Service) GetReadCursors(topicID, userID uuid.UUID) ([]ReadCursor, error) {
+	if _, err := s.GetByID(topicID, userID); err != nil {
+		return nil, err
+	}
+
+	cursors, err := s.repo.GetReadCursors(topicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read cursors: %w", err)
+	}
+	return cursors, nil
+}
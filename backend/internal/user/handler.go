@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -264,6 +266,70 @@ func (h *Handler) DeleteMe(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
 
+// ChangePassword changes the password of the current user
+// POST /api/users/me/password
+func (h *Handler) ChangePassword(c *fiber.Ctx) error {
+	userID := c.Locals("userID")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Invalid user ID format",
+		})
+	}
+
+	userUUID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	if err := h.service.ChangePassword(userUUID, req, auth.CurrentSessionID(c)); err != nil {
+		var locked *auth.AccountLockedError
+		switch {
+		case errors.As(err, &locked):
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many failed password attempts, try again later",
+			})
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid password",
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to change password",
+			})
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // StartEmailChange sends a verification code to a new email
 // POST /api/users/me/email
 func (h *Handler) StartEmailChange(c *fiber.Ctx) error {
@@ -365,7 +431,7 @@ func (h *Handler) ConfirmEmailChange(c *fiber.Ctx) error {
 		})
 	}
 
-	updatedUser, token, err := h.service.ConfirmEmailChange(userUUID, req)
+	updatedUser, tokens, err := h.service.ConfirmEmailChange(userUUID, req, auth.ClientFromRequest(c))
 	if err != nil {
 		switch {
 		case errors.Is(err, auth.ErrEmailAlreadyExists):
@@ -400,7 +466,9 @@ func (h *Handler) ConfirmEmailChange(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(LoginResponse{
-		Token: token,
-		User:  *updatedUser,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         *updatedUser,
 	})
 }
@@ -20,6 +20,8 @@ type User struct {
 	Phone          *string    `json:"phone"`
 	GitHubID       *string    `json:"github_id" gorm:"column:github_id;uniqueIndex"`
 	GitHubUsername *string    `json:"github_username" gorm:"column:github_username"`
+	IsBot          bool       `json:"is_bot" gorm:"column:is_bot;default:false"`
+	BotProjectID   *uuid.UUID `json:"bot_project_id,omitempty" gorm:"column:bot_project_id"` // project that owns the bot
 	IsDeleted      bool       `json:"-" gorm:"column:is_deleted;default:false"`
 	DeletedAt      *time.Time `json:"-" gorm:"column:deleted_at"`
 	CreatedAt      time.Time  `json:"created_at"`
@@ -40,8 +42,10 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         User      `json:"user"`
 }
 
 type UpdateUserRequest struct {
@@ -64,6 +68,11 @@ type ConfirmEmailChangeRequest struct {
 	Code     string `json:"code" validate:"required,len=6"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
 type EmailChangeStartResponse struct {
 	ExpiresAt time.Time `json:"expires_at"`
 }
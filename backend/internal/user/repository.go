@@ -22,3 +22,9 @@ func (r *Repository) GetByID(id uuid.UUID) (*User, error) {
 func (r *Repository) Update(user *User) error {
 	return r.db.Save(user).Error
 }
+
+func (r *Repository) GetByHandle(handle string) (*User, error) {
+	var foundUser User
+	err := r.db.First(&foundUser, "LOWER(handle) = LOWER(?) AND is_deleted = false", handle).Error
+	return &foundUser, err
+}
@@ -161,7 +161,40 @@ func (s *Service) Delete(userID uuid.UUID) error {
 	if result.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
 	}
-	return nil
+
+	return s.authService.RevokeSessions(userID, nil)
+}
+
+// ChangePassword replaces the password of a user and signs out every other session
+func (s *Service) ChangePassword(userID uuid.UUID, req ChangePasswordRequest, currentSession *uuid.UUID) error {
+	var foundUser User
+	if err := s.db.First(&foundUser, "id = ? AND is_deleted = false", userID).Error; err != nil {
+		return err
+	}
+
+	if foundUser.PasswordHash == nil {
+		return auth.ErrInvalidCredentials
+	}
+
+	// Wrong current passwords count towards the login lockout
+	if err := s.authService.CheckLockout(foundUser.Email); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(*foundUser.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return s.authService.PasswordFailed(foundUser.Email)
+	}
+	s.authService.ResetLockout(foundUser.Email)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.db.Model(&foundUser).Update("password_hash", string(hashedPassword)).Error; err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return s.authService.RevokeSessions(userID, currentSession)
 }
 
 func (s *Service) StartEmailChange(userID uuid.UUID, req ChangeEmailRequest) (EmailChangeStartResponse, error) {
@@ -194,42 +227,43 @@ func (s *Service) StartEmailChange(userID uuid.UUID, req ChangeEmailRequest) (Em
 	return EmailChangeStartResponse{ExpiresAt: confirmation.ExpiresAt}, nil
 }
 
-func (s *Service) ConfirmEmailChange(userID uuid.UUID, req ConfirmEmailChangeRequest) (*User, string, error) {
+// ConfirmEmailChange updates the email and signs the user out everywhere,
+// the returned tokens belong to a new session for the current device
+func (s *Service) ConfirmEmailChange(userID uuid.UUID, req ConfirmEmailChangeRequest, client auth.ClientInfo) (*User, *auth.TokenPair, error) {
 	var foundUser User
 	if err := s.db.First(&foundUser, "id = ? AND is_deleted = false", userID).Error; err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	if err := s.authService.EnsureEmailAvailable(req.NewEmail); err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	var confirmation auth.EmailConfirmation
-	if err := s.db.Where("email = ?", req.NewEmail).First(&confirmation).Error; err != nil {
+	if err := s.db.Where("email = ? AND purpose = ?", req.NewEmail, auth.ConfirmationPurposeVerifyEmail).First(&confirmation).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, "", auth.ErrConfirmationNotFound
+			return nil, nil, auth.ErrConfirmationNotFound
 		}
-		return nil, "", fmt.Errorf("database error: %w", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
 	}
 
 	if confirmation.ExpiresAt.Before(time.Now()) {
-		return nil, "", auth.ErrCodeExpired
+		return nil, nil, auth.ErrCodeExpired
 	}
 
 	if confirmation.Attempts >= auth.MaxVerificationAttempts() {
-		return nil, "", auth.ErrTooManyAttempts
+		return nil, nil, auth.ErrTooManyAttempts
 	}
 
 	if confirmation.Code != req.Code {
 		confirmation.Attempts++
 		if err := s.db.Save(&confirmation).Error; err != nil {
-			return nil, "", fmt.Errorf("failed to update confirmation attempts: %w", err)
+			return nil, nil, fmt.Errorf("failed to update confirmation attempts: %w", err)
 		}
-		return nil, "", auth.ErrInvalidCode
+		return nil, nil, auth.ErrInvalidCode
 	}
 
 	var updatedUser *User
-	var token string
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		foundUser.Email = req.NewEmail
 		if err := tx.Save(&foundUser).Error; err != nil {
@@ -240,17 +274,20 @@ func (s *Service) ConfirmEmailChange(userID uuid.UUID, req ConfirmEmailChangeReq
 			return fmt.Errorf("failed to delete confirmation: %w", err)
 		}
 
-		jwtToken, err := s.authService.GenerateToken(foundUser.ID, foundUser.Email)
-		if err != nil {
-			return fmt.Errorf("failed to generate token: %w", err)
-		}
-
 		updatedUser = &foundUser
-		token = jwtToken
 		return nil
 	}); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	if err := s.authService.RevokeSessions(userID, nil); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.authService.StartSession(updatedUser.ID, updatedUser.Email, client)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return updatedUser, token, nil
+	return updatedUser, tokens, nil
 }
@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, ErrNotProjectMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+	case errors.Is(err, ErrNotProjectAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	case errors.Is(err, ErrWebhookNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Webhook not found"})
+	case errors.Is(err, ErrDeliveryNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Delivery not found"})
+	case errors.Is(err, ErrInvalidURL):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "URL must be a public http(s) address"})
+	case errors.Is(err, ErrUnknownEvent):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "events": Events})
+	case errors.Is(err, ErrWebhookInactive):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Webhook is disabled"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+}
+
+func parseIDs(c *fiber.Ctx) (userID, projectID uuid.UUID, err error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, uuid.Nil, fiber.ErrUnauthorized
+	}
+	userID, err = uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fiber.ErrUnauthorized
+	}
+	projectID, err = uuid.Parse(c.Params("projectId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid project ID")
+	}
+	return userID, projectID, nil
+}
+
+func parseWebhookID(c *fiber.Ctx) (uuid.UUID, error) {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+	return webhookID, nil
+}
+
+// Create webhook
+// POST /api/projects/:projectId/webhooks
+func (h *Handler) Create(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	webhook, err := h.service.Create(projectID, userID, req)
+	if err != nil {
+		return h.respondError(c, err, "Failed to create webhook")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(webhook)
+}
+
+// List webhooks
+// GET /api/projects/:projectId/webhooks
+func (h *Handler) List(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := h.service.List(projectID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to list webhooks")
+	}
+
+	return c.JSON(webhooks)
+}
+
+// Get webhook
+// GET /api/projects/:projectId/webhooks/:webhookId
+func (h *Handler) Get(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.service.Get(projectID, webhookID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get webhook")
+	}
+
+	return c.JSON(webhook)
+}
+
+// Update webhook
+// PATCH /api/projects/:projectId/webhooks/:webhookId
+func (h *Handler) Update(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	webhook, err := h.service.Update(projectID, webhookID, userID, req)
+	if err != nil {
+		return h.respondError(c, err, "Failed to update webhook")
+	}
+
+	return c.JSON(webhook)
+}
+
+// Delete webhook
+// DELETE /api/projects/:projectId/webhooks/:webhookId
+func (h *Handler) Delete(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(projectID, webhookID, userID); err != nil {
+		return h.respondError(c, err, "Failed to delete webhook")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Rotate webhook secret
+// POST /api/projects/:projectId/webhooks/:webhookId/rotate-secret
+func (h *Handler) RotateSecret(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.service.RotateSecret(projectID, webhookID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to rotate secret")
+	}
+
+	return c.JSON(webhook)
+}
+
+// Send a ping event
+// POST /api/projects/:projectId/webhooks/:webhookId/ping
+func (h *Handler) Ping(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := h.service.Ping(projectID, webhookID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to queue ping")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(delivery)
+}
+
+// List webhook deliveries
+// GET /api/projects/:projectId/webhooks/:webhookId/deliveries?limit=50
+func (h *Handler) ListDeliveries(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	deliveries, err := h.service.ListDeliveries(projectID, webhookID, userID, limit)
+	if err != nil {
+		return h.respondError(c, err, "Failed to list deliveries")
+	}
+
+	return c.JSON(deliveries)
+}
+
+// Redeliver a past delivery
+// POST /api/projects/:projectId/webhooks/:webhookId/deliveries/:deliveryId/redeliver
+func (h *Handler) Redeliver(c *fiber.Ctx) error {
+	userID, projectID, err := parseIDs(c)
+	if err != nil {
+		return err
+	}
+	webhookID, err := parseWebhookID(c)
+	if err != nil {
+		return err
+	}
+	deliveryID, err := uuid.Parse(c.Params("deliveryId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid delivery ID"})
+	}
+
+	delivery, err := h.service.Redeliver(projectID, webhookID, deliveryID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to queue redelivery")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(delivery)
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/m0khm/devhub/backend/internal/message"
+	"github.com/m0khm/devhub/backend/internal/notification"
+	"github.com/m0khm/devhub/backend/pkg/validator"
+)
+
+type IncomingHandler struct {
+	service             *IncomingService
+	wsHandler           *message.WSHandler // optional
+	notificationService *notification.Service
+}
+
+func NewIncomingHandler(service *IncomingService) *IncomingHandler {
+	return &IncomingHandler{service: service}
+}
+
+// SetWSHandler sets the WebSocket handler (called from main.go)
+func (h *IncomingHandler) SetWSHandler(wsHandler *message.WSHandler) {
+	h.wsHandler = wsHandler
+}
+
+// SetNotificationService sets the notification service (called from main.go)
+func (h *IncomingHandler) SetNotificationService(notificationService *notification.Service) {
+	h.notificationService = notificationService
+}
+
+func (h *IncomingHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, ErrNotProjectMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a project member"})
+	case errors.Is(err, ErrNotProjectAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	case errors.Is(err, ErrTopicNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Topic not found"})
+	case errors.Is(err, ErrIncomingWebhookNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Incoming webhook not found"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+}
+
+func parseTopicIDs(c *fiber.Ctx) (userID, topicID uuid.UUID, err error) {
+	userIDStr, ok := c.Locals("userID").(string)
+	if !ok {
+		return uuid.Nil, uuid.Nil, fiber.ErrUnauthorized
+	}
+	userID, err = uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fiber.ErrUnauthorized
+	}
+	topicID, err = uuid.Parse(c.Params("topicId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid topic ID")
+	}
+	return userID, topicID, nil
+}
+
+func withToken(c *fiber.Ctx, hook *IncomingWebhook, token string) IncomingWebhookWithToken {
+	return IncomingWebhookWithToken{
+		IncomingWebhook: *hook,
+		Token:           token,
+		URL:             fmt.Sprintf("%s/api/hooks/incoming/%s", c.BaseURL(), token),
+	}
+}
+
+// Create incoming webhook
+// POST /api/topics/:topicId/incoming-webhooks
+func (h *IncomingHandler) Create(c *fiber.Ctx) error {
+	userID, topicID, err := parseTopicIDs(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateIncomingWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errs := validator.Validate(req); len(errs) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": errs,
+		})
+	}
+
+	hook, token, err := h.service.Create(topicID, userID, req)
+	if err != nil {
+		return h.respondError(c, err, "Failed to create incoming webhook")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(withToken(c, hook, token))
+}
+
+// List incoming webhooks
+// GET /api/topics/:topicId/incoming-webhooks
+func (h *IncomingHandler) List(c *fiber.Ctx) error {
+	userID, topicID, err := parseTopicIDs(c)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := h.service.List(topicID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to list incoming webhooks")
+	}
+
+	return c.JSON(hooks)
+}
+
+// Rotate incoming webhook token
+// POST /api/topics/:topicId/incoming-webhooks/:hookId/rotate
+func (h *IncomingHandler) Rotate(c *fiber.Ctx) error {
+	userID, topicID, err := parseTopicIDs(c)
+	if err != nil {
+		return err
+	}
+	hookID, err := uuid.Parse(c.Params("hookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook ID"})
+	}
+
+	hook, token, err := h.service.Rotate(topicID, hookID, userID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to rotate token")
+	}
+
+	return c.JSON(withToken(c, hook, token))
+}
+
+// Revoke incoming webhook
+// DELETE /api/topics/:topicId/incoming-webhooks/:hookId
+func (h *IncomingHandler) Revoke(c *fiber.Ctx) error {
+	userID, topicID, err := parseTopicIDs(c)
+	if err != nil {
+		return err
+	}
+	hookID, err := uuid.Parse(c.Params("hookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook ID"})
+	}
+
+	if err := h.service.Revoke(topicID, hookID, userID); err != nil {
+		return h.respondError(c, err, "Failed to revoke incoming webhook")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Post a message through an incoming webhook (public, authenticated by the token).
+// Accepts a JSON body or a form with a JSON "payload" field and answers
+// with Slack's plain text responses.
+// POST /api/hooks/incoming/:token
+func (h *IncomingHandler) Post(c *fiber.Ctx) error {
+	var payload SlackPayload
+	body := c.Body()
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationForm) {
+		body = []byte(c.FormValue("payload"))
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid_payload")
+	}
+
+	created, err := h.service.Post(c.Params("token"), payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTopicNotFound):
+			return c.Status(fiber.StatusNotFound).SendString("no_service")
+		case errors.Is(err, ErrTopicArchived):
+			return c.Status(fiber.StatusGone).SendString("channel_is_archived")
+		case errors.Is(err, ErrNoText):
+			return c.Status(fiber.StatusBadRequest).SendString("no_text")
+		case errors.Is(err, ErrTextTooLong):
+			return c.Status(fiber.StatusBadRequest).SendString("msg_too_long")
+		default:
+			log.Printf("incoming webhook: failed to post message: %v", err)
+			return c.Status(fiber.StatusInternalServerError).SendString("internal_error")
+		}
+	}
+
+	if h.wsHandler != nil {
+		h.wsHandler.BroadcastNewMessage(created)
+	}
+
+	if h.notificationService != nil {
+		notifications, err := h.notificationService.CreateMessageNotifications(created.TopicID, uuid.Nil, created.Content)
+		if err != nil {
+			log.Printf("failed to create notifications for message %s: %v", created.ID, err)
+		} else if h.wsHandler != nil {
+			for _, item := range notifications {
+				h.wsHandler.BroadcastNotificationCreated(item)
+			}
+		}
+	}
+
+	return c.SendString("ok")
+}
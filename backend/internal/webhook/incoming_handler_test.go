@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/m0khm/devhub/backend/internal/message"
+	"github.com/m0khm/devhub/backend/internal/notification"
+	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/topic"
+	"github.com/m0khm/devhub/backend/internal/user"
+)
+
+// recordingBroker keeps the broadcasts published by the hub
+type recordingBroker struct {
+	mu       sync.Mutex
+	messages []*message.BroadcastMessage
+}
+
+func (b *recordingBroker) Publish(ctx context.Context, msg *message.BroadcastMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+func (b *recordingBroker) Subscribe(ctx context.Context, handler func(*message.BroadcastMessage)) error {
+	return nil
+}
+
+func (b *recordingBroker) Close() error {
+	return nil
+}
+
+func (b *recordingBroker) eventTypes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	types := make([]string, 0, len(b.messages))
+	for _, msg := range b.messages {
+		var event message.WSMessage
+		if err := json.Unmarshal(msg.Data, &event); err == nil {
+			types = append(types, event.Type)
+		}
+	}
+	return types
+}
+
+func TestIncomingPostBroadcastsMessage(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const token = "incoming-token"
+	hookID, topicID, projectID, messageID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT \* FROM "incoming_webhooks" WHERE token_hash = \$1 AND revoked_at IS NULL`).
+		WithArgs(hashToken(token), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "topic_id", "name", "token_hash", "token_prefix", "created_by"}).
+			AddRow(hookID, projectID, topicID, "CI", hashToken(token), "incoming", uuid.New()))
+	mock.ExpectQuery(`SELECT \* FROM "topics" WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "type", "access_level", "visibility"}).
+			AddRow(topicID, projectID, "general", "chat", "members", "visible"))
+	mock.ExpectQuery(`SELECT \* FROM "topics" WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project_id", "name", "type", "access_level", "visibility"}).
+			AddRow(topicID, projectID, "general", "chat", "members", "visible"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "messages"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(messageID, "integration"))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM "messages" LEFT JOIN users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic_id", "content", "type", "created_at", "updated_at"}).
+			AddRow(messageID, topicID, "Build passed", "integration", now, now))
+	mock.ExpectQuery(`SELECT message_id, emoji, user_id FROM "message_reactions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "user_id"}))
+	mock.ExpectQuery(`SELECT DISTINCT ON \(parent_id\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id", "reply_count", "last_reply_at", "last_reply_user_id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "incoming_webhooks" SET "last_used_at"=\$1 WHERE id = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	projectRepo := project.NewRepository(db)
+	topicRepo := topic.NewRepository(db)
+	messageService := message.NewService(message.NewRepository(db), topicRepo, projectRepo,
+		notification.NewRepository(db), user.NewRepository(db))
+	broker := &recordingBroker{}
+	wsHandler := message.NewWSHandler(message.NewHub(broker), messageService)
+
+	handler := NewIncomingHandler(NewIncomingService(NewRepository(db), projectRepo, topicRepo, messageService))
+	handler.SetWSHandler(wsHandler)
+	app := fiber.New()
+	app.Post("/api/hooks/incoming/:token", handler.Post)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/hooks/incoming/"+token, strings.NewReader(`{"text":"Build passed"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if types := broker.eventTypes(); len(types) != 1 || types[0] != "new_message" {
+		t.Fatalf("broadcasts = %v, want [new_message]", types)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/message"
+	"github.com/m0khm/devhub/backend/internal/project"
+	"github.com/m0khm/devhub/backend/internal/topic"
+)
+
+const maxIncomingContentLength = 10000
+
+var (
+	ErrTopicNotFound           = errors.New("topic not found")
+	ErrIncomingWebhookNotFound = errors.New("incoming webhook not found")
+	ErrInvalidToken            = errors.New("invalid webhook token")
+	ErrTopicArchived           = errors.New("topic is archived")
+	ErrNoText                  = errors.New("payload has no text")
+	ErrTextTooLong             = errors.New("payload text is too long")
+)
+
+// IncomingService manages per-topic incoming webhooks and turns their payloads into messages
+type IncomingService struct {
+	repo           *Repository
+	projectRepo    *project.Repository
+	topicRepo      *topic.Repository
+	messageService *message.Service
+}
+
+func NewIncomingService(
+	repo *Repository,
+	projectRepo *project.Repository,
+	topicRepo *topic.Repository,
+	messageService *message.Service,
+) *IncomingService {
+	return &IncomingService{
+		repo:           repo,
+		projectRepo:    projectRepo,
+		topicRepo:      topicRepo,
+		messageService: messageService,
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whin_" + hex.EncodeToString(buf), nil
+}
+
+// requireTopicAdmin returns the topic once the user is an admin of its project
+func (s *IncomingService) requireTopicAdmin(topicID, userID uuid.UUID) (*topic.Topic, error) {
+	topicObj, err := s.topicRepo.GetByID(topicID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTopicNotFound
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	role, err := s.projectRepo.GetUserRole(topicObj.ProjectID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotProjectMember
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if role != "owner" && role != "admin" {
+		return nil, ErrNotProjectAdmin
+	}
+
+	return topicObj, nil
+}
+
+func (s *IncomingService) getIncoming(topicID, hookID uuid.UUID) (*IncomingWebhook, error) {
+	hook, err := s.repo.GetIncoming(topicID, hookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get incoming webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// Create incoming webhook. The token is returned once, only its hash is stored.
+func (s *IncomingService) Create(topicID, userID uuid.UUID, req CreateIncomingWebhookRequest) (*IncomingWebhook, string, error) {
+	topicObj, err := s.requireTopicAdmin(topicID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	hook := IncomingWebhook{
+		ProjectID:   topicObj.ProjectID,
+		TopicID:     topicID,
+		Name:        req.Name,
+		Description: req.Description,
+		IconURL:     req.IconURL,
+		TokenHash:   hashToken(token),
+		TokenPrefix: token[:12],
+		CreatedBy:   userID,
+	}
+	if err := s.repo.CreateIncoming(&hook); err != nil {
+		return nil, "", fmt.Errorf("failed to create incoming webhook: %w", err)
+	}
+
+	return &hook, token, nil
+}
+
+func (s *IncomingService) List(topicID, userID uuid.UUID) ([]IncomingWebhook, error) {
+	if _, err := s.requireTopicAdmin(topicID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListIncoming(topicID)
+}
+
+// Rotate replaces the token, the old URL stops working immediately
+func (s *IncomingService) Rotate(topicID, hookID, userID uuid.UUID) (*IncomingWebhook, string, error) {
+	if _, err := s.requireTopicAdmin(topicID, userID); err != nil {
+		return nil, "", err
+	}
+	hook, err := s.getIncoming(topicID, hookID)
+	if err != nil {
+		return nil, "", err
+	}
+	if hook.RevokedAt != nil {
+		return nil, "", ErrIncomingWebhookNotFound
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	hook.TokenHash = hashToken(token)
+	hook.TokenPrefix = token[:12]
+
+	if err := s.repo.UpdateIncoming(hook); err != nil {
+		return nil, "", fmt.Errorf("failed to update incoming webhook: %w", err)
+	}
+	return hook, token, nil
+}
+
+// Revoke disables the webhook, it stays listed for reference
+func (s *IncomingService) Revoke(topicID, hookID, userID uuid.UUID) error {
+	if _, err := s.requireTopicAdmin(topicID, userID); err != nil {
+		return err
+	}
+	hook, err := s.getIncoming(topicID, hookID)
+	if err != nil {
+		return err
+	}
+	if hook.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	hook.RevokedAt = &now
+	if err := s.repo.UpdateIncoming(hook); err != nil {
+		return fmt.Errorf("failed to revoke incoming webhook: %w", err)
+	}
+	return nil
+}
+
+// Post stores a Slack-compatible payload as an integration message
+func (s *IncomingService) Post(token string, payload SlackPayload) (*message.MessageWithUser, error) {
+	hook, err := s.repo.GetIncomingByTokenHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get incoming webhook: %w", err)
+	}
+
+	topicObj, err := s.topicRepo.GetByID(hook.TopicID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTopicNotFound
+		}
+		return nil, fmt.Errorf("failed to get topic: %w", err)
+	}
+	if topicObj.Visibility == "archived" {
+		return nil, ErrTopicArchived
+	}
+
+	content := strings.TrimSpace(payload.Text)
+	if content == "" {
+		content = attachmentsFallback(payload.Attachments)
+	}
+	if content == "" {
+		return nil, ErrNoText
+	}
+	if len(content) > maxIncomingContentLength {
+		return nil, ErrTextTooLong
+	}
+
+	metadata := IntegrationMetadata{
+		Username:    payload.Username,
+		IconURL:     payload.IconURL,
+		IconEmoji:   payload.IconEmoji,
+		Attachments: payload.Attachments,
+	}
+	metadata.Integration.WebhookID = hook.ID
+	metadata.Integration.Name = hook.Name
+	if metadata.Username == "" {
+		metadata.Username = hook.Name
+	}
+	if metadata.IconURL == "" && metadata.IconEmoji == "" && hook.IconURL != nil {
+		metadata.IconURL = *hook.IconURL
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	metadataStr := string(encoded)
+
+	created, err := s.messageService.CreateIntegration(hook.TopicID, content, &metadataStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.TouchIncoming(hook.ID, time.Now()); err != nil {
+		log.Printf("failed to update incoming webhook %s: %v", hook.ID, err)
+	}
+
+	return created, nil
+}
+
+// attachmentsFallback builds message text from attachments when a payload has none,
+// as Slack does for notifications
+func attachmentsFallback(attachments []SlackAttachment) string {
+	parts := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		switch {
+		case attachment.Fallback != "":
+			parts = append(parts, attachment.Fallback)
+		case attachment.Pretext != "":
+			parts = append(parts, attachment.Pretext)
+		case attachment.Title != "":
+			parts = append(parts, attachment.Title)
+		case attachment.Text != "":
+			parts = append(parts, attachment.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
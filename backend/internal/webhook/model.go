@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/m0khm/devhub/backend/internal/project"
+)
+
+// Project events that can be delivered to outgoing webhooks, as emitted by the services
+const (
+	EventPing                    = "ping"
+	EventMessageCreated          = project.EventMessageCreated
+	EventMemberAdded             = project.EventMemberAdded
+	EventInvitationAccepted      = project.EventInvitationAccepted
+	EventDeployTerminalOpened    = project.EventDeployTerminalOpened
+	EventDeployScriptRunFinished = project.EventDeployScriptRunFinished
+)
+
+// Events lists the events a webhook can subscribe to
+var Events = []string{
+	EventMessageCreated,
+	EventMemberAdded,
+	EventInvitationAccepted,
+	EventDeployTerminalOpened,
+	EventDeployScriptRunFinished,
+}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Webhook is an outgoing webhook of a project
+type Webhook struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID   uuid.UUID `json:"project_id" gorm:"not null"`
+	URL         string    `json:"url" gorm:"not null"`
+	Description *string   `json:"description"`
+	Events      []string  `json:"events" gorm:"type:jsonb;serializer:json;not null"`
+	Active      bool      `json:"active" gorm:"not null;default:true"`
+
+	// Signing secrets, encrypted at rest. The previous secret keeps signing
+	// deliveries for a grace period after a rotation.
+	EncryptedSecret         string     `json:"-" gorm:"not null"`
+	EncryptedPreviousSecret *string    `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at"`
+
+	CreatedBy uuid.UUID `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes reports whether the webhook wants an event
+func (w *Webhook) Subscribes(event string) bool {
+	if event == EventPing {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one queued or attempted POST of an event to a webhook.
+// Pending deliveries form the retry queue, the rest are the delivery log.
+type Delivery struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	WebhookID      uuid.UUID  `json:"webhook_id" gorm:"not null"`
+	EventID        uuid.UUID  `json:"event_id" gorm:"not null"`
+	Event          string     `json:"event" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:jsonb;not null"`
+	Status         string     `json:"status" gorm:"not null;default:'pending'"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus *int       `json:"response_status"`
+	ResponseBody   *string    `json:"response_body"`
+	Error          *string    `json:"error"`
+	DurationMs     *int       `json:"duration_ms"`
+	RedeliveryOf   *uuid.UUID `json:"redelivery_of"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Envelope is the JSON body POSTed to webhook URLs
+type Envelope struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	ProjectID uuid.UUID   `json:"project_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// DTOs
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2000"`
+	Description *string  `json:"description" validate:"omitempty,max=500"`
+	Events      []string `json:"events" validate:"required,min=1,dive,required"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" validate:"omitempty,url,max=2000"`
+	Description *string  `json:"description" validate:"omitempty,max=500"`
+	Events      []string `json:"events" validate:"omitempty,min=1,dive,required"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookWithSecret is returned when a secret is created or rotated,
+// the only time the plain secret is shown
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// IncomingWebhook lets an external system post integration messages into a topic
+type IncomingWebhook struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProjectID   uuid.UUID  `json:"project_id" gorm:"not null"`
+	TopicID     uuid.UUID  `json:"topic_id" gorm:"not null"`
+	Name        string     `json:"name" gorm:"not null"`
+	Description *string    `json:"description"`
+	IconURL     *string    `json:"icon_url"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex"`
+	TokenPrefix string     `json:"token_prefix" gorm:"not null"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   uuid.UUID  `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (IncomingWebhook) TableName() string {
+	return "incoming_webhooks"
+}
+
+type CreateIncomingWebhookRequest struct {
+	Name        string  `json:"name" validate:"required,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	IconURL     *string `json:"icon_url" validate:"omitempty,url,max=2000"`
+}
+
+// IncomingWebhookWithToken is returned when a token is created or rotated,
+// the only time the token and the full URL are shown
+type IncomingWebhookWithToken struct {
+	IncomingWebhook
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// SlackPayload is the Slack-compatible body accepted by incoming webhooks
+type SlackPayload struct {
+	Text        string            `json:"text"`
+	Username    string            `json:"username"`
+	IconURL     string            `json:"icon_url"`
+	IconEmoji   string            `json:"icon_emoji"`
+	Attachments []SlackAttachment `json:"attachments"`
+}
+
+type SlackAttachment struct {
+	Fallback   string       `json:"fallback,omitempty"`
+	Color      string       `json:"color,omitempty"`
+	Pretext    string       `json:"pretext,omitempty"`
+	AuthorName string       `json:"author_name,omitempty"`
+	AuthorLink string       `json:"author_link,omitempty"`
+	AuthorIcon string       `json:"author_icon,omitempty"`
+	Title      string       `json:"title,omitempty"`
+	TitleLink  string       `json:"title_link,omitempty"`
+	Text       string       `json:"text,omitempty"`
+	Fields     []SlackField `json:"fields,omitempty"`
+	ImageURL   string       `json:"image_url,omitempty"`
+	ThumbURL   string       `json:"thumb_url,omitempty"`
+	Footer     string       `json:"footer,omitempty"`
+	FooterIcon string       `json:"footer_icon,omitempty"`
+	Ts         interface{}  `json:"ts,omitempty"`
+}
+
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// IntegrationMetadata is stored as the metadata of integration messages
+type IntegrationMetadata struct {
+	Integration struct {
+		WebhookID uuid.UUID `json:"webhook_id"`
+		Name      string    `json:"name"`
+	} `json:"integration"`
+	Username    string            `json:"username,omitempty"`
+	IconURL     string            `json:"icon_url,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(webhook *Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *Repository) GetByID(projectID, webhookID uuid.UUID) (*Webhook, error) {
+	var webhook Webhook
+	err := r.db.First(&webhook, "id = ? AND project_id = ?", webhookID, projectID).Error
+	return &webhook, err
+}
+
+// GetByIDUnscoped loads a webhook without a project check, for the delivery worker
+func (r *Repository) GetByIDUnscoped(webhookID uuid.UUID) (*Webhook, error) {
+	var webhook Webhook
+	err := r.db.First(&webhook, "id = ?", webhookID).Error
+	return &webhook, err
+}
+
+func (r *Repository) ListByProject(projectID uuid.UUID) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := r.db.Where("project_id = ?", projectID).Order("created_at DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *Repository) ListActiveByProject(projectID uuid.UUID) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := r.db.Where("project_id = ? AND active = true", projectID).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *Repository) Update(webhook *Webhook) error {
+	return r.db.Save(webhook).Error
+}
+
+func (r *Repository) Delete(projectID, webhookID uuid.UUID) error {
+	return r.db.Delete(&Webhook{}, "id = ? AND project_id = ?", webhookID, projectID).Error
+}
+
+func (r *Repository) CreateDeliveries(deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+func (r *Repository) GetDelivery(webhookID, deliveryID uuid.UUID) (*Delivery, error) {
+	var delivery Delivery
+	err := r.db.First(&delivery, "id = ? AND webhook_id = ?", deliveryID, webhookID).Error
+	return &delivery, err
+}
+
+func (r *Repository) ListDeliveries(webhookID uuid.UUID, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.Where("webhook_id = ?", webhookID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDueDeliveries leases up to limit due deliveries to the caller.
+// The lease pushes next_attempt_at forward, so a delivery whose worker died
+// is picked up again once the lease runs out. SKIP LOCKED keeps replicas apart.
+func (r *Repository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.Raw(`
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, last_attempt_at = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now, now.Add(lease), now, StatusPending, now, limit).
+		Scan(&deliveries).Error
+	return deliveries, err
+}
+
+// SaveAttempt stores the outcome of a delivery attempt
+func (r *Repository) SaveAttempt(delivery *Delivery) error {
+	return r.db.Model(&Delivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"error":           delivery.Error,
+		"duration_ms":     delivery.DurationMs,
+	}).Error
+}
+
+func (r *Repository) CreateIncoming(hook *IncomingWebhook) error {
+	return r.db.Create(hook).Error
+}
+
+func (r *Repository) GetIncoming(topicID, hookID uuid.UUID) (*IncomingWebhook, error) {
+	var hook IncomingWebhook
+	err := r.db.First(&hook, "id = ? AND topic_id = ?", hookID, topicID).Error
+	return &hook, err
+}
+
+// GetIncomingByTokenHash returns a webhook that has not been revoked
+func (r *Repository) GetIncomingByTokenHash(tokenHash string) (*IncomingWebhook, error) {
+	var hook IncomingWebhook
+	err := r.db.First(&hook, "token_hash = ? AND revoked_at IS NULL", tokenHash).Error
+	return &hook, err
+}
+
+func (r *Repository) ListIncoming(topicID uuid.UUID) ([]IncomingWebhook, error) {
+	var hooks []IncomingWebhook
+	err := r.db.Where("topic_id = ?", topicID).Order("created_at DESC").Find(&hooks).Error
+	return hooks, err
+}
+
+func (r *Repository) UpdateIncoming(hook *IncomingWebhook) error {
+	return r.db.Save(hook).Error
+}
+
+func (r *Repository) TouchIncoming(hookID uuid.UUID, usedAt time.Time) error {
+	return r.db.Model(&IncomingWebhook{}).Where("id = ?", hookID).UpdateColumn("last_used_at", usedAt).Error
+}
@@ -0,0 +1,381 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/config"
+	"github.com/m0khm/devhub/backend/internal/deploy"
+	"github.com/m0khm/devhub/backend/internal/project"
+)
+
+var (
+	ErrNotProjectMember = errors.New("not a project member")
+	ErrNotProjectAdmin  = errors.New("not a project admin")
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrInvalidURL       = errors.New("invalid webhook url")
+	ErrUnknownEvent     = errors.New("unknown event")
+	ErrWebhookInactive  = errors.New("webhook is disabled")
+)
+
+// secretRotationWindow is how long the previous secret keeps signing after a rotation
+const secretRotationWindow = 24 * time.Hour
+
+// SecretBox encrypts signing secrets at rest (implemented by deploy.Encryptor)
+type SecretBox interface {
+	Encrypt(plain []byte) (string, error)
+	Decrypt(encoded string) ([]byte, error)
+}
+
+type Service struct {
+	repo                *Repository
+	projectRepo         *project.Repository
+	secrets             SecretBox
+	worker              *Worker
+	allowPrivateTargets bool
+}
+
+func NewService(repo *Repository, projectRepo *project.Repository, secrets SecretBox, worker *Worker, cfg config.WebhookConfig) *Service {
+	return &Service{
+		repo:                repo,
+		projectRepo:         projectRepo,
+		secrets:             secrets,
+		worker:              worker,
+		allowPrivateTargets: cfg.AllowPrivateTargets,
+	}
+}
+
+func (s *Service) requireAdmin(projectID, userID uuid.UUID) error {
+	role, err := s.projectRepo.GetUserRole(projectID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotProjectMember
+		}
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+	if role != "owner" && role != "admin" {
+		return ErrNotProjectAdmin
+	}
+	return nil
+}
+
+func (s *Service) validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return ErrInvalidURL
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return ErrInvalidURL
+	}
+	if !s.allowPrivateTargets {
+		if err := deploy.ValidateHost(parsed.Hostname()); err != nil {
+			return ErrInvalidURL
+		}
+	}
+	return nil
+}
+
+func validateEvents(events []string) error {
+	for _, event := range events {
+		known := false
+		for _, candidate := range Events {
+			if event == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: %s", ErrUnknownEvent, event)
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func (s *Service) getWebhook(projectID, webhookID uuid.UUID) (*Webhook, error) {
+	webhook, err := s.repo.GetByID(projectID, webhookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Create webhook, the secret is only returned here and on rotation
+func (s *Service) Create(projectID, userID uuid.UUID, req CreateWebhookRequest) (*WebhookWithSecret, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEvents(req.Events); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	encrypted, err := s.secrets.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	webhook := Webhook{
+		ProjectID:       projectID,
+		URL:             req.URL,
+		Description:     req.Description,
+		Events:          req.Events,
+		Active:          true,
+		EncryptedSecret: encrypted,
+		CreatedBy:       userID,
+	}
+	if err := s.repo.Create(&webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &WebhookWithSecret{Webhook: webhook, Secret: secret}, nil
+}
+
+func (s *Service) List(projectID, userID uuid.UUID) ([]Webhook, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProject(projectID)
+}
+
+func (s *Service) Get(projectID, webhookID, userID uuid.UUID) (*Webhook, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.getWebhook(projectID, webhookID)
+}
+
+func (s *Service) Update(projectID, webhookID, userID uuid.UUID, req UpdateWebhookRequest) (*Webhook, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	webhook, err := s.getWebhook(projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := s.validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if err := validateEvents(req.Events); err != nil {
+			return nil, err
+		}
+		webhook.Events = req.Events
+	}
+	if req.Description != nil {
+		webhook.Description = req.Description
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := s.repo.Update(webhook); err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (s *Service) Delete(projectID, webhookID, userID uuid.UUID) error {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return err
+	}
+	if _, err := s.getWebhook(projectID, webhookID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(projectID, webhookID); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// RotateSecret issues a new signing secret. Deliveries are signed with both
+// secrets for a day so receivers can switch over without dropping events.
+func (s *Service) RotateSecret(projectID, webhookID, userID uuid.UUID) (*WebhookWithSecret, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	webhook, err := s.getWebhook(projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	encrypted, err := s.secrets.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	previous := webhook.EncryptedSecret
+	expiresAt := time.Now().Add(secretRotationWindow)
+	webhook.EncryptedPreviousSecret = &previous
+	webhook.PreviousSecretExpiresAt = &expiresAt
+	webhook.EncryptedSecret = encrypted
+
+	if err := s.repo.Update(webhook); err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return &WebhookWithSecret{Webhook: *webhook, Secret: secret}, nil
+}
+
+func (s *Service) ListDeliveries(projectID, webhookID, userID uuid.UUID, limit int) ([]Delivery, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getWebhook(projectID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(webhookID, limit)
+}
+
+// Redeliver queues a copy of a past delivery, the original stays in the log
+func (s *Service) Redeliver(projectID, webhookID, deliveryID, userID uuid.UUID) (*Delivery, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	webhook, err := s.getWebhook(projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Active {
+		return nil, ErrWebhookInactive
+	}
+
+	original, err := s.repo.GetDelivery(webhookID, deliveryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+
+	now := time.Now()
+	deliveries := []Delivery{{
+		WebhookID:     webhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        StatusPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	}}
+	if err := s.repo.CreateDeliveries(deliveries); err != nil {
+		return nil, fmt.Errorf("failed to queue delivery: %w", err)
+	}
+	s.worker.Wake()
+
+	return &deliveries[0], nil
+}
+
+// Ping queues a ping event to a single webhook
+func (s *Service) Ping(projectID, webhookID, userID uuid.UUID) (*Delivery, error) {
+	if err := s.requireAdmin(projectID, userID); err != nil {
+		return nil, err
+	}
+	webhook, err := s.getWebhook(projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Active {
+		return nil, ErrWebhookInactive
+	}
+
+	deliveries, err := s.enqueue(projectID, []Webhook{*webhook}, EventPing, map[string]interface{}{
+		"webhook_id": webhook.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// Dispatch queues an event for every active webhook of the project that subscribes to it.
+// It matches project.ProjectEventFunc and is wired as the event hook of other services.
+func (s *Service) Dispatch(projectID uuid.UUID, event string, data interface{}) {
+	webhooks, err := s.repo.ListActiveByProject(projectID)
+	if err != nil {
+		log.Printf("webhooks: failed to list webhooks of project %s: %v", projectID, err)
+		return
+	}
+
+	subscribed := webhooks[:0]
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	if _, err := s.enqueue(projectID, subscribed, event, data); err != nil {
+		log.Printf("webhooks: failed to queue %s for project %s: %v", event, projectID, err)
+	}
+}
+
+func (s *Service) enqueue(projectID uuid.UUID, webhooks []Webhook, event string, data interface{}) ([]Delivery, error) {
+	envelope := Envelope{
+		ID:        uuid.New(),
+		Event:     event,
+		ProjectID: projectID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	now := time.Now()
+	deliveries := make([]Delivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, Delivery{
+			WebhookID:     webhook.ID,
+			EventID:       envelope.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        StatusPending,
+			NextAttemptAt: &now,
+		})
+	}
+
+	if err := s.repo.CreateDeliveries(deliveries); err != nil {
+		return nil, fmt.Errorf("failed to queue deliveries: %w", err)
+	}
+	s.worker.Wake()
+
+	return deliveries, nil
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/m0khm/devhub/backend/internal/config"
+	"github.com/m0khm/devhub/backend/internal/deploy"
+)
+
+const (
+	deliveryBatchSize   = 20
+	pollInterval        = 5 * time.Second
+	baseRetryDelay      = 30 * time.Second
+	maxRetryDelay       = 6 * time.Hour
+	maxResponseBodySize = 1024
+
+	SignatureHeader = "X-DevHub-Signature-256"
+	TimestampHeader = "X-DevHub-Timestamp"
+	EventHeader     = "X-DevHub-Event"
+	DeliveryHeader  = "X-DevHub-Delivery"
+)
+
+var errPrivateTarget = errors.New("webhook target resolves to a private address")
+
+// Worker posts queued deliveries and schedules retries with exponential backoff
+type Worker struct {
+	repo        *Repository
+	secrets     SecretBox
+	client      *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewWorker(repo *Repository, secrets SecretBox, cfg config.WebhookConfig) *Worker {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	return &Worker{
+		repo:        repo,
+		secrets:     secrets,
+		client:      newHTTPClient(timeout, cfg.AllowPrivateTargets),
+		timeout:     timeout,
+		maxAttempts: cfg.MaxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// newHTTPClient returns a client that does not follow redirects and,
+// unless allowPrivate is set, refuses to connect to private addresses.
+// The check runs on the resolved address, so DNS tricks cannot bypass it.
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if deploy.IsPrivateIP(net.ParseIP(host)) {
+				return errPrivateTarget
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Wake asks the worker to look for due deliveries right away
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due webhooks until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+
+		if err := w.deliverDue(ctx); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+	}
+}
+
+func (w *Worker) deliverDue(ctx context.Context) error {
+	for {
+		// The lease outlives the HTTP timeout so a slow attempt is not picked up twice
+		deliveries, err := w.repo.ClaimDueDeliveries(time.Now(), w.timeout+30*time.Second, deliveryBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim deliveries: %w", err)
+		}
+
+		for i := range deliveries {
+			w.attempt(ctx, &deliveries[i])
+		}
+
+		if len(deliveries) < deliveryBatchSize {
+			return nil
+		}
+	}
+}
+
+func (w *Worker) attempt(ctx context.Context, delivery *Delivery) {
+	webhook, err := w.repo.GetByIDUnscoped(delivery.WebhookID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("webhooks: failed to load webhook %s: %v", delivery.WebhookID, err)
+		}
+		return
+	}
+
+	if !webhook.Active {
+		w.finish(delivery, StatusFailed, "webhook is disabled")
+		return
+	}
+
+	secrets, err := w.signingSecrets(webhook)
+	if err != nil {
+		w.finish(delivery, StatusFailed, fmt.Sprintf("failed to read signing secret: %v", err))
+		return
+	}
+
+	started := time.Now()
+	status, body, err := w.post(ctx, webhook.URL, delivery, secrets)
+	duration := int(time.Since(started).Milliseconds())
+	delivery.DurationMs = &duration
+
+	if err == nil {
+		delivery.ResponseStatus = &status
+		delivery.ResponseBody = &body
+		if status >= 200 && status < 300 {
+			w.finish(delivery, StatusSucceeded, "")
+			return
+		}
+		err = fmt.Errorf("unexpected status %d", status)
+	}
+
+	if delivery.Attempts >= w.maxAttempts {
+		w.finish(delivery, StatusFailed, err.Error())
+		return
+	}
+
+	next := time.Now().Add(retryDelay(delivery.Attempts))
+	message := err.Error()
+	delivery.NextAttemptAt = &next
+	delivery.Error = &message
+	if err := w.repo.SaveAttempt(delivery); err != nil {
+		log.Printf("webhooks: failed to save delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func (w *Worker) finish(delivery *Delivery, status, message string) {
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	delivery.Error = nil
+	if message != "" {
+		delivery.Error = &message
+	}
+	if err := w.repo.SaveAttempt(delivery); err != nil {
+		log.Printf("webhooks: failed to save delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// signingSecrets returns the current secret, then the previous one while it is still valid
+func (w *Worker) signingSecrets(webhook *Webhook) ([]string, error) {
+	current, err := w.secrets.Decrypt(webhook.EncryptedSecret)
+	if err != nil {
+		return nil, err
+	}
+	secrets := []string{string(current)}
+
+	if webhook.EncryptedPreviousSecret != nil &&
+		webhook.PreviousSecretExpiresAt != nil &&
+		time.Now().Before(*webhook.PreviousSecretExpiresAt) {
+		previous, err := w.secrets.Decrypt(*webhook.EncryptedPreviousSecret)
+		if err == nil {
+			secrets = append(secrets, string(previous))
+		}
+	}
+
+	return secrets, nil
+}
+
+func (w *Worker) post(ctx context.Context, url string, delivery *Delivery, secrets []string) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DevHub-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Signature(secrets, timestamp, payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	return resp.StatusCode, string(body), nil
+}
+
+// Signature signs "<timestamp>.<payload>" with HMAC-SHA256.
+// Each secret yields one "sha256=<hex>" value, receivers accept any of them.
+func Signature(secrets []string, timestamp string, payload []byte) string {
+	values := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp))
+		mac.Write([]byte("."))
+		mac.Write(payload)
+		values = append(values, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(values, ",")
+}
+
+// retryDelay doubles the delay after every failed attempt, up to maxRetryDelay
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS thread_subscriptions;
+DROP INDEX IF EXISTS idx_messages_parent_id;
@@ -0,0 +1,11 @@
+CREATE INDEX idx_messages_parent_id ON messages(parent_id, created_at) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE thread_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(message_id, user_id)
+);
+
+CREATE INDEX idx_thread_subscriptions_user_id ON thread_subscriptions(user_id);
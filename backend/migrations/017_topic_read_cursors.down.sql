@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_mentions;
+DROP TABLE IF EXISTS topic_read_cursors;
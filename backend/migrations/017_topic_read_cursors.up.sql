@@ -0,0 +1,16 @@
+CREATE TABLE topic_read_cursors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    topic_id UUID NOT NULL REFERENCES topics(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    last_read_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(topic_id, user_id)
+);
+
+CREATE INDEX idx_topic_read_cursors_user_id ON topic_read_cursors(user_id);
+
+CREATE INDEX idx_messages_mentions ON messages USING GIN ((metadata->'mentions') jsonb_path_ops);
+
+CREATE TRIGGER update_topic_read_cursors_updated_at BEFORE UPDATE ON topic_read_cursors
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1 @@
+CREATE INDEX idx_messages_topic_keyset ON messages(topic_id, created_at DESC, id DESC) WHERE parent_id IS NULL;
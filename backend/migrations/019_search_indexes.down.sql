@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_topics_fts;
+DROP INDEX IF EXISTS idx_project_repo_files_content_fts;
+DROP INDEX IF EXISTS idx_messages_content_fts_russian;
@@ -0,0 +1,5 @@
+CREATE INDEX idx_messages_content_fts_russian ON messages USING GIN (to_tsvector('russian', content));
+
+CREATE INDEX idx_project_repo_files_content_fts ON project_repo_files USING GIN (to_tsvector('simple', content));
+
+CREATE INDEX idx_topics_fts ON topics USING GIN (to_tsvector('english', name || ' ' || coalesce(description, '')));
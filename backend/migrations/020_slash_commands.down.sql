@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS topic_mutes;
+DROP TABLE IF EXISTS message_reminders;
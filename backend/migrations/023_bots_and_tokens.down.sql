@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS personal_access_tokens;
+
+DROP INDEX IF EXISTS idx_users_bot_project_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS bot_project_id,
+    DROP COLUMN IF EXISTS is_bot;
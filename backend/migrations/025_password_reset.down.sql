@@ -0,0 +1,6 @@
+DELETE FROM email_confirmations WHERE purpose <> 'verify_email';
+
+ALTER TABLE email_confirmations DROP CONSTRAINT email_confirmations_email_purpose_key;
+ALTER TABLE email_confirmations ADD CONSTRAINT email_confirmations_email_key UNIQUE (email);
+
+ALTER TABLE email_confirmations DROP COLUMN purpose;
@@ -0,0 +1,4 @@
+ALTER TABLE email_confirmations ADD COLUMN purpose VARCHAR(32) NOT NULL DEFAULT 'verify_email';
+
+ALTER TABLE email_confirmations DROP CONSTRAINT email_confirmations_email_key;
+ALTER TABLE email_confirmations ADD CONSTRAINT email_confirmations_email_purpose_key UNIQUE (email, purpose);
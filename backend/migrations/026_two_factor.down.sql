@@ -0,0 +1,4 @@
+ALTER TABLE projects DROP COLUMN IF EXISTS require_two_factor;
+
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
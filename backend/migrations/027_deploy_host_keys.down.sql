@@ -0,0 +1,5 @@
+ALTER TABLE deploy_servers DROP COLUMN IF EXISTS pending_host_key_fingerprint;
+ALTER TABLE deploy_servers DROP COLUMN IF EXISTS host_key_approved_at;
+ALTER TABLE deploy_servers DROP COLUMN IF EXISTS host_key_approved_by;
+ALTER TABLE deploy_servers DROP COLUMN IF EXISTS host_key_fingerprint;
+ALTER TABLE deploy_servers DROP COLUMN IF EXISTS host_key;
@@ -0,0 +1,6 @@
+ALTER TABLE deploy_servers ADD COLUMN host_key TEXT;
+ALTER TABLE deploy_servers ADD COLUMN host_key_fingerprint VARCHAR(100);
+ALTER TABLE deploy_servers ADD COLUMN host_key_approved_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE deploy_servers ADD COLUMN host_key_approved_at TIMESTAMP;
+-- Key presented by the server that does not match the pinned one, waiting for approval
+ALTER TABLE deploy_servers ADD COLUMN pending_host_key_fingerprint VARCHAR(100);
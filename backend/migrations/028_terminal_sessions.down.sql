@@ -0,0 +1 @@
+DROP TABLE IF EXISTS deploy_terminal_sessions;
@@ -0,0 +1,22 @@
+CREATE TABLE deploy_terminal_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    server_id UUID NOT NULL REFERENCES deploy_servers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    audit_event_id UUID REFERENCES deploy_audit_events(id) ON DELETE SET NULL,
+    cols INTEGER NOT NULL,
+    rows INTEGER NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    duration_ms BIGINT,
+    exit_code INTEGER,
+    exit_signal VARCHAR(20),
+    recording_key TEXT,
+    recording_size BIGINT NOT NULL DEFAULT 0,
+    input_recorded BOOLEAN NOT NULL DEFAULT FALSE,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deploy_terminal_sessions_server_id ON deploy_terminal_sessions(server_id, started_at DESC);
+CREATE INDEX idx_deploy_terminal_sessions_project_id ON deploy_terminal_sessions(project_id);
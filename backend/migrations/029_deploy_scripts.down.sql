@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS deploy_script_run_targets;
+DROP TABLE IF EXISTS deploy_script_runs;
+DROP TABLE IF EXISTS deploy_script_versions;
+DROP TABLE IF EXISTS deploy_scripts;
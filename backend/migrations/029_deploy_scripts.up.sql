@@ -0,0 +1,59 @@
+CREATE TABLE deploy_scripts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    current_version INTEGER NOT NULL DEFAULT 1,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+
+-- Deleted scripts keep their runs, their names can be reused
+CREATE UNIQUE INDEX idx_deploy_scripts_project_name ON deploy_scripts(project_id, LOWER(name)) WHERE deleted_at IS NULL;
+
+CREATE TABLE deploy_script_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    script_id UUID NOT NULL REFERENCES deploy_scripts(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (script_id, version)
+);
+
+CREATE TABLE deploy_script_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    script_id UUID NOT NULL REFERENCES deploy_scripts(id) ON DELETE CASCADE,
+    script_version INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    timeout_seconds INTEGER NOT NULL,
+    concurrency INTEGER NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deploy_script_runs_project_id ON deploy_script_runs(project_id, started_at DESC);
+CREATE INDEX idx_deploy_script_runs_script_id ON deploy_script_runs(script_id, started_at DESC);
+
+CREATE TABLE deploy_script_run_targets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    run_id UUID NOT NULL REFERENCES deploy_script_runs(id) ON DELETE CASCADE,
+    server_id UUID REFERENCES deploy_servers(id) ON DELETE SET NULL,
+    server_name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    exit_code INTEGER,
+    exit_signal VARCHAR(20),
+    error TEXT,
+    log_key TEXT,
+    log_size BIGINT NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_deploy_script_run_targets_run_id ON deploy_script_run_targets(run_id);
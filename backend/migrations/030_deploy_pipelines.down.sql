@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS deploy_pipeline_stage_runs;
+DROP TABLE IF EXISTS deploy_pipeline_runs;
+DROP TABLE IF EXISTS deploy_pipelines;
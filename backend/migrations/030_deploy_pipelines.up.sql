@@ -0,0 +1,51 @@
+CREATE TABLE deploy_pipelines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    definition TEXT NOT NULL,
+    stages JSONB NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_deploy_pipelines_project_name ON deploy_pipelines(project_id, LOWER(name)) WHERE deleted_at IS NULL;
+
+CREATE TABLE deploy_pipeline_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    pipeline_id UUID NOT NULL REFERENCES deploy_pipelines(id) ON DELETE CASCADE,
+    pipeline_name VARCHAR(100) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    current_stage INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deploy_pipeline_runs_pipeline_id ON deploy_pipeline_runs(pipeline_id, started_at DESC);
+
+CREATE TABLE deploy_pipeline_stage_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pipeline_run_id UUID NOT NULL REFERENCES deploy_pipeline_runs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    script_id UUID REFERENCES deploy_scripts(id) ON DELETE SET NULL,
+    script_version INTEGER,
+    server_ids JSONB,
+    timeout_seconds INTEGER,
+    concurrency INTEGER,
+    message TEXT,
+    status VARCHAR(20) NOT NULL,
+    script_run_id UUID REFERENCES deploy_script_runs(id) ON DELETE SET NULL,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP,
+    comment TEXT,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    UNIQUE (pipeline_run_id, position)
+);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_deploy_pipeline_runs_running;
+DROP INDEX IF EXISTS idx_deploy_script_runs_running;
+
+ALTER TABLE deploy_pipeline_runs
+    DROP COLUMN IF EXISTS heartbeat_at,
+    DROP COLUMN IF EXISTS owner_instance;
+
+ALTER TABLE deploy_script_runs
+    DROP COLUMN IF EXISTS heartbeat_at,
+    DROP COLUMN IF EXISTS owner_instance;
@@ -0,0 +1,12 @@
+-- The API instance executing a run refreshes heartbeat_at, the other
+-- instances fail runs whose heartbeat stopped
+ALTER TABLE deploy_script_runs
+    ADD COLUMN owner_instance UUID,
+    ADD COLUMN heartbeat_at TIMESTAMP;
+
+ALTER TABLE deploy_pipeline_runs
+    ADD COLUMN owner_instance UUID,
+    ADD COLUMN heartbeat_at TIMESTAMP;
+
+CREATE INDEX idx_deploy_script_runs_running ON deploy_script_runs(owner_instance) WHERE status = 'running';
+CREATE INDEX idx_deploy_pipeline_runs_running ON deploy_pipeline_runs(owner_instance) WHERE status = 'running';
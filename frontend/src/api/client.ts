@@ -1,5 +1,6 @@
 import axios from 'axios';
 import { safeStorage } from '../shared/utils/storage';
+import { useAuthStore } from '../store/authStore';
 
 export const API_URL =
   (import.meta as any).env?.VITE_API_URL || `${window.location.origin}/api`;
@@ -31,6 +32,52 @@ function attachAuthHeader(config: any) {
 
 apiClient.interceptors.request.use(attachAuthHeader);
 
+// Access tokens are short-lived: on 401 exchange the refresh token once and retry.
+// Concurrent requests share a single refresh, refresh tokens are single-use.
+let refreshInFlight: Promise<string | null> | null = null;
+
+function refreshAccessToken(): Promise<string | null> {
+  const refreshToken = safeStorage.get('auth_refresh_token');
+  if (!refreshToken) return Promise.resolve(null);
+
+  if (!refreshInFlight) {
+    refreshInFlight = axios
+      .post(`${API_URL}/auth/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        useAuthStore
+          .getState()
+          .setAuth(response.data.user, response.data.token, response.data.refresh_token);
+        return response.data.token as string;
+      })
+      .catch(() => {
+        useAuthStore.getState().logout();
+        return null;
+      })
+      .finally(() => {
+        refreshInFlight = null;
+      });
+  }
+
+  return refreshInFlight;
+}
+
+apiClient.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    const isRefresh = String(original?.url || '').includes('/auth/refresh');
+    if (error.response?.status !== 401 || !original || original._retried || isRefresh) {
+      return Promise.reject(error);
+    }
+
+    const token = await refreshAccessToken();
+    if (!token) return Promise.reject(error);
+
+    original._retried = true;
+    return apiClient(original);
+  }
+);
+
 export function getAuthToken(): string | null {
   return safeStorage.get('auth_token') || localStorage.getItem('auth_token');
 }
@@ -48,7 +95,13 @@ export function getApiBaseUrl(): string {
 }
 
 // WS URL for Deploy terminal
-export function getDeployTerminalWsUrl(projectId: string, serverId: string, token?: string) {
+export function getDeployTerminalWsUrl(
+  projectId: string,
+  serverId: string,
+  token?: string,
+  size?: { cols: number; rows: number },
+  shared?: boolean
+) {
   const apiBase = getApiBaseUrl(); // e.g. https://dvhub.tech/api
   const apiUrl = new URL(String(apiBase), window.location.origin);
 
@@ -61,6 +114,34 @@ export function getDeployTerminalWsUrl(projectId: string, serverId: string, toke
   // add token if present
   apiUrl.search = '';
   if (token) apiUrl.searchParams.set('token', token);
+  if (size) {
+    apiUrl.searchParams.set('cols', String(size.cols));
+    apiUrl.searchParams.set('rows', String(size.rows));
+  }
+  if (shared) apiUrl.searchParams.set('shared', 'true');
 
   return apiUrl.toString();
 }
+
+function getDeployWsUrl(projectId: string, path: string, token?: string) {
+  const apiUrl = new URL(String(getApiBaseUrl()), window.location.origin);
+  apiUrl.protocol = apiUrl.protocol === 'https:' ? 'wss:' : 'ws:';
+
+  const basePath = apiUrl.pathname.replace(/\/+$/, '');
+  apiUrl.pathname = `${basePath}/projects/${projectId}/deploy/${path}`;
+
+  apiUrl.search = '';
+  if (token) apiUrl.searchParams.set('token', token);
+
+  return apiUrl.toString();
+}
+
+// WS URL to watch a shared Deploy terminal
+export function getDeployAttachWsUrl(projectId: string, sessionId: string, token?: string) {
+  return getDeployWsUrl(projectId, `sessions/${sessionId}/ws`, token);
+}
+
+// WS URL streaming the output of a Deploy script run
+export function getDeployRunWsUrl(projectId: string, runId: string, token?: string) {
+  return getDeployWsUrl(projectId, `runs/${runId}/ws`, token);
+}
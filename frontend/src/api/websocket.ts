@@ -6,6 +6,7 @@ interface WSHandlers {
   onMessageDeleted?: WSMessageHandler;
   onTyping?: WSMessageHandler;
   onReactionUpdated?: WSMessageHandler;
+  onThreadReply?: WSMessageHandler;
   onNotificationCreated?: WSMessageHandler;
   onConnect?: () => void;
   onDisconnect?: () => void;
@@ -101,6 +102,9 @@ export class WebSocketClient {
       case 'reaction_updated':
         this.handlers.onReactionUpdated?.(data.payload);
         break;
+      case 'thread_reply':
+        this.handlers.onThreadReply?.(data.payload);
+        break;
       case 'notification_created':
         this.handlers.onNotificationCreated?.(data.payload);
         break;
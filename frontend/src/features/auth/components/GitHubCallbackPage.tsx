@@ -0,0 +1,69 @@
+import React, { useEffect } from 'react';
+import { useNavigate } from 'react-router-dom';
+import toast from 'react-hot-toast';
+import { apiClient, setAuthToken } from '../../../api/client';
+import { useAuthStore } from '../../../store/authStore';
+import type { User } from '../../../shared/types';
+
+const errorMessages: Record<string, string> = {
+  access_denied: 'GitHub sign-in was cancelled',
+  invalid_state: 'GitHub sign-in expired, please try again',
+  no_verified_email: 'Your GitHub account has no verified primary email',
+  already_linked: 'This GitHub account is linked to another user',
+};
+
+// Landing page of the GitHub OAuth flow, the backend passes the result in the URL fragment
+export const GitHubCallbackPage: React.FC = () => {
+  const navigate = useNavigate();
+  const setAuth = useAuthStore((state) => state.setAuth);
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    window.history.replaceState(null, '', window.location.pathname);
+
+    const error = params.get('error');
+    if (error) {
+      toast.error(errorMessages[error] || 'GitHub sign-in failed');
+      navigate('/login', { replace: true });
+      return;
+    }
+
+    if (params.get('linked')) {
+      toast.success('GitHub account linked');
+      navigate('/app', { replace: true });
+      return;
+    }
+
+    const mfaToken = params.get('mfa_token');
+    if (mfaToken) {
+      navigate('/login', { replace: true, state: { mfaToken } });
+      return;
+    }
+
+    const token = params.get('token');
+    const refreshToken = params.get('refresh_token') || undefined;
+    if (!token) {
+      navigate('/login', { replace: true });
+      return;
+    }
+
+    setAuthToken(token);
+    apiClient
+      .get<User>('/auth/me')
+      .then((response) => {
+        setAuth(response.data, token, refreshToken);
+        toast.success('Welcome back!');
+        navigate('/app', { replace: true });
+      })
+      .catch(() => {
+        toast.error('GitHub sign-in failed');
+        navigate('/login', { replace: true });
+      });
+  }, [navigate, setAuth]);
+
+  return (
+    <div className="min-h-screen flex items-center justify-center text-slate-500">
+      Signing in with GitHub...
+    </div>
+  );
+};
@@ -1,17 +1,22 @@
 import React, { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
-import { apiClient } from '../../../api/client';
+import { useNavigate, useLocation, Link } from 'react-router-dom';
+import { apiClient, API_URL } from '../../../api/client';
 import { useAuthStore } from '../../../store/authStore';
 import toast from 'react-hot-toast';
-import type  { AuthResponse } from '../../../shared/types';
+import type  { AuthResponse, MFAChallengeResponse } from '../../../shared/types';
 
 export const LoginPage: React.FC = () => {
   const navigate = useNavigate();
+  const location = useLocation();
   const setAuth = useAuthStore((state) => state.setAuth);
-  
-  const [step, setStep] = useState(1);
+  // Set when a GitHub sign-in still needs the second factor
+  const initialMfaToken: string | null = (location.state as any)?.mfaToken ?? null;
+
+  const [step, setStep] = useState(initialMfaToken ? 3 : 1);
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [mfaToken, setMfaToken] = useState<string | null>(initialMfaToken);
+  const [mfaCode, setMfaCode] = useState('');
   const [loading, setLoading] = useState(false);
 
   const validateStep = () => {
@@ -32,6 +37,11 @@ export const LoginPage: React.FC = () => {
       return false;
     }
 
+    if (step === 3 && !mfaCode.trim()) {
+      toast.error('Please enter your authentication code');
+      return false;
+    }
+
     return true;
   };
 
@@ -44,25 +54,39 @@ export const LoginPage: React.FC = () => {
   };
 
   const handleBack = () => {
+    setMfaToken(null);
+    setMfaCode('');
     setStep(1);
   };
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
 
-    if (step !== 2 || !validateStep()) {
+    if (step === 1 || !validateStep()) {
       return;
     }
 
     setLoading(true);
 
     try {
-      const response = await apiClient.post<AuthResponse>('/auth/login', {
-        email,
-        password,
-      });
+      const response =
+        step === 3
+          ? await apiClient.post<AuthResponse>('/auth/login/mfa', {
+              mfa_token: mfaToken,
+              code: mfaCode.trim(),
+            })
+          : await apiClient.post<AuthResponse | MFAChallengeResponse>('/auth/login', {
+              email,
+              password,
+            });
+
+      if ('mfa_required' in response.data) {
+        setMfaToken(response.data.mfa_token);
+        setStep(3);
+        return;
+      }
 
-      setAuth(response.data.user, response.data.token);
+      setAuth(response.data.user, response.data.token, response.data.refresh_token);
       toast.success('Welcome back!');
       navigate('/app');
     } catch (error: any) {
@@ -85,7 +109,7 @@ export const LoginPage: React.FC = () => {
 
         <form onSubmit={handleSubmit} className="space-y-6">
           <div className="text-sm text-slate-500">
-            Step {step} of 2
+            Step {step} of {mfaToken ? 3 : 2}
           </div>
 
           {step === 1 && (
@@ -120,8 +144,24 @@ export const LoginPage: React.FC = () => {
             </div>
           )}
 
+          {step === 3 && (
+            <div>
+              <label className="block text-sm font-medium text-slate-700 mb-2">
+                Authentication code
+              </label>
+              <input
+                type="text"
+                value={mfaCode}
+                onChange={(e) => setMfaCode(e.target.value)}
+                className="w-full px-4 py-3 border border-slate-200 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent transition"
+                placeholder="123456 or a recovery code"
+                autoComplete="one-time-code"
+              />
+            </div>
+          )}
+
           <div className="flex flex-col gap-3">
-            {step === 2 && (
+            {step >= 2 && (
               <button
                 type="button"
                 onClick={handleBack}
@@ -151,6 +191,13 @@ export const LoginPage: React.FC = () => {
           </div>
         </form>
 
+        <a
+          href={`${API_URL}/auth/github`}
+          className="mt-4 block w-full rounded-lg border border-slate-200 bg-white py-3 text-center font-medium text-slate-700 hover:bg-slate-50 transition"
+        >
+          Sign in with GitHub
+        </a>
+
         <p className="mt-6 text-center text-slate-600">
           Don't have an account?{' '}
           <Link to="/register" className="text-blue-600 hover:text-blue-700 font-medium">
@@ -154,7 +154,7 @@ export const RegisterPage: React.FC = () => {
         code,
       });
 
-      setAuth(response.data.user, response.data.token);
+      setAuth(response.data.user, response.data.token, response.data.refresh_token);
       toast.success('Account created successfully!');
       navigate('/app');
     } catch (error: any) {
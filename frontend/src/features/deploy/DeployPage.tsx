@@ -1,7 +1,15 @@
 import React, { useEffect, useMemo, useRef, useState } from 'react';
 import { useParams } from 'react-router-dom';
 import toast from 'react-hot-toast';
-import { apiClient, getAuthToken, getDeployTerminalWsUrl } from '../../api/client';
+import {
+  apiClient,
+  getAuthToken,
+  getDeployAttachWsUrl,
+  getDeployTerminalWsUrl,
+} from '../../api/client';
+import { FilesPanel } from './FilesPanel';
+import { PipelinesPanel } from './PipelinesPanel';
+import { ScriptsPanel } from './ScriptsPanel';
 
 interface DeployServer {
   id: string;
@@ -13,8 +21,80 @@ interface DeployServer {
   auth_type: 'password' | 'key';
   created_at: string;
   updated_at: string;
+  host_key_fingerprint?: string | null;
+  host_key_approved_at?: string | null;
+  pending_host_key_fingerprint?: string | null;
 }
 
+// Control messages of the terminal protocol, raw terminal bytes travel in binary frames
+interface TerminalEvent {
+  type: 'pong' | 'error' | 'exit' | 'session' | 'presence' | 'resize';
+  message?: string;
+  code?: number;
+  signal?: string;
+  session_id?: string;
+  role?: TerminalRole;
+  shared?: boolean;
+  participants?: TerminalParticipant[];
+}
+
+type TerminalRole = 'owner' | 'writer' | 'viewer';
+
+interface TerminalParticipant {
+  user_id: string;
+  name: string;
+  role: TerminalRole;
+}
+
+// A shared terminal other admins of the project can attach to
+interface LiveTerminal {
+  session_id: string;
+  server_id: string;
+  server_name: string;
+  owner_id: string;
+  started_at: string;
+  participants: TerminalParticipant[];
+}
+
+const TERMINAL_PING_INTERVAL_MS = 30000;
+
+// Approximate character cell of the terminal <pre> (text-sm monospace)
+const measureTerminal = (element: HTMLElement | null) => {
+  if (!element) return { cols: 120, rows: 40 };
+  return {
+    cols: Math.max(20, Math.floor((element.clientWidth - 32) / 8.4)),
+    rows: Math.max(5, Math.floor((element.clientHeight - 24) / 20)),
+  };
+};
+
+interface TerminalSession {
+  id: string;
+  user_id: string;
+  started_at: string;
+  duration_ms?: number | null;
+  exit_code?: number | null;
+  exit_signal?: string | null;
+  has_recording: boolean;
+  truncated: boolean;
+}
+
+interface HostKeyInfo {
+  algorithm: string;
+  fingerprint: string;
+}
+
+// The API answers 428 until the host key fingerprint is confirmed and 409 when it changed meanwhile
+const confirmHostKey = (error: any, server: string): string | null => {
+  const status = error.response?.status;
+  const hostKey: HostKeyInfo | undefined = error.response?.data?.host_key;
+  if ((status !== 428 && status !== 409) || !hostKey) return null;
+  const confirmed = window.confirm(
+    `${status === 409 ? 'The host key changed. ' : ''}${server} presents a ${hostKey.algorithm} host key:\n\n` +
+      `${hostKey.fingerprint}\n\nTrust this key only if it matches the fingerprint on the server.`
+  );
+  return confirmed ? hostKey.fingerprint : null;
+};
+
 export const DeployPage: React.FC = () => {
   const { projectId } = useParams();
   const [servers, setServers] = useState<DeployServer[]>([]);
@@ -24,6 +104,12 @@ export const DeployPage: React.FC = () => {
   const [isConnecting, setIsConnecting] = useState(false);
   const [activePanel, setActivePanel] = useState<'deploy' | 'env' | null>(null);
   const wsRef = useRef<WebSocket | null>(null);
+  const terminalRef = useRef<HTMLPreElement | null>(null);
+  const [sessions, setSessions] = useState<TerminalSession[]>([]);
+  const [shareTerminal, setShareTerminal] = useState(false);
+  const [liveTerminals, setLiveTerminals] = useState<LiveTerminal[]>([]);
+  const [terminalRole, setTerminalRole] = useState<TerminalRole | null>(null);
+  const [participants, setParticipants] = useState<TerminalParticipant[]>([]);
 
   const [formState, setFormState] = useState({
     name: '',
@@ -54,7 +140,69 @@ export const DeployPage: React.FC = () => {
     };
   }, []);
 
-  const handleCreateServer = async () => {
+  const loadLiveTerminals = () => {
+    if (!projectId) return;
+    apiClient
+      .get<LiveTerminal[]>(`/projects/${projectId}/deploy/sessions/live`)
+      .then((response) => setLiveTerminals(Array.isArray(response.data) ? response.data : []))
+      .catch(() => setLiveTerminals([]));
+  };
+
+  useEffect(() => {
+    loadLiveTerminals();
+  }, [projectId]);
+
+  const loadSessions = (serverId: string) => {
+    if (!projectId) return;
+    apiClient
+      .get<TerminalSession[]>(`/projects/${projectId}/deploy/servers/${serverId}/sessions`)
+      .then((response) => setSessions(Array.isArray(response.data) ? response.data : []))
+      .catch(() => setSessions([]));
+  };
+
+  useEffect(() => {
+    if (selectedServerId) {
+      loadSessions(selectedServerId);
+    } else {
+      setSessions([]);
+    }
+  }, [projectId, selectedServerId]);
+
+  const handleDownloadRecording = async (session: TerminalSession) => {
+    if (!projectId || !selectedServerId) return;
+    try {
+      const response = await apiClient.get<Blob>(
+        `/projects/${projectId}/deploy/servers/${selectedServerId}/sessions/${session.id}/recording`,
+        { responseType: 'blob' }
+      );
+      const url = URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `${session.id}.cast`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch {
+      toast.error('Failed to download recording');
+    }
+  };
+
+  const sendControl = (message: Record<string, unknown>) => {
+    if (wsRef.current?.readyState === WebSocket.OPEN) {
+      wsRef.current.send(JSON.stringify(message));
+    }
+  };
+
+  useEffect(() => {
+    const handleResize = () => sendControl({ type: 'resize', ...measureTerminal(terminalRef.current) });
+    const ping = window.setInterval(() => sendControl({ type: 'ping' }), TERMINAL_PING_INTERVAL_MS);
+    window.addEventListener('resize', handleResize);
+    return () => {
+      window.removeEventListener('resize', handleResize);
+      window.clearInterval(ping);
+    };
+  }, []);
+
+  const handleCreateServer = async (hostKeyFingerprint?: string) => {
     if (!projectId) return;
     try {
       const payload = {
@@ -65,6 +213,7 @@ export const DeployPage: React.FC = () => {
         auth_type: formState.auth_type,
         password: formState.auth_type === 'password' ? formState.password : undefined,
         private_key: formState.auth_type === 'key' ? formState.private_key : undefined,
+        host_key_fingerprint: hostKeyFingerprint,
       };
 
       const response = await apiClient.post<DeployServer>(
@@ -82,47 +231,148 @@ export const DeployPage: React.FC = () => {
         private_key: '',
       });
     } catch (error: any) {
+      const fingerprint = confirmHostKey(error, formState.host);
+      if (fingerprint) {
+        await handleCreateServer(fingerprint);
+        return;
+      }
       toast.error(error.response?.data?.error || 'Failed to create server');
     }
   };
 
-  const handleConnect = () => {
-    if (!projectId || !selectedServerId) return;
+  const handleApproveHostKey = async (server: DeployServer, fingerprint?: string) => {
+    if (!projectId) return;
+    const expected = fingerprint ?? server.pending_host_key_fingerprint;
+    if (!expected) return;
+    if (
+      !fingerprint &&
+      !window.confirm(
+        `Approve the new host key of ${server.name}?\n\n${expected}\n\n` +
+          'Only approve it if the server was reinstalled or its keys were rotated.'
+      )
+    ) {
+      return;
+    }
+    try {
+      const response = await apiClient.post<DeployServer>(
+        `/projects/${projectId}/deploy/servers/${server.id}/host-key/approve`,
+        { fingerprint: expected }
+      );
+      setServers((prev) => prev.map((item) => (item.id === server.id ? response.data : item)));
+      toast.success('Host key approved');
+    } catch (error: any) {
+      const confirmed = confirmHostKey(error, server.host);
+      if (confirmed) {
+        await handleApproveHostKey(server, confirmed);
+        return;
+      }
+      toast.error(error.response?.data?.error || 'Failed to approve host key');
+    }
+  };
+
+  const openTerminal = (url: string, label: string, onClosed?: () => void) => {
     wsRef.current?.close();
     setIsConnecting(true);
-    const url = getDeployTerminalWsUrl(projectId, selectedServerId, getAuthToken() || undefined);
+    setTerminalRole(null);
+    setParticipants([]);
     const ws = new WebSocket(url);
+    ws.binaryType = 'arraybuffer';
     wsRef.current = ws;
+    const decoder = new TextDecoder();
 
     ws.onopen = () => {
       setIsConnecting(false);
-      setTerminalOutput((prev) => prev + `\n# Connected to ${selectedServer?.name}\n`);
+      setTerminalOutput((prev) => prev + `\n# Connected to ${label}\n`);
     };
     ws.onmessage = (event) => {
-      const message =
-        typeof event.data === 'string' ? event.data.replace(/\r\n/g, '\n') : event.data;
-      setTerminalOutput((prev) => prev + message);
+      if (typeof event.data !== 'string') {
+        const output = decoder.decode(event.data, { stream: true }).replace(/\r\n/g, '\n');
+        setTerminalOutput((prev) => prev + output);
+        return;
+      }
+      const message: TerminalEvent = JSON.parse(event.data);
+      if (message.type === 'exit') {
+        const status = message.signal
+          ? `killed by SIG${message.signal}`
+          : message.code !== undefined
+            ? `exit status ${message.code}`
+            : 'no exit status';
+        setTerminalOutput((prev) => prev + `\n# Shell ended (${status})\n`);
+      } else if (message.type === 'session') {
+        setTerminalRole(message.role ?? null);
+      } else if (message.type === 'presence') {
+        setParticipants(message.participants ?? []);
+      } else if (message.type === 'error') {
+        toast.error(message.message || 'Terminal error');
+      }
     };
     ws.onerror = () => {
       setIsConnecting(false);
       toast.error('Terminal connection error');
     };
-    ws.onclose = () => {
+    ws.onclose = (event) => {
+      if (wsRef.current === ws) {
+        setTerminalRole(null);
+        setParticipants([]);
+      }
       setIsConnecting(false);
       setTerminalOutput((prev) => prev + '\n# Disconnected\n');
+      if (event.reason && event.code !== 1000) {
+        toast.error(event.reason);
+      }
+      onClosed?.();
     };
   };
 
+  const handleConnect = () => {
+    if (!projectId || !selectedServerId) return;
+    const url = getDeployTerminalWsUrl(
+      projectId,
+      selectedServerId,
+      getAuthToken() || undefined,
+      measureTerminal(terminalRef.current),
+      shareTerminal
+    );
+    openTerminal(url, selectedServer?.name ?? 'server', () => {
+      loadSessions(selectedServerId);
+      loadLiveTerminals();
+    });
+    if (shareTerminal) {
+      window.setTimeout(loadLiveTerminals, 1000);
+    }
+  };
+
+  const handleAttach = (live: LiveTerminal) => {
+    if (!projectId) return;
+    const url = getDeployAttachWsUrl(projectId, live.session_id, getAuthToken() || undefined);
+    openTerminal(url, `${live.server_name} (shared)`, loadLiveTerminals);
+  };
+
+  const handleSetWriter = (participant: TerminalParticipant) => {
+    sendControl({
+      type: participant.role === 'writer' ? 'revoke' : 'grant',
+      user_id: participant.user_id,
+    });
+  };
+
   const handleSendCommand = () => {
     if (!terminalInput.trim()) return;
     if (!wsRef.current || wsRef.current.readyState !== WebSocket.OPEN) {
       toast.error('Terminal not connected');
       return;
     }
-    wsRef.current.send(`${terminalInput}\n`);
+    wsRef.current.send(new TextEncoder().encode(`${terminalInput}\n`));
     setTerminalInput('');
   };
 
+  const handleInterrupt = () => {
+    if (!wsRef.current || wsRef.current.readyState !== WebSocket.OPEN) {
+      toast.error('Terminal not connected');
+      return;
+    }
+    sendControl({ type: 'signal', signal: 'INT' });
+  };
+
   return (
     <div className="min-h-screen bg-slate-950 text-slate-100">
       <div className="border-b border-slate-800 bg-slate-950/90 px-6 py-5">
@@ -140,7 +390,7 @@ export const DeployPage: React.FC = () => {
               }
               className="rounded-md border border-slate-700 px-3 py-2 text-sm text-slate-200 hover:border-emerald-500"
             >
-              Deploy options
+              Deploy scripts
             </button>
             <button
               type="button"
@@ -149,6 +399,14 @@ export const DeployPage: React.FC = () => {
             >
               Env variables
             </button>
+            <label className="flex items-center gap-2 text-sm text-slate-300">
+              <input
+                type="checkbox"
+                checked={shareTerminal}
+                onChange={(event) => setShareTerminal(event.target.checked)}
+              />
+              Share with admins
+            </label>
             <button
               type="button"
               onClick={handleConnect}
@@ -233,7 +491,7 @@ export const DeployPage: React.FC = () => {
               )}
               <button
                 type="button"
-                onClick={handleCreateServer}
+                onClick={() => handleCreateServer()}
                 className="w-full rounded-md bg-emerald-600 px-3 py-2 text-sm font-semibold text-white hover:bg-emerald-500"
               >
                 Save server
@@ -263,6 +521,24 @@ export const DeployPage: React.FC = () => {
                       {server.username}@{server.host}:{server.port}
                     </p>
                     <p className="mt-1 text-[11px] text-slate-500">Auth: {server.auth_type}</p>
+                    {server.host_key_fingerprint && (
+                      <p className="mt-1 break-all font-mono text-[11px] text-slate-500">
+                        {server.host_key_fingerprint}
+                      </p>
+                    )}
+                    {server.pending_host_key_fingerprint && (
+                      <span
+                        role="button"
+                        tabIndex={0}
+                        onClick={(event) => {
+                          event.stopPropagation();
+                          handleApproveHostKey(server);
+                        }}
+                        className="mt-2 inline-block rounded border border-amber-600 px-2 py-1 text-[11px] text-amber-300 hover:bg-amber-900/40"
+                      >
+                        Host key changed · review
+                      </span>
+                    )}
                   </button>
                 ))
               )}
@@ -276,11 +552,11 @@ export const DeployPage: React.FC = () => {
               <div className="flex items-center justify-between border-b border-slate-800 px-4 py-3">
                 <div>
                   <p className="text-sm font-semibold text-slate-200">
-                    {activePanel === 'deploy' ? 'Deployment options' : 'Environment variables'}
+                    {activePanel === 'deploy' ? 'Deploy scripts' : 'Environment variables'}
                   </p>
                   <p className="text-xs text-slate-400">
                     {activePanel === 'deploy'
-                      ? 'Configure runtime, build, and rollout preferences.'
+                      ? 'Save versioned scripts, run them on servers and chain them into pipelines.'
                       : 'Store secrets and configuration for deploy steps.'}
                   </p>
                 </div>
@@ -293,28 +569,10 @@ export const DeployPage: React.FC = () => {
                 </button>
               </div>
               <div className="space-y-3 px-4 py-4 text-sm text-slate-300">
-                {activePanel === 'deploy' ? (
+                {activePanel === 'deploy' && projectId ? (
                   <>
-                    <div>
-                      <label className="mb-1 block text-xs uppercase tracking-wide text-slate-500">
-                        Deploy strategy
-                      </label>
-                      <input
-                        disabled
-                        placeholder="Rolling / Blue-green / Canary"
-                        className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 text-sm text-slate-100"
-                      />
-                    </div>
-                    <div>
-                      <label className="mb-1 block text-xs uppercase tracking-wide text-slate-500">
-                        Build command
-                      </label>
-                      <input
-                        disabled
-                        placeholder="npm run build"
-                        className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 text-sm text-slate-100"
-                      />
-                    </div>
+                    <ScriptsPanel projectId={projectId} servers={servers} />
+                    <PipelinesPanel projectId={projectId} />
                   </>
                 ) : (
                   <>
@@ -340,18 +598,47 @@ export const DeployPage: React.FC = () => {
                     </div>
                   </>
                 )}
-                <p className="text-xs text-slate-500">
-                  These forms are placeholders. We can enable editing once the deploy pipeline is
-                  wired in.
-                </p>
+                {activePanel === 'env' && (
+                  <p className="text-xs text-slate-500">
+                    These forms are placeholders. We can enable editing once the deploy pipeline is
+                    wired in.
+                  </p>
+                )}
               </div>
             </div>
           )}
           <div className="rounded-lg border border-slate-800 bg-slate-900/60">
-            <div className="border-b border-slate-800 px-4 py-3 text-sm text-slate-300">
-              Terminal {selectedServer ? `· ${selectedServer.name}` : ''}
+            <div className="flex flex-wrap items-center justify-between gap-2 border-b border-slate-800 px-4 py-3 text-sm text-slate-300">
+              <span>
+                Terminal {selectedServer ? `· ${selectedServer.name}` : ''}
+                {terminalRole === 'viewer' && ' · read-only'}
+              </span>
+              {participants.length > 1 && (
+                <div className="flex flex-wrap items-center gap-2 text-xs">
+                  {participants.map((participant) => (
+                    <span
+                      key={participant.user_id}
+                      className="rounded border border-slate-700 px-2 py-1 text-slate-300"
+                    >
+                      {participant.name} · {participant.role}
+                      {terminalRole === 'owner' && participant.role !== 'owner' && (
+                        <button
+                          type="button"
+                          onClick={() => handleSetWriter(participant)}
+                          className="ml-2 text-emerald-300 hover:text-emerald-200"
+                        >
+                          {participant.role === 'writer' ? 'Revoke' : 'Allow typing'}
+                        </button>
+                      )}
+                    </span>
+                  ))}
+                </div>
+              )}
             </div>
-            <pre className="h-[420px] overflow-y-auto bg-black px-4 py-3 font-mono text-sm text-emerald-200 whitespace-pre-wrap">
+            <pre
+              ref={terminalRef}
+              className="h-[420px] overflow-y-auto bg-black px-4 py-3 font-mono text-sm text-emerald-200 whitespace-pre-wrap"
+            >
               {terminalOutput || 'Select a server and connect to start.'}
             </pre>
             <div className="flex items-center gap-2 border-t border-slate-800 px-4 py-3">
@@ -374,8 +661,88 @@ export const DeployPage: React.FC = () => {
               >
                 Send
               </button>
+              <button
+                type="button"
+                onClick={handleInterrupt}
+                className="rounded-md border border-slate-700 px-3 py-2 text-sm text-slate-200 hover:border-rose-500"
+              >
+                Ctrl+C
+              </button>
             </div>
           </div>
+          {liveTerminals.length > 0 && (
+            <div className="rounded-lg border border-slate-800 bg-slate-900/60">
+              <div className="flex items-center justify-between border-b border-slate-800 px-4 py-3 text-sm text-slate-300">
+                Shared terminals
+                <button
+                  type="button"
+                  onClick={loadLiveTerminals}
+                  className="text-xs text-slate-400 hover:text-slate-200"
+                >
+                  Refresh
+                </button>
+              </div>
+              {liveTerminals.map((live) => (
+                <div
+                  key={live.session_id}
+                  className="flex items-center justify-between border-b border-slate-800 px-4 py-2 text-xs text-slate-300"
+                >
+                  <span>
+                    {live.server_name} · since {new Date(live.started_at).toLocaleTimeString()} ·{' '}
+                    {live.participants.map((participant) => participant.name).join(', ')}
+                  </span>
+                  <button
+                    type="button"
+                    onClick={() => handleAttach(live)}
+                    className="text-emerald-300 hover:text-emerald-200"
+                  >
+                    Watch
+                  </button>
+                </div>
+              ))}
+            </div>
+          )}
+          {selectedServer && (
+            <div className="rounded-lg border border-slate-800 bg-slate-900/60">
+              <div className="border-b border-slate-800 px-4 py-3 text-sm text-slate-300">
+                Recorded sessions
+              </div>
+              {sessions.length === 0 ? (
+                <div className="px-4 py-4 text-sm text-slate-400">No sessions yet.</div>
+              ) : (
+                sessions.map((session) => (
+                  <div
+                    key={session.id}
+                    className="flex items-center justify-between border-b border-slate-800 px-4 py-2 text-xs text-slate-300"
+                  >
+                    <span>
+                      {new Date(session.started_at).toLocaleString()}
+                      {session.duration_ms != null &&
+                        ` · ${Math.round(session.duration_ms / 1000)}s`}
+                      {session.exit_signal
+                        ? ` · SIG${session.exit_signal}`
+                        : session.exit_code != null
+                          ? ` · exit ${session.exit_code}`
+                          : ''}
+                      {session.truncated && ' · truncated'}
+                    </span>
+                    {session.has_recording && (
+                      <button
+                        type="button"
+                        onClick={() => handleDownloadRecording(session)}
+                        className="text-emerald-300 hover:text-emerald-200"
+                      >
+                        Download .cast
+                      </button>
+                    )}
+                  </div>
+                ))
+              )}
+            </div>
+          )}
+          {selectedServer && projectId && (
+            <FilesPanel projectId={projectId} serverId={selectedServer.id} />
+          )}
         </section>
       </div>
     </div>
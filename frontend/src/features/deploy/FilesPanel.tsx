@@ -0,0 +1,233 @@
+import React, { useEffect, useState } from 'react';
+import toast from 'react-hot-toast';
+import { apiClient } from '../../api/client';
+
+interface RemoteFile {
+  name: string;
+  path: string;
+  size: number;
+  mode: string;
+  mod_time: string;
+  is_dir: boolean;
+  is_link: boolean;
+}
+
+interface RemoteFileContent {
+  path: string;
+  content: string;
+  size: number;
+  mod_time: string;
+}
+
+interface FilesPanelProps {
+  projectId: string;
+  serverId: string;
+}
+
+const parentPath = (path: string) => {
+  const parent = path.replace(/\/[^/]*\/?$/, '');
+  return parent === '' ? '/' : parent;
+};
+
+export const FilesPanel: React.FC<FilesPanelProps> = ({ projectId, serverId }) => {
+  const [path, setPath] = useState('');
+  const [files, setFiles] = useState<RemoteFile[]>([]);
+  const [editing, setEditing] = useState<RemoteFileContent | null>(null);
+  const [draft, setDraft] = useState('');
+  const [attachmentId, setAttachmentId] = useState('');
+  const baseUrl = `/projects/${projectId}/deploy/servers/${serverId}/files`;
+
+  const loadDir = async (dir: string) => {
+    try {
+      const response = await apiClient.get<{ path: string; files: RemoteFile[] }>(baseUrl, {
+        params: { path: dir },
+      });
+      setPath(response.data.path);
+      setFiles(Array.isArray(response.data.files) ? response.data.files : []);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to list files');
+    }
+  };
+
+  useEffect(() => {
+    setEditing(null);
+    loadDir('');
+  }, [projectId, serverId]);
+
+  const handleDownload = async (file: RemoteFile) => {
+    try {
+      const response = await apiClient.get<Blob>(`${baseUrl}/download`, {
+        params: { path: file.path },
+        responseType: 'blob',
+      });
+      const url = URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = file.name;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch {
+      toast.error('Failed to download file');
+    }
+  };
+
+  const handleEdit = async (file: RemoteFile) => {
+    try {
+      const response = await apiClient.get<RemoteFileContent>(`${baseUrl}/content`, {
+        params: { path: file.path },
+      });
+      setEditing(response.data);
+      setDraft(response.data.content);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to open file');
+    }
+  };
+
+  const handleSave = async () => {
+    if (!editing) return;
+    try {
+      const response = await apiClient.put<RemoteFileContent>(
+        `${baseUrl}/content`,
+        { content: draft, mod_time: editing.mod_time },
+        { params: { path: editing.path } }
+      );
+      setEditing(response.data);
+      toast.success('File saved');
+      loadDir(path);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to save file');
+    }
+  };
+
+  const handleUpload = async (event: React.ChangeEvent<HTMLInputElement>) => {
+    const file = event.target.files?.[0];
+    event.target.value = '';
+    if (!file) return;
+    const formData = new FormData();
+    formData.append('file', file);
+    try {
+      await apiClient.post(`${baseUrl}/upload`, formData, { params: { path } });
+      toast.success(`Uploaded ${file.name}`);
+      loadDir(path);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to upload file');
+    }
+  };
+
+  const handleTransfer = async () => {
+    if (!attachmentId.trim()) return;
+    try {
+      await apiClient.post(`${baseUrl}/transfer`, { message_id: attachmentId.trim(), path });
+      setAttachmentId('');
+      toast.success('Attachment copied to the server');
+      loadDir(path);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to copy attachment');
+    }
+  };
+
+  return (
+    <div className="rounded-lg border border-slate-800 bg-slate-900/60">
+      <div className="flex items-center justify-between border-b border-slate-800 px-4 py-3 text-sm text-slate-300">
+        <span className="font-mono">{path || 'Files'}</span>
+        <label className="cursor-pointer text-xs text-emerald-300 hover:text-emerald-200">
+          Upload
+          <input type="file" className="hidden" onChange={handleUpload} />
+        </label>
+      </div>
+      <div className="flex gap-2 border-b border-slate-800 px-4 py-2">
+        <input
+          value={attachmentId}
+          onChange={(event) => setAttachmentId(event.target.value)}
+          placeholder="Chat attachment message ID"
+          className="flex-1 rounded-md border border-slate-700 bg-slate-950 px-2 py-1 text-xs text-slate-100 focus:border-emerald-500 focus:outline-none"
+        />
+        <button
+          type="button"
+          onClick={handleTransfer}
+          className="text-xs text-emerald-300 hover:text-emerald-200"
+        >
+          Copy here
+        </button>
+      </div>
+      {editing ? (
+        <div className="space-y-2 px-4 py-3">
+          <p className="font-mono text-xs text-slate-400">{editing.path}</p>
+          <textarea
+            value={draft}
+            onChange={(event) => setDraft(event.target.value)}
+            rows={16}
+            className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 font-mono text-xs text-slate-100 focus:border-emerald-500 focus:outline-none"
+          />
+          <div className="flex gap-3">
+            <button
+              type="button"
+              onClick={handleSave}
+              className="rounded-md bg-emerald-600 px-3 py-1 text-xs font-semibold text-white hover:bg-emerald-500"
+            >
+              Save
+            </button>
+            <button
+              type="button"
+              onClick={() => setEditing(null)}
+              className="text-xs text-slate-400 hover:text-slate-200"
+            >
+              Close
+            </button>
+          </div>
+        </div>
+      ) : (
+        <div className="max-h-80 overflow-y-auto text-xs text-slate-300">
+          {path && path !== '/' && (
+            <button
+              type="button"
+              onClick={() => loadDir(parentPath(path))}
+              className="block w-full border-b border-slate-800 px-4 py-1 text-left hover:bg-slate-800/70"
+            >
+              ..
+            </button>
+          )}
+          {files.map((file) => (
+            <div
+              key={file.path}
+              className="flex items-center justify-between border-b border-slate-800 px-4 py-1"
+            >
+              {file.is_dir ? (
+                <button
+                  type="button"
+                  onClick={() => loadDir(file.path)}
+                  className="font-mono text-emerald-300 hover:text-emerald-200"
+                >
+                  {file.name}/
+                </button>
+              ) : (
+                <span className="font-mono">{file.name}</span>
+              )}
+              <span className="flex items-center gap-3 text-slate-500">
+                {file.mode} · {file.size} B
+                {!file.is_dir && (
+                  <>
+                    <button
+                      type="button"
+                      onClick={() => handleEdit(file)}
+                      className="text-emerald-300 hover:text-emerald-200"
+                    >
+                      Edit
+                    </button>
+                    <button
+                      type="button"
+                      onClick={() => handleDownload(file)}
+                      className="text-emerald-300 hover:text-emerald-200"
+                    >
+                      Download
+                    </button>
+                  </>
+                )}
+              </span>
+            </div>
+          ))}
+        </div>
+      )}
+    </div>
+  );
+};
@@ -0,0 +1,238 @@
+import React, { useEffect, useState } from 'react';
+import toast from 'react-hot-toast';
+import { apiClient } from '../../api/client';
+
+interface DeployPipeline {
+  id: string;
+  name: string;
+  description?: string | null;
+  definition: string;
+}
+
+interface PipelineStageRun {
+  id: string;
+  position: number;
+  name: string;
+  type: 'script' | 'approval';
+  status: string;
+  message?: string | null;
+  comment?: string | null;
+}
+
+interface PipelineRun {
+  id: string;
+  pipeline_id: string;
+  user_id: string;
+  status: string;
+  current_stage: number;
+  started_at: string;
+  finished_at?: string | null;
+  stages: PipelineStageRun[];
+}
+
+interface PipelinesPanelProps {
+  projectId: string;
+}
+
+const exampleDefinition = `stages:
+  - name: build
+    script: build
+    servers: [build-1]
+  - name: release
+    type: approval
+    message: Check the build before rolling out
+  - name: roll out
+    script: deploy
+    servers: [web-1, web-2]
+    concurrency: 1`;
+
+const statusColor = (status: string) =>
+  status === 'succeeded'
+    ? 'text-emerald-300'
+    : status === 'running' || status === 'pending' || status === 'waiting_approval'
+      ? 'text-amber-300'
+      : status === 'skipped'
+        ? 'text-slate-500'
+        : 'text-rose-300';
+
+export const PipelinesPanel: React.FC<PipelinesPanelProps> = ({ projectId }) => {
+  const [pipelines, setPipelines] = useState<DeployPipeline[]>([]);
+  const [editing, setEditing] = useState<DeployPipeline | null>(null);
+  const [form, setForm] = useState({ name: '', definition: exampleDefinition });
+  const [runs, setRuns] = useState<PipelineRun[]>([]);
+
+  const loadPipelines = () => {
+    apiClient
+      .get<DeployPipeline[]>(`/projects/${projectId}/deploy/pipelines`)
+      .then((response) => setPipelines(Array.isArray(response.data) ? response.data : []))
+      .catch(() => setPipelines([]));
+  };
+
+  const loadRuns = (pipeline: DeployPipeline | null) => {
+    if (!pipeline) {
+      setRuns([]);
+      return;
+    }
+    apiClient
+      .get<PipelineRun[]>(`/projects/${projectId}/deploy/pipelines/${pipeline.id}/runs`, {
+        params: { limit: 20 },
+      })
+      .then((response) => setRuns(Array.isArray(response.data) ? response.data : []))
+      .catch(() => setRuns([]));
+  };
+
+  useEffect(() => {
+    loadPipelines();
+  }, [projectId]);
+
+  const handleEdit = (pipeline: DeployPipeline | null) => {
+    setEditing(pipeline);
+    setForm(
+      pipeline
+        ? { name: pipeline.name, definition: pipeline.definition }
+        : { name: '', definition: exampleDefinition }
+    );
+    loadRuns(pipeline);
+  };
+
+  const handleSave = async () => {
+    try {
+      const response = editing
+        ? await apiClient.put<DeployPipeline>(
+            `/projects/${projectId}/deploy/pipelines/${editing.id}`,
+            form
+          )
+        : await apiClient.post<DeployPipeline>(`/projects/${projectId}/deploy/pipelines`, form);
+      setEditing(response.data);
+      loadPipelines();
+      toast.success('Pipeline saved');
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to save pipeline');
+    }
+  };
+
+  const handleStart = async () => {
+    if (!editing) return;
+    try {
+      await apiClient.post(`/projects/${projectId}/deploy/pipelines/${editing.id}/runs`);
+      toast.success('Pipeline started, progress is posted in the deploy topic');
+      loadRuns(editing);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to start pipeline');
+    }
+  };
+
+  const handleDecide = async (run: PipelineRun, decision: 'approve' | 'reject') => {
+    const comment = window.prompt(decision === 'approve' ? 'Approval comment' : 'Reason for rejecting');
+    if (comment === null) return;
+    try {
+      await apiClient.post(`/projects/${projectId}/deploy/pipeline-runs/${run.id}/${decision}`, {
+        comment: comment || undefined,
+      });
+      loadRuns(editing);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || `Failed to ${decision} stage`);
+    }
+  };
+
+  return (
+    <div className="space-y-4 border-t border-slate-800 pt-4 text-sm text-slate-300">
+      <p className="text-xs uppercase tracking-wide text-slate-500">Pipelines</p>
+      <div className="flex flex-wrap gap-2">
+        {pipelines.map((pipeline) => (
+          <button
+            key={pipeline.id}
+            type="button"
+            onClick={() => handleEdit(pipeline)}
+            className={`rounded border px-2 py-1 text-xs ${
+              editing?.id === pipeline.id ? 'border-emerald-500' : 'border-slate-700'
+            }`}
+          >
+            {pipeline.name}
+          </button>
+        ))}
+        <button
+          type="button"
+          onClick={() => handleEdit(null)}
+          className="rounded border border-dashed border-slate-700 px-2 py-1 text-xs"
+        >
+          New pipeline
+        </button>
+      </div>
+      <input
+        value={form.name}
+        onChange={(event) => setForm((prev) => ({ ...prev, name: event.target.value }))}
+        placeholder="Pipeline name"
+        className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 text-sm text-slate-100 focus:border-emerald-500 focus:outline-none"
+      />
+      <textarea
+        value={form.definition}
+        onChange={(event) => setForm((prev) => ({ ...prev, definition: event.target.value }))}
+        rows={8}
+        className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 font-mono text-sm text-slate-100 focus:border-emerald-500 focus:outline-none"
+      />
+      <div className="flex items-center gap-3">
+        <button
+          type="button"
+          onClick={handleSave}
+          className="rounded-md border border-slate-700 px-3 py-2 text-sm text-slate-200 hover:border-emerald-500"
+        >
+          {editing ? 'Save pipeline' : 'Create pipeline'}
+        </button>
+        <button
+          type="button"
+          onClick={handleStart}
+          disabled={!editing}
+          className="rounded-md bg-emerald-600 px-3 py-2 text-sm font-semibold text-white hover:bg-emerald-500 disabled:cursor-not-allowed disabled:bg-emerald-800"
+        >
+          Start
+        </button>
+        {editing && (
+          <button
+            type="button"
+            onClick={() => loadRuns(editing)}
+            className="text-xs text-emerald-300 hover:text-emerald-200"
+          >
+            Refresh runs
+          </button>
+        )}
+      </div>
+
+      {runs.map((run) => (
+        <div key={run.id} className="rounded border border-slate-800 px-3 py-2 text-xs">
+          <div className="flex justify-between">
+            <span className={statusColor(run.status)}>{run.status}</span>
+            <span>{new Date(run.started_at).toLocaleString()}</span>
+          </div>
+          <ol className="mt-1 space-y-1">
+            {run.stages.map((stage) => (
+              <li key={stage.id}>
+                {stage.position + 1}. {stage.name} ·{' '}
+                <span className={statusColor(stage.status)}>{stage.status}</span>
+                {stage.comment && ` · ${stage.comment}`}
+                {stage.status === 'waiting_approval' && (
+                  <>
+                    <button
+                      type="button"
+                      onClick={() => handleDecide(run, 'approve')}
+                      className="ml-2 text-emerald-300 hover:text-emerald-200"
+                    >
+                      Approve
+                    </button>
+                    <button
+                      type="button"
+                      onClick={() => handleDecide(run, 'reject')}
+                      className="ml-2 text-rose-300 hover:text-rose-200"
+                    >
+                      Reject
+                    </button>
+                  </>
+                )}
+              </li>
+            ))}
+          </ol>
+        </div>
+      ))}
+    </div>
+  );
+};
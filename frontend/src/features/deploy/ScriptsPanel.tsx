@@ -0,0 +1,309 @@
+import React, { useEffect, useRef, useState } from 'react';
+import toast from 'react-hot-toast';
+import { apiClient, getAuthToken, getDeployRunWsUrl } from '../../api/client';
+
+interface DeployScript {
+  id: string;
+  name: string;
+  description?: string | null;
+  current_version: number;
+  content?: string;
+}
+
+interface RunTarget {
+  id: string;
+  server_id?: string | null;
+  server_name: string;
+  status: string;
+  exit_code?: number | null;
+  exit_signal?: string | null;
+  error?: string | null;
+  has_log: boolean;
+  truncated: boolean;
+}
+
+interface ScriptRun {
+  id: string;
+  script_id: string;
+  script_version: number;
+  status: string;
+  started_at: string;
+  finished_at?: string | null;
+  targets: RunTarget[];
+}
+
+// Messages of the run websocket
+interface RunEvent {
+  type: 'run' | 'target' | 'output' | 'pong';
+  run?: ScriptRun;
+  target?: RunTarget;
+  target_id?: string;
+  data?: string;
+}
+
+interface ScriptsPanelProps {
+  projectId: string;
+  servers: { id: string; name: string }[];
+}
+
+const statusColor = (status: string) =>
+  status === 'succeeded'
+    ? 'text-emerald-300'
+    : status === 'running' || status === 'pending'
+      ? 'text-amber-300'
+      : 'text-rose-300';
+
+export const ScriptsPanel: React.FC<ScriptsPanelProps> = ({ projectId, servers }) => {
+  const [scripts, setScripts] = useState<DeployScript[]>([]);
+  const [editing, setEditing] = useState<DeployScript | null>(null);
+  const [form, setForm] = useState({ name: '', content: '' });
+  const [targetIds, setTargetIds] = useState<string[]>([]);
+  const [runs, setRuns] = useState<ScriptRun[]>([]);
+  const [activeRun, setActiveRun] = useState<ScriptRun | null>(null);
+  const [output, setOutput] = useState<Record<string, string>>({});
+  const wsRef = useRef<WebSocket | null>(null);
+
+  const loadScripts = () => {
+    apiClient
+      .get<DeployScript[]>(`/projects/${projectId}/deploy/scripts`)
+      .then((response) => setScripts(Array.isArray(response.data) ? response.data : []))
+      .catch(() => setScripts([]));
+  };
+
+  const loadRuns = () => {
+    apiClient
+      .get<ScriptRun[]>(`/projects/${projectId}/deploy/runs`, { params: { limit: 20 } })
+      .then((response) => setRuns(Array.isArray(response.data) ? response.data : []))
+      .catch(() => setRuns([]));
+  };
+
+  useEffect(() => {
+    loadScripts();
+    loadRuns();
+    return () => {
+      wsRef.current?.close();
+    };
+  }, [projectId]);
+
+  const handleEdit = async (script: DeployScript | null) => {
+    if (!script) {
+      setEditing(null);
+      setForm({ name: '', content: '' });
+      return;
+    }
+    try {
+      const response = await apiClient.get<DeployScript>(
+        `/projects/${projectId}/deploy/scripts/${script.id}`
+      );
+      setEditing(response.data);
+      setForm({ name: response.data.name, content: response.data.content ?? '' });
+    } catch {
+      toast.error('Failed to load script');
+    }
+  };
+
+  const handleSave = async () => {
+    try {
+      if (editing) {
+        const response = await apiClient.put<DeployScript>(
+          `/projects/${projectId}/deploy/scripts/${editing.id}`,
+          form
+        );
+        setEditing(response.data);
+        toast.success(`Saved version ${response.data.current_version}`);
+      } else {
+        const response = await apiClient.post<DeployScript>(
+          `/projects/${projectId}/deploy/scripts`,
+          form
+        );
+        setEditing(response.data);
+      }
+      loadScripts();
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to save script');
+    }
+  };
+
+  const watchRun = (run: ScriptRun) => {
+    wsRef.current?.close();
+    setActiveRun(run);
+    setOutput({});
+    const ws = new WebSocket(getDeployRunWsUrl(projectId, run.id, getAuthToken() || undefined));
+    wsRef.current = ws;
+    ws.onmessage = (event) => {
+      const message: RunEvent = JSON.parse(event.data);
+      if (message.type === 'run' && message.run) {
+        setActiveRun(message.run);
+      } else if (message.type === 'target' && message.target) {
+        const target = message.target;
+        setActiveRun((prev) =>
+          prev
+            ? { ...prev, targets: prev.targets.map((item) => (item.id === target.id ? target : item)) }
+            : prev
+        );
+      } else if (message.type === 'output' && message.target_id) {
+        const targetId = message.target_id;
+        setOutput((prev) => ({ ...prev, [targetId]: (prev[targetId] ?? '') + (message.data ?? '') }));
+      }
+    };
+    ws.onclose = () => loadRuns();
+  };
+
+  const handleRun = async () => {
+    if (!editing || targetIds.length === 0) return;
+    try {
+      const response = await apiClient.post<ScriptRun>(
+        `/projects/${projectId}/deploy/scripts/${editing.id}/runs`,
+        { server_ids: targetIds }
+      );
+      watchRun(response.data);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to start script');
+    }
+  };
+
+  const handleDownloadLog = async (run: ScriptRun, target: RunTarget) => {
+    try {
+      const response = await apiClient.get<Blob>(
+        `/projects/${projectId}/deploy/runs/${run.id}/targets/${target.id}/log`,
+        { responseType: 'blob' }
+      );
+      const url = URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `${run.id}-${target.server_name}.log`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch {
+      toast.error('Failed to download log');
+    }
+  };
+
+  return (
+    <div className="space-y-4 text-sm text-slate-300">
+      <div className="flex flex-wrap gap-2">
+        {scripts.map((script) => (
+          <button
+            key={script.id}
+            type="button"
+            onClick={() => handleEdit(script)}
+            className={`rounded border px-2 py-1 text-xs ${
+              editing?.id === script.id ? 'border-emerald-500' : 'border-slate-700'
+            }`}
+          >
+            {script.name} · v{script.current_version}
+          </button>
+        ))}
+        <button
+          type="button"
+          onClick={() => handleEdit(null)}
+          className="rounded border border-dashed border-slate-700 px-2 py-1 text-xs"
+        >
+          New script
+        </button>
+      </div>
+      <input
+        value={form.name}
+        onChange={(event) => setForm((prev) => ({ ...prev, name: event.target.value }))}
+        placeholder="Script name"
+        className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 text-sm text-slate-100 focus:border-emerald-500 focus:outline-none"
+      />
+      <textarea
+        value={form.content}
+        onChange={(event) => setForm((prev) => ({ ...prev, content: event.target.value }))}
+        rows={5}
+        placeholder="git pull && docker compose up -d"
+        className="w-full rounded-md border border-slate-700 bg-slate-950 px-3 py-2 font-mono text-sm text-slate-100 focus:border-emerald-500 focus:outline-none"
+      />
+      <div className="flex flex-wrap items-center gap-3">
+        <button
+          type="button"
+          onClick={handleSave}
+          className="rounded-md border border-slate-700 px-3 py-2 text-sm text-slate-200 hover:border-emerald-500"
+        >
+          {editing ? 'Save new version' : 'Create script'}
+        </button>
+        {servers.map((server) => (
+          <label key={server.id} className="flex items-center gap-1 text-xs">
+            <input
+              type="checkbox"
+              checked={targetIds.includes(server.id)}
+              onChange={(event) =>
+                setTargetIds((prev) =>
+                  event.target.checked ? [...prev, server.id] : prev.filter((id) => id !== server.id)
+                )
+              }
+            />
+            {server.name}
+          </label>
+        ))}
+        <button
+          type="button"
+          onClick={handleRun}
+          disabled={!editing || targetIds.length === 0}
+          className="rounded-md bg-emerald-600 px-3 py-2 text-sm font-semibold text-white hover:bg-emerald-500 disabled:cursor-not-allowed disabled:bg-emerald-800"
+        >
+          Run
+        </button>
+      </div>
+
+      {activeRun && (
+        <div className="space-y-2">
+          <p className={statusColor(activeRun.status)}>
+            Run v{activeRun.script_version} · {activeRun.status}
+          </p>
+          {activeRun.targets.map((target) => (
+            <div key={target.id} className="rounded border border-slate-800">
+              <div className="flex justify-between px-3 py-1 text-xs">
+                <span>{target.server_name}</span>
+                <span className={statusColor(target.status)}>
+                  {target.status}
+                  {target.exit_code != null && ` · exit ${target.exit_code}`}
+                  {target.exit_signal && ` · SIG${target.exit_signal}`}
+                  {target.error && ` · ${target.error}`}
+                </span>
+              </div>
+              <pre className="max-h-48 overflow-y-auto bg-black px-3 py-2 font-mono text-xs text-emerald-200 whitespace-pre-wrap">
+                {output[target.id] ?? ''}
+              </pre>
+            </div>
+          ))}
+        </div>
+      )}
+
+      {runs.length > 0 && (
+        <div>
+          <p className="mb-1 text-xs uppercase tracking-wide text-slate-500">Run history</p>
+          {runs.map((run) => (
+            <div key={run.id} className="border-b border-slate-800 py-1 text-xs">
+              <span className={statusColor(run.status)}>{run.status}</span> ·{' '}
+              {scripts.find((script) => script.id === run.script_id)?.name ?? 'deleted script'} v
+              {run.script_version} · {new Date(run.started_at).toLocaleString()}
+              {run.status === 'running' && (
+                <button
+                  type="button"
+                  onClick={() => watchRun(run)}
+                  className="ml-2 text-emerald-300 hover:text-emerald-200"
+                >
+                  Watch
+                </button>
+              )}
+              {run.targets
+                .filter((target) => target.has_log)
+                .map((target) => (
+                  <button
+                    key={target.id}
+                    type="button"
+                    onClick={() => handleDownloadLog(run, target)}
+                    className="ml-2 text-emerald-300 hover:text-emerald-200"
+                  >
+                    {target.server_name}.log
+                  </button>
+                ))}
+            </div>
+          ))}
+        </div>
+      )}
+    </div>
+  );
+};
@@ -12,6 +12,7 @@ import { MessageList } from './MessageList';
 import { MessageItem } from './MessageItem';
 import { MessageInput } from './MessageInput';
 import { SearchBar } from './SearchBar';
+import { ThreadPanel, type ThreadState } from './ThreadPanel';
 import { VideoCallButton } from '../../video/components/VideoCallButton';
 import { TopicSettingsModal } from '../../topics/components/TopicSettingsModal';
 
@@ -37,14 +38,12 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
   const [pinnedMessages, setPinnedMessages] = useState<Message[]>([]);
   const [typingUsers, setTypingUsers] = useState<Set<string>>(new Set());
   const [highlightedMessageId, setHighlightedMessageId] = useState<string | null>(null);
-  const [replyToMessage, setReplyToMessage] = useState<Message | null>(null);
+  const [thread, setThread] = useState<ThreadState | null>(null);
   const [threadRootId, setThreadRootId] = useState<string | null>(null);
+  const [threadLoading, setThreadLoading] = useState(false);
+  const threadRootRef = useRef<string | null>(null);
   const [isTopicSettingsOpen, setIsTopicSettingsOpen] = useState(false);
   const typingTimeoutRef = useRef<Record<string, NodeJS.Timeout>>({});
-  const messageMap = useMemo(
-    () => new Map(messages.map((message) => [message.id, message])),
-    [messages]
-  );
   const quickLink = useMemo(() => {
     if (topic.type === 'code') {
       return {
@@ -67,49 +66,23 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
     return null;
   }, [topic.project_id, topic.type]);
 
-  const resolveThreadRoot = (message: Message) => {
-    let current = message;
-    while (current.parent_id && messageMap.has(current.parent_id)) {
-      const next = messageMap.get(current.parent_id);
-      if (!next) break;
-      current = next;
-    }
-    return current;
+  // Replies of the open thread, kept in sync with thread_reply and message events
+  const updateThreadReplies = (update: (replies: Message[]) => Message[]) => {
+    setThread((prev) => (prev ? { ...prev, replies: update(prev.replies) } : prev));
   };
 
-  const threadRoot = threadRootId ? messageMap.get(threadRootId) ?? null : null;
-  const threadMessages = useMemo(() => {
-    if (!threadRootId) return [];
-    const childrenMap = new Map<string, Message[]>();
-    messages.forEach((message) => {
-      if (!message.parent_id) return;
-      const list = childrenMap.get(message.parent_id) ?? [];
-      list.push(message);
-      childrenMap.set(message.parent_id, list);
-    });
-
-    const sortByTime = (items: Message[]) =>
-      items.sort(
-        (a, b) => new Date(a.created_at).getTime() - new Date(b.created_at).getTime()
-      );
-
-    const result: Message[] = [];
-    const visit = (parentId: string) => {
-      const children = sortByTime(childrenMap.get(parentId) ?? []);
-      children.forEach((child) => {
-        result.push(child);
-        visit(child.id);
-      });
-    };
-
-    visit(threadRootId);
-    return result;
-  }, [messages, threadRootId]);
+  const appendThreadReply = (reply: Message) => {
+    if (threadRootRef.current !== reply.parent_id) return;
+    updateThreadReplies((replies) =>
+      replies.some((item) => item.id === reply.id) ? replies : [...replies, reply]
+    );
+  };
 
   useEffect(() => {
     clearMessages();
     setHighlightedMessageId(null);
     setPinnedMessages([]);
+    handleCloseThread();
     loadMessages();
     loadPinnedMessages();
 
@@ -118,8 +91,20 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
         onNewMessage: (payload) => {
           addMessage(payload.message);
         },
+        onThreadReply: (payload) => {
+          updateMessage(payload.parent_id, {
+            reply_count: payload.reply_count,
+            last_reply_at: payload.last_reply_at ?? null,
+          });
+          appendThreadReply(payload.message);
+        },
         onMessageUpdated: (payload) => {
           updateMessage(payload.message.id, payload.message);
+          updateThreadReplies((replies) =>
+            replies.map((reply) =>
+              reply.id === payload.message.id ? { ...reply, ...payload.message } : reply
+            )
+          );
           setPinnedMessages((prev) =>
             prev.map((message) =>
               message.id === payload.message.id ? { ...message, ...payload.message } : message
@@ -128,6 +113,12 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
         },
         onMessageDeleted: (payload) => {
           deleteMessage(payload.message_id);
+          if (threadRootRef.current === payload.message_id) {
+            handleCloseThread();
+          }
+          updateThreadReplies((replies) =>
+            replies.filter((reply) => reply.id !== payload.message_id)
+          );
           setPinnedMessages((prev) =>
             prev.filter((message) => message.id !== payload.message_id)
           );
@@ -137,6 +128,11 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
         },
         onReactionUpdated: (payload) => {
           updateMessage(payload.message_id, { reactions: payload.reactions });
+          updateThreadReplies((replies) =>
+            replies.map((reply) =>
+              reply.id === payload.message_id ? { ...reply, reactions: payload.reactions } : reply
+            )
+          );
         },
         onNotificationCreated: (payload) => {
           const notification = payload?.notification;
@@ -174,10 +170,10 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
   const loadMessages = async () => {
     try {
       setLoading(true);
-      const response = await apiClient.get<Message[]>(
+      const response = await apiClient.get<{ messages: Message[] }>(
         `/topics/${topic.id}/messages?limit=50`
       );
-      const list = Array.isArray(response.data) ? response.data : [];
+      const list = Array.isArray(response.data?.messages) ? response.data.messages : [];
       setMessages(list.reverse()); // oldest first
     } catch (error) {
       toast.error('Failed to load messages');
@@ -223,10 +219,8 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
         content,
         type: 'text',
         metadata,
-        parent_id: replyToMessage?.id,
       });
       // добавится через WS
-      setReplyToMessage(null);
     } catch (error: any) {
       toast.error(error.response?.data?.error || 'Failed to send message');
     }
@@ -261,14 +255,71 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
     }
   };
 
-  const handleReply = (message: Message) => {
-    setReplyToMessage(message);
-    setThreadRootId(resolveThreadRoot(message).id);
+  // Replies live in the thread panel, the topic only lists top-level messages
+  const handleOpenThread = async (message: Message) => {
+    const rootId = message.parent_id ?? message.id;
+    threadRootRef.current = rootId;
+    setThreadRootId(rootId);
+    setThread(null);
+    setThreadLoading(true);
+    try {
+      const response = await apiClient.get<{
+        parent: Message;
+        replies: Message[];
+        following: boolean;
+      }>(`/messages/${rootId}/thread`);
+      if (threadRootRef.current !== rootId) return;
+      setThread({
+        parent: response.data.parent,
+        replies: Array.isArray(response.data.replies) ? response.data.replies : [],
+        following: response.data.following,
+      });
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to load thread');
+      if (threadRootRef.current === rootId) handleCloseThread();
+    } finally {
+      if (threadRootRef.current === rootId) setThreadLoading(false);
+    }
   };
 
   const handleCloseThread = () => {
+    threadRootRef.current = null;
     setThreadRootId(null);
-    setReplyToMessage(null);
+    setThread(null);
+    setThreadLoading(false);
+  };
+
+  const handleSendReply = async (content: string, mentions: Mention[]) => {
+    if (!threadRootId) return;
+    try {
+      const metadata =
+        mentions.length > 0 ? JSON.stringify({ mentions }) : undefined;
+      const response = await apiClient.post<Message>(`/topics/${topic.id}/messages`, {
+        content,
+        type: 'text',
+        metadata,
+        parent_id: threadRootId,
+      });
+      appendThreadReply(response.data);
+      setThread((prev) => (prev ? { ...prev, following: true } : prev));
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to send reply');
+    }
+  };
+
+  const handleToggleFollow = async () => {
+    if (!thread) return;
+    const following = thread.following;
+    try {
+      if (following) {
+        await apiClient.delete(`/messages/${thread.parent.id}/thread/follow`);
+      } else {
+        await apiClient.post(`/messages/${thread.parent.id}/thread/follow`);
+      }
+      setThread((prev) => (prev ? { ...prev, following: !following } : prev));
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || 'Failed to update thread subscription');
+    }
   };
 
   return (
@@ -331,7 +382,8 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
             loading={loading}
             highlightedMessageId={highlightedMessageId}
             onHighlightMessage={setHighlightedMessageId}
-            onReply={handleReply}
+            onReply={handleOpenThread}
+            onOpenThread={handleOpenThread}
             onTogglePin={handleTogglePin}
             onDelete={handleDeleteMessage}
           />
@@ -355,11 +407,22 @@ export const ChatView: React.FC<ChatViewProps> = ({ topic, onOpenProfile }) => {
           {/* Message input */}
           <MessageInput
             topicId={topic.id}
+            projectId={topic.project_id}
             onSend={handleSendMessage}
-            replyTo={replyToMessage}
-            onCancelReply={() => setReplyToMessage(null)}
           />
         </div>
+        {threadRootId && (
+          <ThreadPanel
+            thread={thread}
+            loading={threadLoading}
+            topicId={topic.id}
+            projectId={topic.project_id}
+            onSend={handleSendReply}
+            onToggleFollow={handleToggleFollow}
+            onDelete={handleDeleteMessage}
+            onClose={handleCloseThread}
+          />
+        )}
       </div>
     </div>
   );
@@ -21,6 +21,7 @@ interface MessageItemProps {
   isHighlighted?: boolean;
   onSelect?: (message: Message) => void;
   onReply?: (message: Message) => void;
+  onOpenThread?: (message: Message) => void;
   onTogglePin?: (message: Message) => void;
   onDelete?: (message: Message) => void;
 }
@@ -34,6 +35,7 @@ export const MessageItem = React.forwardRef<HTMLDivElement, MessageItemProps>(
       isHighlighted = false,
       onSelect,
       onReply,
+      onOpenThread,
       onTogglePin,
       onDelete,
     },
@@ -509,6 +511,21 @@ export const MessageItem = React.forwardRef<HTMLDivElement, MessageItemProps>(
               )}
             </div>
 
+            {onOpenThread && (message.reply_count ?? 0) > 0 && (
+              <button
+                type="button"
+                onClick={(event) => {
+                  event.stopPropagation();
+                  onOpenThread(message);
+                }}
+                className="mt-1 text-xs font-semibold text-sky-300 hover:text-sky-200"
+              >
+                {message.reply_count === 1 ? '1 reply' : `${message.reply_count} replies`}
+                {message.last_reply_at &&
+                  ` · ${formatDistanceToNow(new Date(message.last_reply_at), { addSuffix: true })}`}
+              </button>
+            )}
+
             {/* Reactions */}
             {message.reactions && message.reactions.length > 0 && (
               <div className="flex gap-1 mt-1 flex-wrap">
@@ -9,6 +9,7 @@ interface MessageListProps {
   highlightedMessageId?: string | null;
   onHighlightMessage?: (messageId: string | null) => void;
   onReply?: (message: Message) => void;
+  onOpenThread?: (message: Message) => void;
   onTogglePin?: (message: Message) => void;
   onDelete?: (message: Message) => void;
 }
@@ -20,6 +21,7 @@ export const MessageList: React.FC<MessageListProps> = ({
   highlightedMessageId,
   onHighlightMessage,
   onReply,
+  onOpenThread,
   onTogglePin,
   onDelete,
 }) => {
@@ -198,6 +200,7 @@ export const MessageList: React.FC<MessageListProps> = ({
           messageMap={messageMap}
           isHighlighted={message.id === highlightedMessageId}
           onReply={onReply}
+          onOpenThread={onOpenThread}
           onTogglePin={onTogglePin}
           onDelete={onDelete}
         />
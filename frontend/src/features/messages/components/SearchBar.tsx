@@ -29,10 +29,10 @@ export const SearchBar: React.FC<SearchBarProps> = ({ topicId, onJumpToMessage }
 
     setLoading(true);
     try {
-      const response = await apiClient.get<Message[]>(
+      const response = await apiClient.get<{ messages: Message[] }>(
         `/topics/${topicId}/messages?query=${encodeURIComponent(q)}`
       );
-      const messages = response.data;
+      const messages = response.data.messages ?? [];
       const ids = messages.map((message) => message.id);
       setResults(messages);
       setResultIds(ids);
@@ -0,0 +1,86 @@
+import React, { useEffect, useRef } from 'react';
+import { XMarkIcon } from '@heroicons/react/24/outline';
+import type { Mention, Message } from '../../../shared/types';
+import { MessageItem } from './MessageItem';
+import { MessageInput } from './MessageInput';
+
+export interface ThreadState {
+  parent: Message;
+  replies: Message[];
+  following: boolean;
+}
+
+interface ThreadPanelProps {
+  thread: ThreadState | null;
+  loading: boolean;
+  topicId: string;
+  projectId: string;
+  onSend: (content: string, mentions: Mention[]) => void;
+  onToggleFollow: () => void;
+  onDelete?: (message: Message) => void;
+  onClose: () => void;
+}
+
+export const ThreadPanel: React.FC<ThreadPanelProps> = ({
+  thread,
+  loading,
+  topicId,
+  projectId,
+  onSend,
+  onToggleFollow,
+  onDelete,
+  onClose,
+}) => {
+  const endRef = useRef<HTMLDivElement>(null);
+
+  useEffect(() => {
+    endRef.current?.scrollIntoView({ behavior: 'smooth' });
+  }, [thread?.replies.length]);
+
+  return (
+    <aside className="flex w-full max-w-md min-h-0 flex-col border-l border-border/70 bg-slate-900/80">
+      <div className="flex items-center justify-between border-b border-border/70 px-4 py-3">
+        <span className="text-sm font-semibold text-slate-200">Thread</span>
+        <div className="flex items-center gap-2">
+          {thread && (
+            <button
+              type="button"
+              onClick={onToggleFollow}
+              className="rounded-md border border-slate-700/60 px-2 py-1 text-xs text-slate-300 transition hover:bg-slate-800/80"
+            >
+              {thread.following ? 'Unfollow' : 'Follow'}
+            </button>
+          )}
+          <button
+            type="button"
+            onClick={onClose}
+            className="rounded-full p-1 text-slate-300 transition hover:bg-slate-800/80 hover:text-slate-100"
+            aria-label="Close thread"
+          >
+            <XMarkIcon className="h-4 w-4" />
+          </button>
+        </div>
+      </div>
+
+      {loading || !thread ? (
+        <div className="flex flex-1 items-center justify-center text-sm text-slate-400">
+          Loading thread...
+        </div>
+      ) : (
+        <>
+          <div className="flex-1 min-h-0 space-y-3 overflow-y-auto px-4 py-3">
+            <MessageItem message={thread.parent} onDelete={onDelete} />
+            <div className="border-t border-slate-700/60 pt-2 text-xs text-slate-400">
+              {thread.replies.length === 1 ? '1 reply' : `${thread.replies.length} replies`}
+            </div>
+            {thread.replies.map((reply) => (
+              <MessageItem key={reply.id} message={reply} onDelete={onDelete} />
+            ))}
+            <div ref={endRef} />
+          </div>
+          <MessageInput topicId={topicId} projectId={projectId} onSend={onSend} />
+        </>
+      )}
+    </aside>
+  );
+};
@@ -142,7 +142,7 @@ export const ProfileForm: React.FC<ProfileFormProps> = ({ user, onSaved }) => {
           code: emailCode.trim(),
         }
       );
-      setAuth(response.data.user, response.data.token);
+      setAuth(response.data.user, response.data.token, response.data.refresh_token);
       setNewEmail('');
       setCurrentPassword('');
       setEmailCode('');
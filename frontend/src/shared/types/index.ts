@@ -103,6 +103,10 @@ export interface Message {
     avatar_url?: string;
   };
   reactions?: ReactionGroup[];
+  // Thread info, only set on top-level messages
+  reply_count?: number;
+  last_reply_at?: string | null;
+  last_reply_user_id?: string | null;
 }
 
 export interface FileMetadata {
@@ -161,5 +165,13 @@ export interface RegisterRequest {
 
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_at: string;
   user: User;
 }
+
+export interface MFAChallengeResponse {
+  mfa_required: true;
+  mfa_token: string;
+  expires_at: string;
+}
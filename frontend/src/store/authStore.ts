@@ -7,7 +7,7 @@ interface AuthState {
   token: string | null;
   isAuthenticated: boolean;
   isHydrated: boolean;
-  setAuth: (user: User, token: string) => void;
+  setAuth: (user: User, token: string, refreshToken?: string) => void;
   updateUser: (user: User) => void;
   logout: () => void;
   loadFromStorage: () => void;
@@ -19,8 +19,11 @@ export const useAuthStore = create<AuthState>((set) => ({
   isAuthenticated: false,
   isHydrated: false,
 
-  setAuth: (user, token) => {
+  setAuth: (user, token, refreshToken) => {
     safeStorage.set('auth_token', token);
+    if (refreshToken) {
+      safeStorage.set('auth_refresh_token', refreshToken);
+    }
     safeStorage.set('auth_user', JSON.stringify(user));
     set({ user, token, isAuthenticated: true, isHydrated: true });
   },
@@ -37,6 +40,7 @@ export const useAuthStore = create<AuthState>((set) => ({
 
   logout: () => {
     safeStorage.remove('auth_token');
+    safeStorage.remove('auth_refresh_token');
     safeStorage.remove('auth_user');
     set({ user: null, token: null, isAuthenticated: false, isHydrated: true });
   },